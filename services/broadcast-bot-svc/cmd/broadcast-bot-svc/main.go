@@ -10,6 +10,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/BitwaveCorp/shared-svcs/shared/dedup"
 	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/broadcast-bot-svc/internal/api"
 	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/broadcast-bot-svc/internal/config"
 	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/broadcast-bot-svc/internal/slack"
@@ -45,8 +46,16 @@ func main() {
 		"broadcast_channel_id", cfg.BroadcastChannelID,
 	)
 
-	slackClient := slack.NewClient(cfg.SlackBotToken, logger)
-	handler := api.NewHandler(slackClient, cfg.BroadcastChannelID, logger)
+	dedupBackend, err := dedup.OpenBackend(cfg.DedupBackend, cfg.DedupPath)
+	if err != nil {
+		slog.Error("Failed to open dedup store", "error", err)
+		os.Exit(1)
+	}
+	messages := dedup.New(dedupBackend, cfg.DedupTTL, logger)
+	defer messages.Close()
+
+	slackClient := slack.NewClient(cfg.SlackAPIBaseURL, cfg.SlackBotToken, logger)
+	handler := api.NewHandler(slackClient, cfg.BroadcastChannelID, messages, logger)
 
 	mux := http.NewServeMux()
 	handler.RegisterRoutes(mux)
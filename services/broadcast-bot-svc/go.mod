@@ -3,9 +3,25 @@ module github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/broadcast
 go 1.24
 
 require (
-	github.com/BitwaveCorp/shared-svcs/shared/utils v0.0.0
-	github.com/kelseyhightower/envconfig v1.4.0
+	github.com/BitwaveCorp/shared-svcs/shared/dedup v0.0.0
+	github.com/BitwaveCorp/shared-svcs/shared/mrkdwn v0.0.0
+	github.com/BitwaveCorp/shared-svcs/shared/slackapi v0.0.0
 	github.com/joho/godotenv v1.5.1
+	github.com/kelseyhightower/envconfig v1.4.0
+)
+
+require (
+	github.com/BitwaveCorp/shared-svcs/shared/boltbucket v0.0.0 // indirect
+	go.etcd.io/bbolt v1.3.11 // indirect
+	golang.org/x/sys v0.4.0 // indirect
 )
 
 replace github.com/BitwaveCorp/shared-svcs/shared/utils => ../../shared/utils
+
+replace github.com/BitwaveCorp/shared-svcs/shared/boltbucket => ../../shared/boltbucket
+
+replace github.com/BitwaveCorp/shared-svcs/shared/dedup => ../../shared/dedup
+
+replace github.com/BitwaveCorp/shared-svcs/shared/mrkdwn => ../../shared/mrkdwn
+
+replace github.com/BitwaveCorp/shared-svcs/shared/slackapi => ../../shared/slackapi
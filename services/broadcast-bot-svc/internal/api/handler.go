@@ -2,27 +2,28 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
-	"sync"
 
-	"github.com/BitwaveCorp/shared-svcs/services/broadcast-bot-svc/internal/slack"
+	"github.com/BitwaveCorp/shared-svcs/shared/dedup"
+	"github.com/BitwaveCorp/shared-svcs/shared/slackapi"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/broadcast-bot-svc/internal/slack"
 )
 
 type Handler struct {
 	slackClient        *slack.Client
 	broadcastChannelID string
 	logger             *slog.Logger
-	processedMessages  map[string]bool
-	messagesMutex      sync.RWMutex
+	messages           *dedup.Deduplicator
 }
 
-func NewHandler(slackClient *slack.Client, broadcastChannelID string, logger *slog.Logger) *Handler {
+func NewHandler(slackClient *slack.Client, broadcastChannelID string, messages *dedup.Deduplicator, logger *slog.Logger) *Handler {
 	return &Handler{
 		slackClient:        slackClient,
 		broadcastChannelID: broadcastChannelID,
 		logger:             logger,
-		processedMessages:  make(map[string]bool),
+		messages:           messages,
 	}
 }
 
@@ -39,16 +40,29 @@ func (h *Handler) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-func (h *Handler) isMessageProcessed(correlationID string) bool {
-	h.messagesMutex.RLock()
-	defer h.messagesMutex.RUnlock()
-	return h.processedMessages[correlationID]
+// idempotencyKey returns the key used to drop duplicate deliveries: the
+// listener's outbox sends one, older callers only have the correlation ID
+func idempotencyKey(r *http.Request, correlationID string) string {
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		return key
+	}
+	return correlationID
 }
 
-func (h *Handler) markMessageProcessed(correlationID string) {
-	h.messagesMutex.Lock()
-	defer h.messagesMutex.Unlock()
-	h.processedMessages[correlationID] = true
+// postFailureStatus tells the listener's outbox whether a failed post is
+// worth retrying: 4xx responses are dead-lettered, except 429. Slack
+// rejecting the message (not_in_channel, channel_not_found, ...) won't change
+// on retry, while rate limits and outages will.
+func postFailureStatus(err error) int {
+	var apiErr *slackapi.Error
+	switch {
+	case slackapi.IsRateLimited(err):
+		return http.StatusTooManyRequests
+	case errors.As(err, &apiErr) && !apiErr.Temporary():
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
 }
 
 func (h *Handler) handleFeedback(w http.ResponseWriter, r *http.Request) {
@@ -65,23 +79,26 @@ func (h *Handler) handleFeedback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if h.isMessageProcessed(req.CorrelationID) {
+	// Claim the request before posting so concurrent retries are dropped
+	key := idempotencyKey(r, req.CorrelationID)
+	if !h.messages.Claim(key) {
 		h.logger.Info("Feedback message already processed", "correlation_id", req.CorrelationID)
 		w.WriteHeader(http.StatusOK)
 		return
 	}
-
-	h.markMessageProcessed(req.CorrelationID)
+	defer h.releaseOnPanic(w, key)
 
 	h.logger.Info("Processing feedback request",
 		"correlation_id", req.CorrelationID,
+		"team_id", req.TeamID,
 		"user_id", req.UserID,
 		"feedback_type", req.FeedbackType)
 
-	err := h.slackClient.PostFeedbackMessage(r.Context(), h.broadcastChannelID, req)
+	err := h.slackClient.PostFeedbackMessage(r.Context(), h.targetChannel(req.BroadcastChannelID), req)
 	if err != nil {
-		h.logger.Error("Failed to post feedback message", "error", err, "correlation_id", req.CorrelationID)
-		http.Error(w, "Failed to post feedback message", http.StatusInternalServerError)
+		h.messages.Release(key)
+		h.logger.Error("Failed to post feedback message", "error", err, "slack_error", slackapi.ErrorCode(err), "correlation_id", req.CorrelationID)
+		http.Error(w, "Failed to post feedback message", postFailureStatus(err))
 		return
 	}
 
@@ -111,23 +128,26 @@ func (h *Handler) handleBroadcast(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if h.isMessageProcessed(req.CorrelationID) {
+	// Claim the request before posting so concurrent retries are dropped
+	key := idempotencyKey(r, req.CorrelationID)
+	if !h.messages.Claim(key) {
 		h.logger.Info("Broadcast message already processed", "correlation_id", req.CorrelationID)
 		w.WriteHeader(http.StatusOK)
 		return
 	}
-
-	h.markMessageProcessed(req.CorrelationID)
+	defer h.releaseOnPanic(w, key)
 
 	h.logger.Info("Processing broadcast request",
 		"correlation_id", req.CorrelationID,
+		"team_id", req.TeamID,
 		"user_id", req.UserID,
 		"channel_id", req.ChannelID)
 
-	err := h.slackClient.PostBroadcastMessage(r.Context(), h.broadcastChannelID, req)
+	err := h.slackClient.PostBroadcastMessage(r.Context(), h.targetChannel(req.BroadcastChannelID), req)
 	if err != nil {
-		h.logger.Error("Failed to post broadcast message", "error", err, "correlation_id", req.CorrelationID)
-		http.Error(w, "Failed to post broadcast message", http.StatusInternalServerError)
+		h.messages.Release(key)
+		h.logger.Error("Failed to post broadcast message", "error", err, "slack_error", slackapi.ErrorCode(err), "correlation_id", req.CorrelationID)
+		http.Error(w, "Failed to post broadcast message", postFailureStatus(err))
 		return
 	}
 
@@ -142,3 +162,23 @@ func (h *Handler) handleBroadcast(w http.ResponseWriter, r *http.Request) {
 
 	h.logger.Info("Successfully processed broadcast request", "correlation_id", req.CorrelationID)
 }
+
+// releaseOnPanic is deferred once a request is claimed. If building or
+// posting the message panics, the claim is released so the outbox's retry
+// isn't dropped as a duplicate.
+func (h *Handler) releaseOnPanic(w http.ResponseWriter, key string) {
+	if r := recover(); r != nil {
+		h.messages.Release(key)
+		h.logger.Error("Posting message panicked", "panic", r, "idempotency_key", key)
+		http.Error(w, "Failed to post message", http.StatusInternalServerError)
+	}
+}
+
+// targetChannel is the channel a message is posted to: the one the listener's
+// channel policy asked for, or the default broadcast channel
+func (h *Handler) targetChannel(requested string) string {
+	if requested != "" {
+		return requested
+	}
+	return h.broadcastChannelID
+}
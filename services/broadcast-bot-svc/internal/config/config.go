@@ -1,9 +1,17 @@
 package config
 
+import "time"
+
 type Config struct {
 	LogLevel string `envconfig:"LOG_LEVEL" default:"info"`
 	Port     int    `envconfig:"PORT" default:"8082"`
 
 	SlackBotToken      string `envconfig:"SLACK_BOT_TOKEN" required:"true"`
 	BroadcastChannelID string `envconfig:"BROADCAST_CHANNEL_ID" required:"true"`
+	SlackAPIBaseURL    string `envconfig:"SLACK_API_BASE_URL" default:"https://slack.com/api"`
+
+	// Request deduplication: "memory" or "bolt"; TTL should cover the listener's outbox retries
+	DedupBackend string        `envconfig:"DEDUP_BACKEND" default:"memory"`
+	DedupPath    string        `envconfig:"DEDUP_PATH" default:"dedup.db"`
+	DedupTTL     time.Duration `envconfig:"DEDUP_TTL" default:"24h"`
 }
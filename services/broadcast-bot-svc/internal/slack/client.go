@@ -8,19 +8,38 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
+	"unicode/utf8"
+
+	"github.com/BitwaveCorp/shared-svcs/shared/mrkdwn"
+	"github.com/BitwaveCorp/shared-svcs/shared/slackapi"
+)
+
+const (
+	// Slack rejects section text over 3000 characters
+	maxSectionChars = 3000
+
+	// Above this much question and answer text a broadcast only shows a
+	// preview, and the full exchange is attached in its thread as a file
+	maxBroadcastChars = 12000
+	previewChars      = 1500
+
+	// Feedback posts show what was rated; the broadcast has it in full
+	feedbackPreviewChars = 1500
 )
 
 type Client struct {
-	botToken string
-	logger   *slog.Logger
-	client   *http.Client
+	api    *slackapi.Client
+	logger *slog.Logger
+	client *http.Client // for file uploads
 }
 
-func NewClient(botToken string, logger *slog.Logger) *Client {
+func NewClient(apiURL, botToken string, logger *slog.Logger) *Client {
 	return &Client{
-		botToken: botToken,
-		logger:   logger,
+		api:    slackapi.New(apiURL, botToken, logger),
+		logger: logger,
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
@@ -66,47 +85,40 @@ func (c *Client) PostFeedbackMessage(ctx context.Context, channelID string, req
 			Type: "section",
 			Text: &TextObject{
 				Type: "mrkdwn",
-				Text: fmt.Sprintf("*Detailed Feedback:*\n%s", req.FeedbackText),
+				Text: fmt.Sprintf("*Detailed Feedback:*\n%s", preview(req.FeedbackText, maxSectionChars-len("*Detailed Feedback:*\n"))),
 			},
 		})
 	}
 
+	// Show what was rated, when the listener knows
+	if req.Question != "" {
+		blocks = append(blocks, textSections("*Question:*", preview(req.Question, feedbackPreviewChars))...)
+	}
+	if req.Response != "" {
+		blocks = append(blocks, textSections("*Response:*", preview(mrkdwn.Render(req.Response), feedbackPreviewChars))...)
+	}
+
 	// Add context information
-	blocks = append(blocks, MessageBlock{
-		Type: "context",
-		Text: &TextObject{
-			Type: "mrkdwn",
-			Text: fmt.Sprintf("Correlation ID: `%s`", req.CorrelationID),
-		},
-	})
+	contextText := fmt.Sprintf("Correlation ID: `%s`", req.CorrelationID)
+	if req.AnswerCorrelationID != "" {
+		contextText += fmt.Sprintf(" | Answer: `%s`", req.AnswerCorrelationID)
+	}
+	if req.Model != "" {
+		contextText += fmt.Sprintf(" | Model: `%s`", req.Model)
+	}
+	if req.PromptVersion != "" {
+		contextText += fmt.Sprintf(" | Prompt: v%s", req.PromptVersion)
+	}
+	blocks = append(blocks, contextBlock(contextText))
 
 	message := SlackMessage{
 		Channel: channelID,
+		Text:    "Wavie feedback",
 		Blocks:  blocks,
 	}
 
-	jsonData, err := json.Marshal(message)
-	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://slack.com/api/chat.postMessage", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.botToken)
-
-	resp, err := c.client.Do(httpReq)
-	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("slack API error: %d - %s", resp.StatusCode, string(body))
+	if _, err := c.postMessage(ctx, message); err != nil {
+		return err
 	}
 
 	c.logger.Info("Feedback message posted to Slack",
@@ -116,72 +128,160 @@ func (c *Client) PostFeedbackMessage(ctx context.Context, channelID string, req
 	return nil
 }
 
+// PostBroadcastMessage posts a question and its answer. When they are too
+// long for one message, the message shows a preview and the full exchange is
+// uploaded as a file in its thread.
 func (c *Client) PostBroadcastMessage(ctx context.Context, channelID string, req BroadcastRequest) error {
+	question := req.Question
+	response := mrkdwn.Render(req.Response)
+	attachTranscript := length(question)+length(response) > maxBroadcastChars
+	if attachTranscript {
+		question = preview(question, previewChars)
+		response = preview(response, previewChars)
+	}
+
 	blocks := []MessageBlock{
-		{
-			Type: "section",
-			Text: &TextObject{
-				Type: "mrkdwn",
-				Text: fmt.Sprintf("*Wavie Interaction*\n*User:* <@%s>\n*Channel:* <#%s>\n*Time:* %s",
-					req.UserID,
-					req.ChannelID,
-					req.Timestamp.Format("2006-01-02 15:04:05 UTC")),
-			},
-		},
-		{
-			Type: "section",
-			Text: &TextObject{
-				Type: "mrkdwn",
-				Text: fmt.Sprintf("*Question:*\n%s", req.Question),
-			},
-		},
-		{
-			Type: "section",
-			Text: &TextObject{
-				Type: "mrkdwn",
-				Text: fmt.Sprintf("*Response:*\n%s", req.Response),
-			},
-		},
-		{
-			Type: "context",
-			Text: &TextObject{
-				Type: "mrkdwn",
-				Text: fmt.Sprintf("Correlation ID: `%s`", req.CorrelationID),
-			},
-		},
+		section(fmt.Sprintf("*Wavie Interaction*\n*User:* <@%s>\n*Channel:* <#%s>\n*Time:* %s",
+			req.UserID,
+			req.ChannelID,
+			req.Timestamp.Format("2006-01-02 15:04:05 UTC"))),
+	}
+	blocks = append(blocks, textSections("*Question:*", question)...)
+	blocks = append(blocks, textSections("*Response:*", response)...)
+	if attachTranscript {
+		blocks = append(blocks, section("_Too long to show in full; the complete transcript is attached in the thread._"))
 	}
+	blocks = append(blocks, contextBlock(fmt.Sprintf("Correlation ID: `%s`", req.CorrelationID)))
 
 	message := SlackMessage{
 		Channel: channelID,
+		Text:    "Wavie interaction",
 		Blocks:  blocks,
 	}
 
-	jsonData, err := json.Marshal(message)
+	ts, err := c.postMessage(ctx, message)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://slack.com/api/chat.postMessage", bytes.NewBuffer(jsonData))
+	c.logger.Info("Broadcast message posted to Slack",
+		"channel", channelID,
+		"correlation_id", req.CorrelationID)
+
+	if attachTranscript {
+		// The broadcast itself is out, so a failed upload is only logged;
+		// failing the request would get the broadcast retried and duplicated
+		filename := fmt.Sprintf("wavie-%s.md", req.CorrelationID)
+		if err := c.UploadSnippet(ctx, channelID, ts, filename, transcript(req)); err != nil {
+			c.logger.Error("Failed to attach broadcast transcript",
+				"error", err,
+				"channel", channelID,
+				"correlation_id", req.CorrelationID)
+		}
+	}
+
+	return nil
+}
+
+// UploadSnippet uploads content as a text file in the thread of threadTS,
+// using Slack's external upload flow: reserve an upload URL, send the
+// content to it, then share the file in the channel
+func (c *Client) UploadSnippet(ctx context.Context, channelID, threadTS, filename, content string) error {
+	var upload UploadURLResponse
+	err := c.api.CallForm(ctx, "files.getUploadURLExternal", url.Values{
+		"filename": {filename},
+		"length":   {strconv.Itoa(len(content))},
+	}, &upload)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.botToken)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", upload.UploadURL, bytes.NewBufferString(content))
+	if err != nil {
+		return fmt.Errorf("failed to create upload request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "text/plain; charset=utf-8")
 
 	resp, err := c.client.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+		return fmt.Errorf("failed to upload file: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("slack API error: %d - %s", resp.StatusCode, string(body))
+		return fmt.Errorf("file upload error: %d - %s", resp.StatusCode, string(body))
 	}
 
-	c.logger.Info("Broadcast message posted to Slack",
-		"channel", channelID,
-		"correlation_id", req.CorrelationID)
-	return nil
+	files, err := json.Marshal([]map[string]string{{"id": upload.FileID, "title": filename}})
+	if err != nil {
+		return fmt.Errorf("failed to marshal files: %w", err)
+	}
+
+	return c.api.CallForm(ctx, "files.completeUploadExternal", url.Values{
+		"files":      {string(files)},
+		"channel_id": {channelID},
+		"thread_ts":  {threadTS},
+	}, nil)
+}
+
+// postMessage calls chat.postMessage and returns the posted message's timestamp
+func (c *Client) postMessage(ctx context.Context, message SlackMessage) (string, error) {
+	posted, err := c.api.PostMessage(ctx, message)
+	if err != nil {
+		return "", fmt.Errorf("failed to send message: %w", err)
+	}
+	return posted.TS, nil
+}
+
+func section(text string) MessageBlock {
+	return MessageBlock{
+		Type: "section",
+		Text: &TextObject{Type: "mrkdwn", Text: text},
+	}
+}
+
+func contextBlock(text string) MessageBlock {
+	return MessageBlock{
+		Type:     "context",
+		Elements: []TextObject{{Type: "mrkdwn", Text: text}},
+	}
+}
+
+// textSections lays out a heading and mrkdwn text as one or more sections,
+// splitting the text between paragraphs where it is too long for one
+func textSections(heading, text string) []MessageBlock {
+	var blocks []MessageBlock
+	for i, part := range mrkdwn.Split(text, maxSectionChars-length(heading)-1) {
+		if i == 0 {
+			part = heading + "\n" + part
+		}
+		blocks = append(blocks, section(part))
+	}
+	return blocks
+}
+
+// preview shortens mrkdwn text to about limit characters, cutting it where
+// mrkdwn.Split would
+func preview(text string, limit int) string {
+	parts := mrkdwn.Split(text, limit)
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return parts[0] + "\n…"
+}
+
+// transcript is the full exchange uploaded when a broadcast is too long
+func transcript(req BroadcastRequest) string {
+	return fmt.Sprintf("# Wavie interaction\n\nUser: %s\nChannel: %s\nTime: %s\nCorrelation ID: %s\n\n## Question\n\n%s\n\n## Response\n\n%s\n",
+		req.UserID,
+		req.ChannelID,
+		req.Timestamp.Format("2006-01-02 15:04:05 UTC"),
+		req.CorrelationID,
+		req.Question,
+		req.Response)
+}
+
+func length(text string) int {
+	return utf8.RuneCountInString(text)
 }
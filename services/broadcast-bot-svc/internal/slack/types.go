@@ -3,16 +3,21 @@ package slack
 import "time"
 
 type BroadcastRequest struct {
+	TeamID        string    `json:"team_id"` // workspace the question was asked in
 	UserID        string    `json:"user_id"`
 	ChannelID     string    `json:"channel_id"`
 	Question      string    `json:"question"`
 	Response      string    `json:"response"`
 	Timestamp     time.Time `json:"timestamp"`
 	CorrelationID string    `json:"correlation_id"`
+
+	// Overrides the broadcast channel, from the listener's channel policy
+	BroadcastChannelID string `json:"broadcast_channel_id,omitempty"`
 }
 
 // FeedbackRequest represents a request to broadcast user feedback
 type FeedbackRequest struct {
+	TeamID        string    `json:"team_id"`
 	UserID        string    `json:"user_id"`
 	ChannelID     string    `json:"channel_id"`
 	MessageTS     string    `json:"message_ts"`
@@ -23,11 +28,21 @@ type FeedbackRequest struct {
 	FeedbackText  string    `json:"feedback_text,omitempty"`
 	Timestamp     time.Time `json:"timestamp"`
 	CorrelationID string    `json:"correlation_id"`
+
+	// Correlation ID of the rated answer, matching its broadcast, and what
+	// produced it
+	AnswerCorrelationID string `json:"answer_correlation_id,omitempty"`
+	Model               string `json:"model,omitempty"`
+	PromptVersion       string `json:"prompt_version,omitempty"`
+
+	// Overrides the broadcast channel, from the listener's channel policy
+	BroadcastChannelID string `json:"broadcast_channel_id,omitempty"`
 }
 
 type MessageBlock struct {
-	Type string      `json:"type"`
-	Text *TextObject `json:"text,omitempty"`
+	Type     string       `json:"type"`
+	Text     *TextObject  `json:"text,omitempty"`
+	Elements []TextObject `json:"elements,omitempty"` // context blocks
 }
 
 type TextObject struct {
@@ -36,6 +51,14 @@ type TextObject struct {
 }
 
 type SlackMessage struct {
-	Channel string         `json:"channel"`
-	Blocks  []MessageBlock `json:"blocks"`
+	Channel  string         `json:"channel"`
+	Text     string         `json:"text,omitempty"` // notification fallback
+	ThreadTS string         `json:"thread_ts,omitempty"`
+	Blocks   []MessageBlock `json:"blocks"`
+}
+
+// UploadURLResponse is the response from files.getUploadURLExternal
+type UploadURLResponse struct {
+	UploadURL string `json:"upload_url"`
+	FileID    string `json:"file_id"`
 }
@@ -45,8 +45,20 @@ func main() {
 		"claude_model", cfg.ClaudeModel,
 	)
 
-	claudeClient := openai.NewClient(cfg.ClaudeAPIKey, cfg.ClaudeModel, logger)
-	handler := api.NewHandler(claudeClient, logger)
+	if cfg.SummaryChunkTokens < 1000 {
+		slog.Error("SUMMARY_CHUNK_TOKENS must be at least 1000", "summary_chunk_tokens", cfg.SummaryChunkTokens)
+		os.Exit(1)
+	}
+
+	personas, err := openai.LoadPersonas(cfg.PersonasPath)
+	if err != nil {
+		slog.Error("Failed to load personas", "error", err)
+		os.Exit(1)
+	}
+
+	claudeClient := openai.NewClient(cfg.ClaudeAPIKey, cfg.ClaudeModel, personas, logger)
+	summarizer := openai.NewSummarizer(claudeClient, cfg.SummaryChunkTokens, cfg.SummaryConcurrency, logger)
+	handler := api.NewHandler(claudeClient, summarizer, logger)
 
 	mux := http.NewServeMux()
 	handler.RegisterRoutes(mux)
@@ -3,11 +3,13 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/BitwaveCorp/shared-svcs/services/gpt-agent-proxy-svc/internal/openai"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/claude-agent-proxy-svc/internal/openai"
 )
 
 type ConversationMessage struct {
@@ -24,22 +26,60 @@ type GPTRequest struct {
 	ThreadTS           string               `json:"thread_ts,omitempty"`
 	ConversationHistory []ConversationMessage `json:"conversation_history,omitempty"`
 	CorrelationID      string               `json:"correlation_id"`
+	Attachments        []Attachment         `json:"attachments,omitempty"`
+
+	// From the listener's channel policy; empty means the defaults
+	Persona string `json:"persona,omitempty"`
+	Model   string `json:"model,omitempty"`
+}
+
+// Attachment is a file shared with the question; Data is base64 in JSON
+type Attachment struct {
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type"`
+	Data     []byte `json:"data"`
+}
+
+// options returns the persona and model the request asks for
+func (req GPTRequest) options() openai.Options {
+	return openai.Options{Persona: req.Persona, Model: req.Model}
 }
 
 type GPTResponse struct {
 	Response      string `json:"response"`
 	CorrelationID string `json:"correlation_id"`
+	Model         string `json:"model,omitempty"`
+	PromptVersion string `json:"prompt_version,omitempty"`
+	InputTokens   int    `json:"input_tokens,omitempty"`
+	OutputTokens  int    `json:"output_tokens,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// ChatStreamEvent is one line of the NDJSON response of /api/chat/stream:
+// "delta" events carry the next piece of the answer, and the stream ends
+// with a "done" event holding the whole answer or an "error" event
+type ChatStreamEvent struct {
+	Type          string `json:"type"`
+	Text          string `json:"text,omitempty"`
+	Response      string `json:"response,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	Model         string `json:"model,omitempty"`
+	PromptVersion string `json:"prompt_version,omitempty"`
+	InputTokens   int    `json:"input_tokens,omitempty"`
+	OutputTokens  int    `json:"output_tokens,omitempty"`
 	Error         string `json:"error,omitempty"`
 }
 
 type Handler struct {
 	openaiClient *openai.Client
+	summarizer   *openai.Summarizer
 	logger       *slog.Logger
 }
 
-func NewHandler(openaiClient *openai.Client, logger *slog.Logger) *Handler {
+func NewHandler(openaiClient *openai.Client, summarizer *openai.Summarizer, logger *slog.Logger) *Handler {
 	return &Handler{
 		openaiClient: openaiClient,
+		summarizer:   summarizer,
 		logger:       logger,
 	}
 }
@@ -47,6 +87,8 @@ func NewHandler(openaiClient *openai.Client, logger *slog.Logger) *Handler {
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /health", h.handleHealthCheck)
 	mux.HandleFunc("POST /api/chat", h.handleChatCompletion)
+	mux.HandleFunc("POST /api/chat/stream", h.handleChatStream)
+	mux.HandleFunc("POST /api/summarize", h.handleSummarize)
 }
 
 func (h *Handler) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
@@ -64,24 +106,27 @@ func (h *Handler) handleChatCompletion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Message == "" {
+	if req.Message == "" && len(req.Attachments) == 0 {
 		h.logger.Error("Empty message in request", "correlation_id", req.CorrelationID)
 		http.Error(w, "Message is required", http.StatusBadRequest)
 		return
 	}
 
+	message, attachments := h.toContentBlocks(req.Message, req.Attachments, req.CorrelationID)
+
 	h.logger.Info("Processing chat completion request",
 		"correlation_id", req.CorrelationID,
 		"user_id", req.UserID,
 		"channel_id", req.ChannelID,
 		"thread_ts", req.ThreadTS,
-		"has_history", len(req.ConversationHistory) > 0)
+		"has_history", len(req.ConversationHistory) > 0,
+		"attachments", len(attachments))
 
 	ctx, cancel := context.WithTimeout(r.Context(), 90*time.Second)
 	defer cancel()
 
 	// Use conversation history if available
-	response, err := h.openaiClient.ChatCompletionWithHistory(ctx, req.Message, req.ConversationHistory, req.CorrelationID)
+	response, usage, err := h.openaiClient.ChatCompletionWithHistory(ctx, message, attachments, toOpenAIMessages(req.ConversationHistory), req.options(), req.CorrelationID)
 	if err != nil {
 		h.logger.Error("Failed to get chat completion", "error", err, "correlation_id", req.CorrelationID)
 
@@ -99,6 +144,10 @@ func (h *Handler) handleChatCompletion(w http.ResponseWriter, r *http.Request) {
 	gptResp := GPTResponse{
 		Response:      response,
 		CorrelationID: req.CorrelationID,
+		Model:         h.openaiClient.ModelFor(req.options()),
+		PromptVersion: h.openaiClient.PromptVersionFor(req.options()),
+		InputTokens:   usage.InputTokens,
+		OutputTokens:  usage.OutputTokens,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -107,3 +156,107 @@ func (h *Handler) handleChatCompletion(w http.ResponseWriter, r *http.Request) {
 
 	h.logger.Info("Successfully processed chat completion", "correlation_id", req.CorrelationID)
 }
+
+// handleChatStream is handleChatCompletion, but streams the answer as it is
+// generated as newline-delimited JSON events
+func (h *Handler) handleChatStream(w http.ResponseWriter, r *http.Request) {
+	var req GPTRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request", "error", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Message == "" && len(req.Attachments) == 0 {
+		h.logger.Error("Empty message in request", "correlation_id", req.CorrelationID)
+		http.Error(w, "Message is required", http.StatusBadRequest)
+		return
+	}
+
+	message, attachments := h.toContentBlocks(req.Message, req.Attachments, req.CorrelationID)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("Processing streaming chat request",
+		"correlation_id", req.CorrelationID,
+		"user_id", req.UserID,
+		"channel_id", req.ChannelID,
+		"thread_ts", req.ThreadTS,
+		"has_history", len(req.ConversationHistory) > 0,
+		"attachments", len(attachments))
+
+	ctx, cancel := context.WithTimeout(r.Context(), 90*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	send := func(event ChatStreamEvent) {
+		if err := encoder.Encode(event); err != nil {
+			// The listener went away; the request context ends the stream
+			h.logger.Warn("Failed to write stream event", "error", err, "correlation_id", req.CorrelationID)
+			return
+		}
+		flusher.Flush()
+	}
+
+	response, usage, err := h.openaiClient.ChatCompletionStream(ctx, message, attachments, toOpenAIMessages(req.ConversationHistory), req.options(), req.CorrelationID, func(text string) {
+		send(ChatStreamEvent{Type: "delta", Text: text})
+	})
+	if err != nil {
+		h.logger.Error("Failed to stream chat completion", "error", err, "correlation_id", req.CorrelationID)
+		send(ChatStreamEvent{Type: "error", CorrelationID: req.CorrelationID, Error: err.Error()})
+		return
+	}
+
+	send(ChatStreamEvent{
+		Type:          "done",
+		Response:      response,
+		CorrelationID: req.CorrelationID,
+		Model:         h.openaiClient.ModelFor(req.options()),
+		PromptVersion: h.openaiClient.PromptVersionFor(req.options()),
+		InputTokens:   usage.InputTokens,
+		OutputTokens:  usage.OutputTokens,
+	})
+
+	h.logger.Info("Successfully streamed chat completion", "correlation_id", req.CorrelationID)
+}
+
+// toOpenAIMessages converts the conversation history sent by the listener
+// into messages for the Claude client
+func toOpenAIMessages(history []ConversationMessage) []openai.Message {
+	messages := make([]openai.Message, 0, len(history))
+	for _, msg := range history {
+		messages = append(messages, openai.TextMessage(msg.Role, msg.Content))
+	}
+	return messages
+}
+
+// toContentBlocks converts the files shared with a question into content
+// blocks for the Claude client. A file that can't be converted, such as a
+// text file that isn't UTF-8, is left out and a note added to the message
+// instead, so one bad file doesn't fail the whole question.
+func (h *Handler) toContentBlocks(message string, attachments []Attachment, correlationID string) (string, []openai.ContentBlock) {
+	blocks := make([]openai.ContentBlock, 0, len(attachments))
+	var notes []string
+	for _, attachment := range attachments {
+		block, err := openai.AttachmentBlock(attachment.Name, attachment.MimeType, attachment.Data)
+		if err != nil {
+			h.logger.Warn("Skipping unreadable attachment", "error", err, "correlation_id", correlationID)
+			notes = append(notes, fmt.Sprintf("[attachment %s could not be read]", attachment.Name))
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+
+	if len(notes) > 0 {
+		message = strings.TrimSpace(message + "\n\n" + strings.Join(notes, "\n"))
+	}
+	return message, blocks
+}
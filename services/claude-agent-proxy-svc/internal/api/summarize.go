@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/claude-agent-proxy-svc/internal/openai"
+)
+
+// ThreadMessage is one message of a thread to summarize. The listener
+// resolves authors and mentions to names before sending.
+type ThreadMessage struct {
+	Author    string    `json:"author"`
+	Text      string    `json:"text"`
+	Timestamp time.Time `json:"timestamp,omitempty"`
+}
+
+type SummarizeRequest struct {
+	Messages      []ThreadMessage `json:"messages"`
+	UserID        string          `json:"user_id"`
+	ChannelID     string          `json:"channel_id"`
+	ThreadTS      string          `json:"thread_ts"`
+	CorrelationID string          `json:"correlation_id"`
+
+	// From the listener's channel policy; empty means the default model
+	Model string `json:"model,omitempty"`
+}
+
+type SummarizeResponse struct {
+	Summary       openai.Summary `json:"summary"`
+	Chunks        int            `json:"chunks,omitempty"`
+	CorrelationID string         `json:"correlation_id"`
+	Model         string         `json:"model,omitempty"`
+	PromptVersion string         `json:"prompt_version,omitempty"`
+	InputTokens   int            `json:"input_tokens,omitempty"`
+	OutputTokens  int            `json:"output_tokens,omitempty"`
+	Error         string         `json:"error,omitempty"`
+}
+
+// handleSummarize summarizes a Slack thread into its decisions, open
+// questions and action items. Threads too long for the context window are
+// summarized in chunks and merged, so this can take a few minutes.
+func (h *Handler) handleSummarize(w http.ResponseWriter, r *http.Request) {
+	var req SummarizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request", "error", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Messages) == 0 {
+		h.logger.Error("Empty thread in summarize request", "correlation_id", req.CorrelationID)
+		http.Error(w, "Messages are required", http.StatusBadRequest)
+		return
+	}
+
+	h.logger.Info("Processing summarize request",
+		"correlation_id", req.CorrelationID,
+		"user_id", req.UserID,
+		"channel_id", req.ChannelID,
+		"thread_ts", req.ThreadTS,
+		"messages", len(req.Messages))
+
+	ctx, cancel := context.WithTimeout(r.Context(), 4*time.Minute)
+	defer cancel()
+
+	messages := make([]openai.ThreadMessage, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		messages = append(messages, openai.ThreadMessage{Author: msg.Author, Text: msg.Text, Timestamp: msg.Timestamp})
+	}
+
+	opts := openai.Options{Model: req.Model}
+	summary, usage, chunks, err := h.summarizer.Summarize(ctx, messages, opts, req.CorrelationID)
+
+	resp := SummarizeResponse{
+		Summary:       summary,
+		Chunks:        chunks,
+		CorrelationID: req.CorrelationID,
+		Model:         h.openaiClient.ModelFor(opts),
+		PromptVersion: openai.SummaryPromptVersion,
+		InputTokens:   usage.InputTokens,
+		OutputTokens:  usage.OutputTokens,
+	}
+
+	status := http.StatusOK
+	if err != nil {
+		h.logger.Error("Failed to summarize thread", "error", err, "correlation_id", req.CorrelationID)
+		resp.Error = err.Error()
+		status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+
+	if err == nil {
+		h.logger.Info("Successfully summarized thread", "correlation_id", req.CorrelationID, "chunks", chunks)
+	}
+}
@@ -6,4 +6,13 @@ type Config struct {
 
 	ClaudeAPIKey string `envconfig:"CLAUDE_API_KEY" required:"true"`
 	ClaudeModel  string `envconfig:"CLAUDE_MODEL" default:"claude-3-opus-20240229"`
+
+	// JSON file of named system prompts that channel policies can pick
+	PersonasPath string `envconfig:"PERSONAS_PATH"`
+
+	// Thread summaries: how much of a thread goes into one request (threads
+	// that don't fit are summarized in chunks and merged) and how many chunk
+	// requests run at once
+	SummaryChunkTokens int `envconfig:"SUMMARY_CHUNK_TOKENS" default:"40000"`
+	SummaryConcurrency int `envconfig:"SUMMARY_CONCURRENCY" default:"4"`
 }
@@ -1,6 +1,7 @@
 package openai
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -8,21 +9,30 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 )
 
+// PromptVersion identifies the system prompt. Bump it whenever systemPrompt
+// changes so feedback can be traced to the prompt that produced an answer.
+const PromptVersion = "1"
+
+const systemPrompt = "You are Wavie, a helpful AI assistant for Bitwave. You provide clear, concise, and helpful responses to user questions. Keep your responses professional but friendly."
+
 type Client struct {
-	apiKey string
-	model  string
-	logger *slog.Logger
-	client *http.Client
+	apiKey   string
+	model    string
+	personas map[string]Persona
+	logger   *slog.Logger
+	client   *http.Client
 }
 
-func NewClient(apiKey, model string, logger *slog.Logger) *Client {
+func NewClient(apiKey, model string, personas map[string]Persona, logger *slog.Logger) *Client {
 	return &Client{
-		apiKey: apiKey,
-		model:  model,
-		logger: logger,
+		apiKey:   apiKey,
+		model:    model,
+		personas: personas,
+		logger:   logger,
 		client: &http.Client{
 			Timeout: 120 * time.Second,
 		},
@@ -32,28 +42,96 @@ func NewClient(apiKey, model string, logger *slog.Logger) *Client {
 // ChatCompletion sends a single message to OpenAI without conversation history
 func (c *Client) ChatCompletion(ctx context.Context, userMessage, correlationID string) (string, error) {
 	messages := []Message{
-		{
-			Role:    "system",
-			Content: "You are Wavie, a helpful AI assistant for Bitwave. You provide clear, concise, and helpful responses to user questions. Keep your responses professional but friendly.",
-		},
-		{
-			Role:    "user",
-			Content: userMessage,
-		},
+		TextMessage("system", systemPrompt),
+		TextMessage("user", userMessage),
 	}
 
-	return c.sendChatRequest(ctx, messages, correlationID)
+	response, _, err := c.sendChatRequest(ctx, c.newClaudeRequest(c.model, messages), correlationID)
+	return response, err
 }
 
-// ChatCompletionWithHistory sends a message to OpenAI with conversation history
-func (c *Client) ChatCompletionWithHistory(ctx context.Context, userMessage string, history []Message, correlationID string) (string, error) {
-	// Start with system message
-	messages := []Message{
-		{
-			Role:    "system",
-			Content: "You are Wavie, a helpful AI assistant for Bitwave. You provide clear, concise, and helpful responses to user questions. Keep your responses professional but friendly.",
-		},
+// ChatCompletionWithHistory sends a message to OpenAI with conversation
+// history. attachments (images and documents, see AttachmentBlock) are sent
+// with the message, and opts can pick a persona and model. It returns the
+// answer and the tokens it used.
+func (c *Client) ChatCompletionWithHistory(ctx context.Context, userMessage string, attachments []ContentBlock, history []Message, opts Options, correlationID string) (string, ClaudeUsage, error) {
+	messages := c.withHistory(c.systemPromptFor(opts), userMessage, attachments, history)
+	return c.sendChatRequest(ctx, c.newClaudeRequest(c.ModelFor(opts), messages), correlationID)
+}
+
+// ChatCompletionStream is ChatCompletionWithHistory using the streaming
+// Messages API. onText is called with each piece of the answer as it is
+// generated; the complete answer and the tokens it used are returned at the
+// end.
+func (c *Client) ChatCompletionStream(ctx context.Context, userMessage string, attachments []ContentBlock, history []Message, opts Options, correlationID string, onText func(text string)) (string, ClaudeUsage, error) {
+	messages := c.withHistory(c.systemPromptFor(opts), userMessage, attachments, history)
+	request := c.newClaudeRequest(c.ModelFor(opts), messages)
+	request.Stream = true
+
+	c.logger.Info("Sending streaming request to Claude API", "correlation_id", correlationID, "model", request.Model)
+
+	resp, err := c.post(ctx, request)
+	if err != nil {
+		return "", ClaudeUsage{}, err
 	}
+	defer resp.Body.Close()
+
+	var response strings.Builder
+	var usage ClaudeUsage
+
+	// Server-sent events: we only need the data lines, each a JSON event
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+
+		var event StreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return "", ClaudeUsage{}, fmt.Errorf("failed to unmarshal Claude stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				usage.InputTokens = event.Message.Usage.InputTokens
+			}
+		case "content_block_delta":
+			if event.Delta != nil && event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				response.WriteString(event.Delta.Text)
+				onText(event.Delta.Text)
+			}
+		case "message_delta":
+			if event.Usage != nil {
+				usage.OutputTokens = event.Usage.OutputTokens
+			}
+		case "error":
+			if event.Error != nil {
+				return "", ClaudeUsage{}, fmt.Errorf("Claude API error: %s", event.Error.Message)
+			}
+			return "", ClaudeUsage{}, fmt.Errorf("Claude API error: %s", data)
+		case "message_stop":
+			c.logger.Info("Received streamed response from Claude API",
+				"correlation_id", correlationID,
+				"tokens_used", usage.InputTokens+usage.OutputTokens,
+				"response_length", response.Len())
+			return response.String(), usage, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", ClaudeUsage{}, fmt.Errorf("failed to read Claude stream: %w", err)
+	}
+	return "", ClaudeUsage{}, fmt.Errorf("Claude stream ended before the message was complete")
+}
+
+// withHistory builds the message list for a request: the system prompt, any
+// conversation history, then the user's message with its attachments
+func (c *Client) withHistory(system, userMessage string, attachments []ContentBlock, history []Message) []Message {
+	// Start with system message
+	messages := []Message{TextMessage("system", system)}
 
 	// Add conversation history if available
 	if len(history) > 0 {
@@ -61,27 +139,29 @@ func (c *Client) ChatCompletionWithHistory(ctx context.Context, userMessage stri
 		messages = append(messages, history...)
 	}
 
-	// Add the current user message
-	messages = append(messages, Message{
-		Role:    "user",
-		Content: userMessage,
-	})
+	// Add the current user message; Claude does best with files before the
+	// question about them
+	current := Message{Role: "user", Content: append([]ContentBlock(nil), attachments...)}
+	if userMessage != "" {
+		current.Content = append(current.Content, ContentBlock{Type: "text", Text: userMessage})
+	}
+	messages = append(messages, current)
 
-	return c.sendChatRequest(ctx, messages, correlationID)
+	return messages
 }
 
-// sendChatRequest handles the actual API call to Claude API
-func (c *Client) sendChatRequest(ctx context.Context, messages []Message, correlationID string) (string, error) {
-
+// newClaudeRequest converts messages into a Claude API request for model,
+// moving the system message into the system field
+func (c *Client) newClaudeRequest(model string, messages []Message) ClaudeRequest {
 	// Convert messages to Claude format
 	systemMessage := ""
-	userMessages := []string{}
-	assistantMessages := []string{}
+	userMessages := [][]ContentBlock{}
+	assistantMessages := [][]ContentBlock{}
 
 	for _, msg := range messages {
 		switch msg.Role {
 		case "system":
-			systemMessage = msg.Content
+			systemMessage = msg.Text()
 		case "user":
 			userMessages = append(userMessages, msg.Content)
 		case "assistant":
@@ -91,7 +171,7 @@ func (c *Client) sendChatRequest(ctx context.Context, messages []Message, correl
 
 	// Build Claude API request
 	request := ClaudeRequest{
-		Model:       c.model,
+		Model:       model,
 		System:      systemMessage,
 		Temperature: 0.7,
 		MaxTokens:   1000,
@@ -105,16 +185,20 @@ func (c *Client) sendChatRequest(ctx context.Context, messages []Message, correl
 		}
 	}
 
+	return request
+}
+
+// post sends a request to the Messages API. The caller must close the
+// response body; non-200 responses are turned into errors.
+func (c *Client) post(ctx context.Context, request ClaudeRequest) (*http.Response, error) {
 	jsonData, err := json.Marshal(request)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	c.logger.Info("Sending request to Claude API", "correlation_id", correlationID, "model", c.model)
-
 	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -123,30 +207,45 @@ func (c *Client) sendChatRequest(ctx context.Context, messages []Message, correl
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+
 		var errorResp ErrorResponse
 		if err := json.Unmarshal(body, &errorResp); err != nil {
-			return "", fmt.Errorf("Claude API error: %d - %s", resp.StatusCode, string(body))
+			return nil, fmt.Errorf("Claude API error: %d - %s", resp.StatusCode, string(body))
 		}
-		return "", fmt.Errorf("Claude API error: %s", errorResp.Error.Message)
+		return nil, fmt.Errorf("Claude API error: %s", errorResp.Error.Message)
+	}
+
+	return resp, nil
+}
+
+// sendChatRequest handles the actual API call to Claude API
+func (c *Client) sendChatRequest(ctx context.Context, request ClaudeRequest, correlationID string) (string, ClaudeUsage, error) {
+	c.logger.Info("Sending request to Claude API", "correlation_id", correlationID, "model", request.Model)
+
+	resp, err := c.post(ctx, request)
+	if err != nil {
+		return "", ClaudeUsage{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", ClaudeUsage{}, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	var claudeResp ClaudeResponse
 	if err := json.Unmarshal(body, &claudeResp); err != nil {
-		return "", fmt.Errorf("failed to unmarshal Claude response: %w", err)
+		return "", ClaudeUsage{}, fmt.Errorf("failed to unmarshal Claude response: %w", err)
 	}
 
 	if len(claudeResp.Content) == 0 {
-		return "", fmt.Errorf("no content in Claude response")
+		return "", ClaudeUsage{}, fmt.Errorf("no content in Claude response")
 	}
 
 	// Extract text from content blocks
@@ -162,5 +261,5 @@ func (c *Client) sendChatRequest(ctx context.Context, messages []Message, correl
 		"tokens_used", claudeResp.Usage.InputTokens + claudeResp.Usage.OutputTokens,
 		"response_length", len(response))
 
-	return response, nil
+	return response, claudeResp.Usage, nil
 }
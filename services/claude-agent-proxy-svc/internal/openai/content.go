@@ -0,0 +1,57 @@
+package openai
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// TextMessage creates a message with only text content
+func TextMessage(role, text string) Message {
+	return Message{Role: role, Content: []ContentBlock{{Type: "text", Text: text}}}
+}
+
+// Text returns the text content of a message, ignoring images and documents
+func (m Message) Text() string {
+	var text strings.Builder
+	for _, block := range m.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	return text.String()
+}
+
+// AttachmentBlock converts a file into a content block the Messages API
+// accepts: images as images, PDFs as documents, and text files (such as
+// CSV exports) as plain text documents. Other types are rejected.
+func AttachmentBlock(name, mimeType string, data []byte) (ContentBlock, error) {
+	switch mimeType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return ContentBlock{
+			Type:   "image",
+			Source: &ContentSource{Type: "base64", MediaType: mimeType, Data: base64.StdEncoding.EncodeToString(data)},
+		}, nil
+
+	case "application/pdf":
+		return ContentBlock{
+			Type:   "document",
+			Title:  name,
+			Source: &ContentSource{Type: "base64", MediaType: mimeType, Data: base64.StdEncoding.EncodeToString(data)},
+		}, nil
+
+	case "text/plain", "text/csv", "text/markdown", "application/json":
+		if !utf8.Valid(data) {
+			return ContentBlock{}, fmt.Errorf("attachment %q is not valid UTF-8 text", name)
+		}
+		return ContentBlock{
+			Type:   "document",
+			Title:  name,
+			Source: &ContentSource{Type: "text", MediaType: "text/plain", Data: string(data)},
+		}, nil
+
+	default:
+		return ContentBlock{}, fmt.Errorf("attachment %q has unsupported type %q", name, mimeType)
+	}
+}
@@ -0,0 +1,78 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Persona is a named system prompt, used instead of the default one in
+// channels whose policy asks for it
+type Persona struct {
+	Prompt string `json:"prompt"`
+	// Bump Version whenever Prompt changes, like PromptVersion
+	Version string `json:"version"`
+}
+
+// Options adjust a single request; empty fields use the client's defaults
+type Options struct {
+	Persona string
+	Model   string
+}
+
+// LoadPersonas reads a JSON file mapping persona names to personas. An empty
+// path means there are no personas.
+func LoadPersonas(path string) (map[string]Persona, error) {
+	personas := make(map[string]Persona)
+	if path == "" {
+		return personas, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read personas file: %w", err)
+	}
+	if err := json.Unmarshal(data, &personas); err != nil {
+		return nil, fmt.Errorf("failed to parse personas file: %w", err)
+	}
+	return personas, nil
+}
+
+// ModelFor returns the model a request with opts is sent to
+func (c *Client) ModelFor(opts Options) string {
+	if opts.Model != "" {
+		return opts.Model
+	}
+	return c.model
+}
+
+// PromptVersionFor returns the version of the system prompt used for a
+// request with opts. Persona versions are prefixed with the persona name so
+// feedback can tell them apart from the default prompt.
+func (c *Client) PromptVersionFor(opts Options) string {
+	if persona, ok := c.persona(opts); ok {
+		return opts.Persona + "/" + persona.Version
+	}
+	return PromptVersion
+}
+
+// systemPromptFor returns the system prompt for a request with opts
+func (c *Client) systemPromptFor(opts Options) string {
+	if persona, ok := c.persona(opts); ok {
+		return persona.Prompt
+	}
+	return systemPrompt
+}
+
+// persona returns the persona opts asks for. Unknown personas fall back to
+// the default prompt so a policy typo doesn't stop Wavie from answering.
+func (c *Client) persona(opts Options) (Persona, bool) {
+	if opts.Persona == "" {
+		return Persona{}, false
+	}
+	persona, ok := c.personas[opts.Persona]
+	if !ok {
+		c.logger.Warn("Unknown persona, using the default prompt", "persona", opts.Persona)
+	}
+	return persona, ok
+}
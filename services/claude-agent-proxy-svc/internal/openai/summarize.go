@@ -0,0 +1,305 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// SummaryPromptVersion identifies the summarization prompts. Bump it whenever
+// they change, like PromptVersion.
+const SummaryPromptVersion = "summary/1"
+
+const (
+	// Rough characters per token, for sizing chunks without a tokenizer
+	charsPerToken = 4
+
+	// Room for each summary call's answer
+	summaryMaxTokens = 2000
+
+	summaryTemperature = 0.2
+)
+
+const summarySystemPrompt = "You are Wavie, an assistant for Bitwave that summarizes Slack threads for people who weren't in them. " +
+	"Be faithful to the thread: never invent decisions, owners or dates, and keep people's names exactly as written. " +
+	"Reply with only a JSON object, no prose or code fences, of the form " +
+	`{"overview": "two or three sentences", "decisions": ["..."], "open_questions": ["..."], "action_items": [{"owner": "@name", "task": "..."}]}. ` +
+	"Use empty lists when there is nothing to report, and an empty owner when nobody took a task on."
+
+// ThreadMessage is one message of a thread to summarize, with its author
+// already resolved to a name
+type ThreadMessage struct {
+	Author    string
+	Text      string
+	Timestamp time.Time
+}
+
+// ActionItem is a task someone took on, or was asked to, in a thread
+type ActionItem struct {
+	Owner string `json:"owner"`
+	Task  string `json:"task"`
+}
+
+// Summary is a thread's structured summary
+type Summary struct {
+	Overview      string       `json:"overview"`
+	Decisions     []string     `json:"decisions"`
+	OpenQuestions []string     `json:"open_questions"`
+	ActionItems   []ActionItem `json:"action_items"`
+}
+
+// Summarizer summarizes threads too long for one request by splitting them
+// into chunks that fit the context window, summarizing each (map), then
+// merging the partial summaries (reduce), in as many rounds as it takes
+type Summarizer struct {
+	client      *Client
+	chunkChars  int
+	concurrency int
+	logger      *slog.Logger
+}
+
+// NewSummarizer creates a summarizer that sends at most chunkTokens of thread
+// per request and runs up to concurrency requests at once
+func NewSummarizer(client *Client, chunkTokens, concurrency int, logger *slog.Logger) *Summarizer {
+	return &Summarizer{
+		client:      client,
+		chunkChars:  chunkTokens * charsPerToken,
+		concurrency: max(concurrency, 1),
+		logger:      logger,
+	}
+}
+
+// Summarize returns the structured summary of a thread, the tokens it used
+// and how many chunks the thread was split into
+func (s *Summarizer) Summarize(ctx context.Context, messages []ThreadMessage, opts Options, correlationID string) (Summary, ClaudeUsage, int, error) {
+	var usage usageTotal
+
+	chunks := chunkLines(transcriptLines(messages), s.chunkChars)
+	if len(chunks) == 0 {
+		return Summary{}, ClaudeUsage{}, 0, fmt.Errorf("thread has no messages to summarize")
+	}
+
+	s.logger.Info("Summarizing thread",
+		"correlation_id", correlationID,
+		"messages", len(messages),
+		"chunks", len(chunks))
+
+	if len(chunks) == 1 {
+		text, err := s.complete(ctx, "Summarize this Slack thread:\n\n"+chunks[0], opts, correlationID, &usage)
+		if err != nil {
+			return Summary{}, usage.total(), 1, err
+		}
+		return parseSummary(text), usage.total(), 1, nil
+	}
+
+	// Map: summarize each chunk on its own
+	partials := make([]string, len(chunks))
+	err := s.forEach(ctx, len(chunks), func(ctx context.Context, i int) (string, error) {
+		prompt := fmt.Sprintf("This is part %d of %d of a long Slack thread. Summarize this part:\n\n%s", i+1, len(chunks), chunks[i])
+		return s.complete(ctx, prompt, opts, correlationID, &usage)
+	}, partials)
+	if err != nil {
+		return Summary{}, usage.total(), len(chunks), err
+	}
+
+	// Reduce: merge partial summaries, in groups that fit a request, until
+	// one is left
+	for round := 1; len(partials) > 1; round++ {
+		groups := groupPartials(partials, s.chunkChars)
+		s.logger.Info("Merging partial summaries",
+			"correlation_id", correlationID,
+			"round", round,
+			"partials", len(partials),
+			"groups", len(groups))
+
+		merged := make([]string, len(groups))
+		err := s.forEach(ctx, len(groups), func(ctx context.Context, i int) (string, error) {
+			prompt := "These are summaries of consecutive parts of one long Slack thread, in order. " +
+				"Merge them into one summary of the whole thread, dropping questions that a later part answered:\n\n" +
+				strings.Join(groups[i], "\n\n")
+			return s.complete(ctx, prompt, opts, correlationID, &usage)
+		}, merged)
+		if err != nil {
+			return Summary{}, usage.total(), len(chunks), err
+		}
+		partials = merged
+	}
+
+	return parseSummary(partials[0]), usage.total(), len(chunks), nil
+}
+
+// forEach runs fn for 0..n-1, at most s.concurrency at a time, storing the
+// results in order. The first error cancels the calls still running and is
+// returned.
+func (s *Summarizer) forEach(ctx context.Context, n int, fn func(ctx context.Context, i int) (string, error), results []string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	slots := make(chan struct{}, s.concurrency)
+
+	for i := range n {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			result, err := fn(ctx, i)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// complete sends one summarization request and adds its tokens to usage
+func (s *Summarizer) complete(ctx context.Context, prompt string, opts Options, correlationID string, usage *usageTotal) (string, error) {
+	request := s.client.newClaudeRequest(s.client.ModelFor(opts), []Message{
+		TextMessage("system", summarySystemPrompt),
+		TextMessage("user", prompt),
+	})
+	request.MaxTokens = summaryMaxTokens
+	request.Temperature = summaryTemperature
+
+	text, used, err := s.client.sendChatRequest(ctx, request, correlationID)
+	if err != nil {
+		return "", err
+	}
+
+	usage.add(used)
+	return text, nil
+}
+
+// usageTotal adds up the tokens of calls made concurrently
+type usageTotal struct {
+	mutex sync.Mutex
+	usage ClaudeUsage
+}
+
+func (u *usageTotal) add(used ClaudeUsage) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.usage.InputTokens += used.InputTokens
+	u.usage.OutputTokens += used.OutputTokens
+}
+
+func (u *usageTotal) total() ClaudeUsage {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.usage
+}
+
+// transcriptLines renders messages as "[time] author: text" lines
+func transcriptLines(messages []ThreadMessage) []string {
+	lines := make([]string, 0, len(messages))
+	for _, message := range messages {
+		text := strings.TrimSpace(message.Text)
+		if text == "" {
+			continue
+		}
+		line := message.Author + ": " + text
+		if !message.Timestamp.IsZero() {
+			line = "[" + message.Timestamp.UTC().Format("2006-01-02 15:04") + "] " + line
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// chunkLines joins lines into chunks of at most limit characters. A line
+// longer than a whole chunk is cut short rather than split across chunks,
+// and marked as cut if the limit leaves room for the marker.
+func chunkLines(lines []string, limit int) []string {
+	marker := " […]"
+	if limit < len(marker) {
+		marker = ""
+	}
+
+	var chunks []string
+	var chunk strings.Builder
+	for _, line := range lines {
+		if len(line) > limit {
+			cut := max(limit-len(marker), 0)
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			line = line[:cut] + marker
+		}
+		if chunk.Len() > 0 && chunk.Len()+1+len(line) > limit {
+			chunks = append(chunks, chunk.String())
+			chunk.Reset()
+		}
+		if chunk.Len() > 0 {
+			chunk.WriteByte('\n')
+		}
+		chunk.WriteString(line)
+	}
+	if chunk.Len() > 0 {
+		chunks = append(chunks, chunk.String())
+	}
+	return chunks
+}
+
+// groupPartials splits partial summaries into groups of at most limit
+// characters. Every group has at least two partials, so each round of
+// merging makes progress.
+func groupPartials(partials []string, limit int) [][]string {
+	var groups [][]string
+	var group []string
+	size := 0
+	for _, partial := range partials {
+		if len(group) >= 2 && size+len(partial) > limit {
+			groups = append(groups, group)
+			group, size = nil, 0
+		}
+		group = append(group, partial)
+		size += len(partial)
+	}
+	if len(group) == 1 && len(groups) > 0 {
+		// Don't leave a partial to be merged with nothing
+		groups[len(groups)-1] = append(groups[len(groups)-1], group[0])
+	} else if len(group) > 0 {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// parseSummary decodes Claude's JSON summary. Anything that isn't valid JSON
+// becomes the overview, so a malformed reply still reaches the user.
+func parseSummary(text string) Summary {
+	start := strings.IndexByte(text, '{')
+	end := strings.LastIndexByte(text, '}')
+	if start >= 0 && end > start {
+		var summary Summary
+		if err := json.Unmarshal([]byte(text[start:end+1]), &summary); err == nil {
+			return summary
+		}
+	}
+	return Summary{Overview: strings.TrimSpace(text)}
+}
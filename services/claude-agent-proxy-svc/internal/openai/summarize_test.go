@@ -0,0 +1,111 @@
+package openai
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChunkLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		limit int
+		want  []string
+	}{
+		{name: "empty thread", limit: 10},
+		{name: "fits in one chunk", lines: []string{"a", "b"}, limit: 10, want: []string{"a\nb"}},
+		{
+			name:  "splits between lines",
+			lines: []string{"aaaa", "bbbb", "cc"},
+			limit: 9,
+			want:  []string{"aaaa\nbbbb", "cc"},
+		},
+		{
+			name:  "cuts a line longer than a chunk",
+			lines: []string{"ok", strings.Repeat("x", 20), "ok"},
+			limit: 10,
+			want:  []string{"ok", "xxxx […]", "ok"},
+		},
+		{
+			name:  "cuts between runes",
+			lines: []string{strings.Repeat("é", 10)},
+			limit: 11,
+			want:  []string{"éé […]"},
+		},
+		{
+			name:  "limit too small for the marker",
+			lines: []string{"abcdefgh", "ab"},
+			limit: 4,
+			want:  []string{"abcd", "ab"},
+		},
+		{
+			name:  "limit too small for the marker cuts between runes",
+			lines: []string{"éé"},
+			limit: 3,
+			want:  []string{"é"},
+		},
+		{name: "no room for any text", lines: []string{"abc"}, limit: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := chunkLines(tt.lines, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("chunkLines = %q, want %q", got, tt.want)
+			}
+			for _, chunk := range got {
+				if len(chunk) > tt.limit {
+					t.Errorf("chunk %q is longer than %d", chunk, tt.limit)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupPartials(t *testing.T) {
+	tests := []struct {
+		name     string
+		partials []string
+		limit    int
+		want     [][]string
+	}{
+		{name: "one partial", partials: []string{"a"}, limit: 10, want: [][]string{{"a"}}},
+		{
+			name:     "all fit in one group",
+			partials: []string{"aaa", "bbb", "ccc"},
+			limit:    10,
+			want:     [][]string{{"aaa", "bbb", "ccc"}},
+		},
+		{
+			name:     "splits into groups",
+			partials: []string{"aaa", "bbb", "ccc", "ddd"},
+			limit:    6,
+			want:     [][]string{{"aaa", "bbb"}, {"ccc", "ddd"}},
+		},
+		{
+			name:     "last partial joins the group before it",
+			partials: []string{"aaa", "bbb", "ccc"},
+			limit:    6,
+			want:     [][]string{{"aaa", "bbb", "ccc"}},
+		},
+		{
+			name:     "partials longer than the limit are still paired",
+			partials: []string{"aaaaaaaa", "bbbbbbbb", "cccccccc", "dddddddd"},
+			limit:    5,
+			want:     [][]string{{"aaaaaaaa", "bbbbbbbb"}, {"cccccccc", "dddddddd"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := groupPartials(tt.partials, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("groupPartials = %q, want %q", got, tt.want)
+			}
+			if len(tt.partials) > 1 && len(got) >= len(tt.partials) {
+				t.Errorf("%d partials made %d groups; merging wouldn't make progress", len(tt.partials), len(got))
+			}
+		})
+	}
+}
@@ -15,11 +15,14 @@ type ClaudeRequest struct {
 	Messages    []Message `json:"messages"`
 	Temperature float64   `json:"temperature,omitempty"`
 	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
 }
 
+// Message is one turn of a conversation. Its content is a list of blocks so
+// a user turn can carry images and documents as well as text.
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string         `json:"role"`
+	Content []ContentBlock `json:"content"`
 }
 
 // OpenAI style response (legacy)
@@ -50,9 +53,36 @@ type ClaudeResponse struct {
 	Usage        ClaudeUsage    `json:"usage"`
 }
 
+// StreamEvent is one server-sent event from the streaming Messages API
+type StreamEvent struct {
+	Type    string          `json:"type"`
+	Message *ClaudeResponse `json:"message,omitempty"` // message_start
+	Delta   *StreamDelta    `json:"delta,omitempty"`   // content_block_delta, message_delta
+	Usage   *ClaudeUsage    `json:"usage,omitempty"`   // message_delta
+	Error   *ErrorDetail    `json:"error,omitempty"`   // error
+}
+
+type StreamDelta struct {
+	Type       string `json:"type,omitempty"`
+	Text       string `json:"text,omitempty"`
+	StopReason string `json:"stop_reason,omitempty"`
+}
+
+// ContentBlock is a piece of message content: "text", or an "image" or
+// "document" with its source
 type ContentBlock struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type   string         `json:"type"`
+	Text   string         `json:"text,omitempty"`
+	Source *ContentSource `json:"source,omitempty"`
+	Title  string         `json:"title,omitempty"` // documents only
+}
+
+// ContentSource holds an image or document: "base64" encoded data, or
+// "text" for plain text documents
+type ContentSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
 }
 
 type ClaudeUsage struct {
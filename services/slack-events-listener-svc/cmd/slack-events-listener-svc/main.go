@@ -10,9 +10,20 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/BitwaveCorp/shared-svcs/shared/dedup"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/activity"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/answers"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/attachments"
 	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/api"
 	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/config"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/conversation"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/installations"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/outbox"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/policy"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/ratelimit"
 	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/socketmode"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/workerpool"
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
 )
@@ -40,17 +51,195 @@ func main() {
 		slog.SetDefault(logger)
 	}
 
+	switch cfg.SlackTransport {
+	case "http":
+		if cfg.SlackSigningSecret == "" {
+			slog.Error("SLACK_SIGNING_SECRET is required in http mode")
+			os.Exit(1)
+		}
+	case "socket":
+		if cfg.SlackAppToken == "" {
+			slog.Error("SLACK_APP_TOKEN is required in socket mode")
+			os.Exit(1)
+		}
+	default:
+		slog.Error("Unknown SLACK_TRANSPORT", "transport", cfg.SlackTransport)
+		os.Exit(1)
+	}
+
+	if cfg.SlackClientID != "" && (cfg.SlackClientSecret == "" || cfg.InstallationEncryptionKey == "") {
+		slog.Error("SLACK_CLIENT_SECRET and INSTALLATION_ENCRYPTION_KEY are required for OAuth installs")
+		os.Exit(1)
+	}
+	if cfg.SlackBotToken == "" && cfg.InstallationEncryptionKey == "" {
+		slog.Error("SLACK_BOT_TOKEN is required unless workspaces install through OAuth")
+		os.Exit(1)
+	}
+
 	slog.Info("Starting Slack Events Listener Service",
 		"port", cfg.Port,
+		"transport", cfg.SlackTransport,
 		"claude_proxy_url", cfg.ClaudeProxyServiceURL,
 		"broadcast_url", cfg.BroadcastServiceURL,
+		"conversation_store", cfg.ConversationStoreBackend,
 	)
 
-	slackClient := slack.NewClient(cfg.SlackBotToken, logger)
-	handler := api.NewHandler(slackClient, cfg.SlackSigningSecret, cfg.ClaudeProxyServiceURL, cfg.BroadcastServiceURL, logger)
+	backend, err := conversation.OpenBackend(cfg.ConversationStoreBackend, cfg.ConversationStorePath)
+	if err != nil {
+		slog.Error("Failed to open conversation store", "error", err)
+		os.Exit(1)
+	}
+	conversationStore := conversation.NewStore(backend, cfg.ConversationMaxMessages, cfg.ConversationMaxAge, logger)
+	defer conversationStore.Close()
+
+	answerBackend, err := answers.OpenBackend(cfg.AnswerStoreBackend, cfg.AnswerStorePath)
+	if err != nil {
+		slog.Error("Failed to open answer store", "error", err)
+		os.Exit(1)
+	}
+	answerStore := answers.NewStore(answerBackend, cfg.AnswerRetention, logger)
+	defer answerStore.Close()
+
+	activityBackend, err := activity.OpenBackend(cfg.ActivityStoreBackend, cfg.ActivityStorePath)
+	if err != nil {
+		slog.Error("Failed to open activity store", "error", err)
+		os.Exit(1)
+	}
+	activityStore := activity.NewStore(activityBackend, cfg.ActivityRetention, logger)
+	defer activityStore.Close()
+
+	broadcastOutbox, err := outbox.Open(cfg.OutboxPath, cfg.BroadcastServiceURL, cfg.OutboxMaxAttempts, logger)
+	if err != nil {
+		slog.Error("Failed to open outbox", "error", err)
+		os.Exit(1)
+	}
+	defer broadcastOutbox.Close()
+
+	dedupBackend, err := dedup.OpenBackend(cfg.DedupBackend, cfg.DedupPath)
+	if err != nil {
+		slog.Error("Failed to open dedup store", "error", err)
+		os.Exit(1)
+	}
+	events := dedup.New(dedupBackend, cfg.DedupTTL, logger)
+	defer events.Close()
+
+	pool := workerpool.New(cfg.WorkerCount, cfg.WorkerQueueSize, logger)
+
+	// Each workspace's events are handled with its own bot token; without an
+	// installation store every workspace uses SLACK_BOT_TOKEN
+	var installationStore *installations.Store
+	var tokens slack.TokenSource
+	if cfg.InstallationEncryptionKey != "" {
+		key, err := installations.ParseKey(cfg.InstallationEncryptionKey)
+		if err != nil {
+			slog.Error("Invalid INSTALLATION_ENCRYPTION_KEY", "error", err)
+			os.Exit(1)
+		}
+		installationBackend, err := installations.OpenBackend(cfg.InstallationStoreBackend, cfg.InstallationStorePath)
+		if err != nil {
+			slog.Error("Failed to open installation store", "error", err)
+			os.Exit(1)
+		}
+		installationStore, err = installations.NewStore(installationBackend, key, logger)
+		if err != nil {
+			slog.Error("Failed to create installation store", "error", err)
+			os.Exit(1)
+		}
+		defer installationStore.Close()
+		tokens = installationStore
+	}
+
+	// SLACK_BOT_TOKEN is only ever used for the workspace it belongs to
+	homeTeamID := cfg.SlackTeamID
+	if homeTeamID == "" && cfg.SlackBotToken != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		homeTeamID, err = slack.NewClient(cfg.SlackAPIBaseURL, cfg.SlackBotToken, logger).TeamID(ctx)
+		cancel()
+		if err != nil {
+			slog.Error("Failed to look up the home workspace; set SLACK_TEAM_ID", "error", err)
+			os.Exit(1)
+		}
+	}
+	slackClients := slack.NewClients(cfg.SlackAPIBaseURL, tokens, cfg.SlackBotToken, homeTeamID, cfg.NameCacheTTL, logger)
+
+	limitBackend, err := ratelimit.OpenBackend(cfg.RateLimitBackend, cfg.RateLimitPath, cfg.RateLimitRedisURL)
+	if err != nil {
+		slog.Error("Failed to open rate limit store", "error", err)
+		os.Exit(1)
+	}
+	limiter := ratelimit.NewLimiter(limitBackend, map[string]ratelimit.Limits{
+		"user":      {PerMinute: cfg.UserRatePerMinute, Burst: cfg.UserRateBurst, DailyRequests: cfg.UserDailyRequests, DailyTokens: cfg.UserDailyTokens},
+		"channel":   {PerMinute: cfg.ChannelRatePerMinute, Burst: cfg.ChannelRateBurst, DailyRequests: cfg.ChannelDailyRequests, DailyTokens: cfg.ChannelDailyTokens},
+		"workspace": {PerMinute: cfg.WorkspaceRatePerMinute, Burst: cfg.WorkspaceRateBurst, DailyRequests: cfg.WorkspaceDailyRequests, DailyTokens: cfg.WorkspaceDailyTokens},
+	}, logger)
+	defer limiter.Close()
+
+	policies, err := policy.Load(cfg.ChannelPolicyPath)
+	if err != nil {
+		slog.Error("Failed to load channel policy", "error", err)
+		os.Exit(1)
+	}
+
+	downloader := attachments.NewDownloader(cfg.MaxAttachments, cfg.MaxAttachmentBytes, logger)
+	handler := api.NewHandler(api.HandlerOptions{
+		SlackClients:            slackClients,
+		Installations:           installationStore,
+		Attachments:             downloader,
+		Policies:                policies,
+		Limiter:                 limiter,
+		ConversationStore:       conversationStore,
+		AnswerStore:             answerStore,
+		Activity:                activityStore,
+		Outbox:                  broadcastOutbox,
+		Events:                  events,
+		Pool:                    pool,
+		SigningSecret:           cfg.SlackSigningSecret,
+		ClaudeProxyServiceURL:   cfg.ClaudeProxyServiceURL,
+		BroadcastServiceURL:     cfg.BroadcastServiceURL,
+		BroadcastDirectMessages: cfg.BroadcastDirectMessages,
+		BroadcastAllWorkspaces:  cfg.BroadcastAllWorkspaces,
+		StreamResponses:         cfg.ClaudeStreaming,
+		Logger:                  logger,
+	})
 
 	mux := http.NewServeMux()
-	handler.RegisterRoutes(mux)
+	if cfg.SlackTransport == "socket" {
+		// Only health checks are served over HTTP; Slack traffic uses the socket
+		handler.RegisterServiceRoutes(mux)
+	} else {
+		handler.RegisterRoutes(mux)
+	}
+
+	// The install flow is a browser redirect, so it is served over HTTP in
+	// either transport
+	if cfg.SlackClientID != "" {
+		oauth := slack.NewOAuth(cfg.SlackAPIBaseURL, cfg.SlackClientID, cfg.SlackClientSecret, cfg.SlackOAuthRedirectURL, cfg.SlackOAuthScopes, logger)
+		api.NewOAuthHandler(oauth, installationStore, logger).RegisterRoutes(mux)
+	}
+
+	if cfg.AdminToken != "" {
+		api.NewAdminHandler(broadcastOutbox, cfg.AdminToken, logger).RegisterRoutes(mux)
+	}
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	go broadcastOutbox.Run(backgroundCtx)
+
+	// The socket is stopped at shutdown like the HTTP server, before the
+	// worker pool, and closed once the envelopes it took are handled
+	socketCtx, stopSocket := context.WithCancel(context.Background())
+	defer stopSocket()
+	socketDone := make(chan struct{})
+	if cfg.SlackTransport == "socket" {
+		socketClient := socketmode.NewClient(cfg.SlackAppToken, cfg.SlackAPIBaseURL, handler, logger)
+		go func() {
+			socketClient.Run(socketCtx)
+			close(socketDone)
+		}()
+	} else {
+		close(socketDone)
+	}
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.Port),
@@ -68,14 +257,28 @@ func main() {
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	sig := <-sigCh
 
-	slog.Info("Received signal, shutting down", "signal", sig)
+	slog.Info("Received signal, shutting down", "signal", sig, "grace_period", cfg.ShutdownGracePeriod)
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.ShutdownGracePeriod)
 	defer shutdownCancel()
 
+	// Stop taking new work first, then let queued work finish. The outbox
+	// keeps running meanwhile so broadcasts from finishing answers are sent.
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		slog.Error("HTTP server shutdown failed", "error", err)
 	}
+	stopSocket()
+	select {
+	case <-socketDone:
+	case <-shutdownCtx.Done():
+		slog.Error("Socket Mode client didn't stop within the grace period")
+	}
+
+	if err := pool.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Worker pool shutdown failed", "error", err)
+	}
+
+	stopBackground()
 
 	slog.Info("Service shutdown complete")
 }
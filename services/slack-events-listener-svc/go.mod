@@ -3,9 +3,34 @@ module github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-eve
 go 1.24
 
 require (
+	github.com/BitwaveCorp/shared-svcs/shared/boltbucket v0.0.0
+	github.com/BitwaveCorp/shared-svcs/shared/dedup v0.0.0
+	github.com/BitwaveCorp/shared-svcs/shared/mrkdwn v0.0.0
+	github.com/BitwaveCorp/shared-svcs/shared/slackapi v0.0.0
 	github.com/BitwaveCorp/shared-svcs/shared/utils v0.0.0
-	github.com/kelseyhightower/envconfig v1.4.0
+	github.com/alicebob/miniredis/v2 v2.34.0
+	github.com/google/uuid v1.6.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/joho/godotenv v1.5.1
+	github.com/kelseyhightower/envconfig v1.4.0
+	github.com/redis/go-redis/v9 v9.7.3
+	go.etcd.io/bbolt v1.3.11
+)
+
+require (
+	github.com/alicebob/gopher-json v0.0.0-20230218143504-906a9b012302 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
+	golang.org/x/sys v0.4.0 // indirect
 )
 
 replace github.com/BitwaveCorp/shared-svcs/shared/utils => ../../shared/utils
+
+replace github.com/BitwaveCorp/shared-svcs/shared/boltbucket => ../../shared/boltbucket
+
+replace github.com/BitwaveCorp/shared-svcs/shared/dedup => ../../shared/dedup
+
+replace github.com/BitwaveCorp/shared-svcs/shared/mrkdwn => ../../shared/mrkdwn
+
+replace github.com/BitwaveCorp/shared-svcs/shared/slackapi => ../../shared/slackapi
@@ -0,0 +1,79 @@
+package activity
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BitwaveCorp/shared-svcs/shared/boltbucket"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltBackend stores activity in an embedded bbolt database so users' Home
+// tabs survive restarts
+type BoltBackend struct {
+	bucket *boltbucket.Bucket
+}
+
+// NewBoltBackend opens (or creates) the database file at path
+func NewBoltBackend(path string) (*BoltBackend, error) {
+	bucket, err := boltbucket.Open(path, "activity")
+	if err != nil {
+		return nil, err
+	}
+	return &BoltBackend{bucket: bucket}, nil
+}
+
+func (b *BoltBackend) Get(userID string) (*Activity, error) {
+	var activity *Activity
+	err := b.bucket.View(func(bucket *bolt.Bucket) error {
+		data := bucket.Get([]byte(userID))
+		if data == nil {
+			return nil
+		}
+		activity = &Activity{}
+		return json.Unmarshal(data, activity)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read activity: %w", err)
+	}
+	return activity, nil
+}
+
+func (b *BoltBackend) Update(userID string, fn func(activity *Activity)) error {
+	err := b.bucket.Update(func(bucket *bolt.Bucket) error {
+		var activity Activity
+		if data := bucket.Get([]byte(userID)); data != nil {
+			if err := json.Unmarshal(data, &activity); err != nil {
+				return err
+			}
+		}
+
+		fn(&activity)
+
+		data, err := json.Marshal(activity)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(userID), data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write activity: %w", err)
+	}
+	return nil
+}
+
+func (b *BoltBackend) DeleteExpired(cutoff time.Time) (int, error) {
+	removed, err := b.bucket.DeleteIf(func(_, data []byte) bool {
+		var activity Activity
+		return json.Unmarshal(data, &activity) != nil || activity.UpdatedAt.Before(cutoff)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired activity: %w", err)
+	}
+	return removed, nil
+}
+
+func (b *BoltBackend) Close() error {
+	return b.bucket.Close()
+}
@@ -0,0 +1,59 @@
+package activity
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryBackend keeps activity in an in-process map. It is lost on restart
+// and is not shared between instances.
+type MemoryBackend struct {
+	activity map[string]Activity
+	mutex    sync.RWMutex
+}
+
+// NewMemoryBackend creates an empty in-memory backend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		activity: make(map[string]Activity),
+	}
+}
+
+func (b *MemoryBackend) Get(userID string) (*Activity, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	activity, exists := b.activity[userID]
+	if !exists {
+		return nil, nil
+	}
+	return &activity, nil
+}
+
+func (b *MemoryBackend) Update(userID string, fn func(activity *Activity)) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	activity := b.activity[userID]
+	fn(&activity)
+	b.activity[userID] = activity
+	return nil
+}
+
+func (b *MemoryBackend) DeleteExpired(cutoff time.Time) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	removed := 0
+	for userID, activity := range b.activity {
+		if activity.UpdatedAt.Before(cutoff) {
+			delete(b.activity, userID)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (b *MemoryBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,170 @@
+// Package activity remembers what each user recently did with Wavie, the
+// questions they asked and the feedback they gave, for their App Home tab.
+package activity
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// How many questions and feedback entries are kept per user
+const maxEntries = 10
+
+// Question is a question a user asked and where it was answered
+type Question struct {
+	ChannelID string    `json:"channel_id"`
+	MessageTS string    `json:"message_ts"` // the answer
+	ThreadTS  string    `json:"thread_ts,omitempty"`
+	Question  string    `json:"question"`
+	AskedAt   time.Time `json:"asked_at"`
+}
+
+// Feedback is feedback a user gave on an answer
+type Feedback struct {
+	ChannelID    string    `json:"channel_id"`
+	MessageTS    string    `json:"message_ts"` // the rated answer
+	ThreadTS     string    `json:"thread_ts,omitempty"`
+	FeedbackType string    `json:"feedback_type"`
+	FeedbackText string    `json:"feedback_text,omitempty"`
+	Question     string    `json:"question,omitempty"`
+	GivenAt      time.Time `json:"given_at"`
+}
+
+// Activity is a user's recent questions and feedback, newest first
+type Activity struct {
+	Questions []Question `json:"questions,omitempty"`
+	Feedback  []Feedback `json:"feedback,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Backend persists activity keyed by user ID. Implementations must be safe
+// for concurrent use.
+type Backend interface {
+	// Get returns a user's activity, or nil if none is stored.
+	Get(userID string) (*Activity, error)
+	// Update applies fn to a user's activity (empty if none is stored) and
+	// saves it atomically.
+	Update(userID string, fn func(activity *Activity)) error
+	// DeleteExpired removes activity last updated before cutoff and returns
+	// how many users were removed.
+	DeleteExpired(cutoff time.Time) (int, error)
+	// Close closes the activity database, if any.
+	Close() error
+}
+
+// OpenBackend returns a memory backend for kind "" or "memory", or opens the
+// activity database at path for "bolt".
+func OpenBackend(kind, path string) (Backend, error) {
+	switch kind {
+	case "", "memory":
+		return NewMemoryBackend(), nil
+	case "bolt":
+		return NewBoltBackend(path)
+	default:
+		return nil, fmt.Errorf("unknown activity store backend: %q", kind)
+	}
+}
+
+// Store records user activity. Users who haven't used Wavie for the
+// retention period are forgotten.
+type Store struct {
+	backend   Backend
+	retention time.Duration
+	logger    *slog.Logger
+	done      chan struct{}
+}
+
+// NewStore creates an activity store that keeps activity for retention
+func NewStore(backend Backend, retention time.Duration, logger *slog.Logger) *Store {
+	store := &Store{
+		backend:   backend,
+		retention: retention,
+		logger:    logger,
+		done:      make(chan struct{}),
+	}
+
+	go store.cleanupRoutine()
+
+	return store
+}
+
+// RecordQuestion adds a question the user asked
+func (s *Store) RecordQuestion(userID string, question Question) {
+	if question.AskedAt.IsZero() {
+		question.AskedAt = time.Now()
+	}
+
+	err := s.backend.Update(userID, func(activity *Activity) {
+		activity.Questions = prepend(activity.Questions, question)
+		activity.UpdatedAt = question.AskedAt
+	})
+	if err != nil {
+		s.logger.Error("Failed to record question", "error", err, "user", userID)
+	}
+}
+
+// RecordFeedback adds feedback the user gave
+func (s *Store) RecordFeedback(userID string, feedback Feedback) {
+	if feedback.GivenAt.IsZero() {
+		feedback.GivenAt = time.Now()
+	}
+
+	err := s.backend.Update(userID, func(activity *Activity) {
+		activity.Feedback = prepend(activity.Feedback, feedback)
+		activity.UpdatedAt = feedback.GivenAt
+	})
+	if err != nil {
+		s.logger.Error("Failed to record feedback", "error", err, "user", userID)
+	}
+}
+
+// Get returns a user's recent activity
+func (s *Store) Get(userID string) Activity {
+	activity, err := s.backend.Get(userID)
+	if err != nil {
+		s.logger.Error("Failed to read activity", "error", err, "user", userID)
+		return Activity{}
+	}
+	if activity == nil || time.Since(activity.UpdatedAt) > s.retention {
+		return Activity{}
+	}
+	return *activity
+}
+
+// Close stops the cleanup routine and closes the backend
+func (s *Store) Close() error {
+	close(s.done)
+	return s.backend.Close()
+}
+
+// cleanupRoutine periodically removes activity past retention
+func (s *Store) cleanupRoutine() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			removed, err := s.backend.DeleteExpired(time.Now().Add(-s.retention))
+			if err != nil {
+				s.logger.Error("Failed to clean up activity", "error", err)
+				continue
+			}
+			if removed > 0 {
+				s.logger.Info("Cleaned up expired activity", "removed", removed)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// prepend adds entry at the front, dropping the oldest beyond maxEntries
+func prepend[T any](entries []T, entry T) []T {
+	entries = append([]T{entry}, entries...)
+	if len(entries) > maxEntries {
+		entries = entries[:maxEntries]
+	}
+	return entries
+}
@@ -0,0 +1,71 @@
+package answers
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BitwaveCorp/shared-svcs/shared/boltbucket"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltBackend stores answers in an embedded bbolt database so feedback on
+// answers posted before a restart can still be linked
+type BoltBackend struct {
+	bucket *boltbucket.Bucket
+}
+
+// NewBoltBackend opens (or creates) the database file at path
+func NewBoltBackend(path string) (*BoltBackend, error) {
+	bucket, err := boltbucket.Open(path, "answers")
+	if err != nil {
+		return nil, err
+	}
+	return &BoltBackend{bucket: bucket}, nil
+}
+
+func (b *BoltBackend) Get(channelID, messageTS string) (*Answer, error) {
+	var answer *Answer
+	err := b.bucket.View(func(bucket *bolt.Bucket) error {
+		data := bucket.Get([]byte(key(channelID, messageTS)))
+		if data == nil {
+			return nil
+		}
+		answer = &Answer{}
+		return json.Unmarshal(data, answer)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read answer: %w", err)
+	}
+	return answer, nil
+}
+
+func (b *BoltBackend) Put(answer *Answer) error {
+	data, err := json.Marshal(answer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal answer: %w", err)
+	}
+
+	err = b.bucket.Update(func(bucket *bolt.Bucket) error {
+		return bucket.Put([]byte(key(answer.ChannelID, answer.MessageTS)), data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write answer: %w", err)
+	}
+	return nil
+}
+
+func (b *BoltBackend) DeleteExpired(cutoff time.Time) (int, error) {
+	removed, err := b.bucket.DeleteIf(func(_, data []byte) bool {
+		var answer Answer
+		return json.Unmarshal(data, &answer) != nil || answer.PostedAt.Before(cutoff)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired answers: %w", err)
+	}
+	return removed, nil
+}
+
+func (b *BoltBackend) Close() error {
+	return b.bucket.Close()
+}
@@ -0,0 +1,57 @@
+package answers
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryBackend keeps answers in an in-process map. They are lost on restart
+// and are not shared between instances.
+type MemoryBackend struct {
+	answers map[string]Answer
+	mutex   sync.RWMutex
+}
+
+// NewMemoryBackend creates an empty in-memory backend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		answers: make(map[string]Answer),
+	}
+}
+
+func (b *MemoryBackend) Get(channelID, messageTS string) (*Answer, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	answer, exists := b.answers[key(channelID, messageTS)]
+	if !exists {
+		return nil, nil
+	}
+	return &answer, nil
+}
+
+func (b *MemoryBackend) Put(answer *Answer) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.answers[key(answer.ChannelID, answer.MessageTS)] = *answer
+	return nil
+}
+
+func (b *MemoryBackend) DeleteExpired(cutoff time.Time) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	removed := 0
+	for k, answer := range b.answers {
+		if answer.PostedAt.Before(cutoff) {
+			delete(b.answers, k)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (b *MemoryBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,131 @@
+package answers
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Answer records one message Wavie posted, so feedback on it can be traced
+// back to the question, the answer, and the answer's correlation ID
+type Answer struct {
+	ChannelID     string    `json:"channel_id"`
+	MessageTS     string    `json:"message_ts"`
+	ThreadTS      string    `json:"thread_ts,omitempty"`
+	CorrelationID string    `json:"correlation_id"`
+	Model         string    `json:"model,omitempty"`
+	PromptVersion string    `json:"prompt_version,omitempty"`
+	Question      string    `json:"question"`
+	Response      string    `json:"response"`
+	PostedAt      time.Time `json:"posted_at"`
+}
+
+// Backend persists answers keyed by channel and message timestamp.
+// Implementations must be safe for concurrent use.
+type Backend interface {
+	// Get returns the answer posted as channelID/messageTS, or nil if none is stored.
+	Get(channelID, messageTS string) (*Answer, error)
+	// Put creates or replaces an answer.
+	Put(answer *Answer) error
+	// DeleteExpired removes answers posted before cutoff and returns how many
+	// were removed.
+	DeleteExpired(cutoff time.Time) (int, error)
+	// Close closes the answer database; the memory backend has nothing
+	// to close.
+	Close() error
+}
+
+// OpenBackend opens the answer store for ANSWER_STORE_BACKEND. path is the
+// database file of the "bolt" backend.
+func OpenBackend(kind, path string) (Backend, error) {
+	switch kind {
+	case "", "memory":
+		return NewMemoryBackend(), nil
+	case "bolt":
+		return NewBoltBackend(path)
+	default:
+		return nil, fmt.Errorf("unknown answer store backend: %q", kind)
+	}
+}
+
+// Store maps the messages Wavie posted to the answers they carry. Answers are
+// forgotten after the retention period, after which feedback on them is
+// ignored.
+type Store struct {
+	backend   Backend
+	retention time.Duration
+	logger    *slog.Logger
+	done      chan struct{}
+}
+
+// NewStore creates an answer store that keeps answers for retention
+func NewStore(backend Backend, retention time.Duration, logger *slog.Logger) *Store {
+	store := &Store{
+		backend:   backend,
+		retention: retention,
+		logger:    logger,
+		done:      make(chan struct{}),
+	}
+
+	go store.cleanupRoutine()
+
+	return store
+}
+
+// Record stores an answer that was just posted
+func (s *Store) Record(answer Answer) {
+	if answer.PostedAt.IsZero() {
+		answer.PostedAt = time.Now()
+	}
+
+	if err := s.backend.Put(&answer); err != nil {
+		s.logger.Error("Failed to record answer", "error", err, "correlation_id", answer.CorrelationID)
+	}
+}
+
+// Lookup returns the answer posted as channelID/messageTS. It reports false
+// for messages Wavie didn't post and for answers past retention.
+func (s *Store) Lookup(channelID, messageTS string) (Answer, bool) {
+	answer, err := s.backend.Get(channelID, messageTS)
+	if err != nil {
+		s.logger.Error("Failed to look up answer", "error", err, "channel", channelID, "message_ts", messageTS)
+		return Answer{}, false
+	}
+	if answer == nil || time.Since(answer.PostedAt) > s.retention {
+		return Answer{}, false
+	}
+	return *answer, true
+}
+
+// Close stops the cleanup routine and closes the backend
+func (s *Store) Close() error {
+	close(s.done)
+	return s.backend.Close()
+}
+
+// cleanupRoutine periodically removes answers past retention
+func (s *Store) cleanupRoutine() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			removed, err := s.backend.DeleteExpired(time.Now().Add(-s.retention))
+			if err != nil {
+				s.logger.Error("Failed to clean up answers", "error", err)
+				continue
+			}
+			if removed > 0 {
+				s.logger.Info("Cleaned up expired answers", "removed", removed)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// key identifies a posted message; timestamps are only unique per channel
+func key(channelID, messageTS string) string {
+	return channelID + ":" + messageTS
+}
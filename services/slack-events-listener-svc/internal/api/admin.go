@@ -0,0 +1,83 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/outbox"
+)
+
+// AdminHandler serves operator endpoints, authenticated with a static bearer token
+type AdminHandler struct {
+	outbox     *outbox.Outbox
+	adminToken string
+	logger     *slog.Logger
+}
+
+func NewAdminHandler(outbox *outbox.Outbox, adminToken string, logger *slog.Logger) *AdminHandler {
+	return &AdminHandler{
+		outbox:     outbox,
+		adminToken: adminToken,
+		logger:     logger,
+	}
+}
+
+func (h *AdminHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /admin/outbox/dead-letters", h.requireToken(h.handleListDeadLetters))
+	mux.HandleFunc("POST /admin/outbox/dead-letters/{id}/replay", h.requireToken(h.handleReplayDeadLetter))
+}
+
+func (h *AdminHandler) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		expected := "Bearer " + h.adminToken
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (h *AdminHandler) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	items, err := h.outbox.DeadLetters()
+	if err != nil {
+		h.logger.Error("Failed to list dead letters", "error", err)
+		http.Error(w, "Failed to list dead letters", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]any{
+		"count": len(items),
+		"items": items,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *AdminHandler) handleReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if err := h.outbox.Replay(id); err != nil {
+		if errors.Is(err, outbox.ErrNotFound) {
+			http.Error(w, "Dead letter not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to replay dead letter", "error", err, "correlation_id", id)
+		http.Error(w, "Failed to replay dead letter", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]string{
+		"status":         "queued",
+		"correlation_id": id,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
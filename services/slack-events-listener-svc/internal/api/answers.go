@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/answers"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+// lookupAnswer finds the answer posted as channel/messageTS. Answers this
+// instance didn't record (a restart, another instance, or past the local
+// retention) are recovered from the metadata Wavie attaches to its answers;
+// only the question is lost then. It reports false for any other message.
+func (h *Handler) lookupAnswer(ctx context.Context, client *slack.Client, channel, messageTS string) (answers.Answer, bool) {
+	if answer, ok := h.answerStore.Lookup(channel, messageTS); ok {
+		return answer, true
+	}
+
+	message, err := client.GetMessage(ctx, channel, messageTS)
+	if err != nil {
+		h.logger.Error("Failed to fetch message to recover answer", "error", err, "channel", channel, "message_ts", messageTS)
+		return answers.Answer{}, false
+	}
+	if message == nil || message.Metadata == nil || message.Metadata.EventType != slack.AnswerEventType {
+		return answers.Answer{}, false
+	}
+
+	payload := message.Metadata.EventPayload
+	answer := answers.Answer{
+		ChannelID:     channel,
+		MessageTS:     messageTS,
+		ThreadTS:      message.ThreadTS,
+		CorrelationID: payload.CorrelationID,
+		Model:         payload.Model,
+		PromptVersion: payload.PromptVersion,
+		Response:      stripHint(message.Text),
+	}
+
+	h.logger.Info("Recovered answer from message metadata", "channel", channel, "message_ts", messageTS, "correlation_id", answer.CorrelationID)
+
+	// Cache it so further feedback on the same answer skips the lookup
+	h.answerStore.Record(answer)
+	return answer, true
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+// attachFiles downloads the files shared with a question. The question text
+// gains a note naming each file, so the conversation history (which only
+// keeps text) still shows what was shared, and Claude can explain why a file
+// it didn't get was left out.
+func (h *Handler) attachFiles(ctx context.Context, client *slack.Client, message string, files []slack.File) (string, []slack.Attachment) {
+	if len(files) == 0 {
+		return message, nil
+	}
+
+	attached, skipped := h.attachments.Download(ctx, client, files)
+
+	var notes []string
+	for _, file := range attached {
+		notes = append(notes, "[Attached file: "+file.Name+"]")
+	}
+	for _, note := range skipped {
+		notes = append(notes, "[Attached file not included: "+note+"]")
+	}
+
+	return strings.TrimSpace(message + "\n\n" + strings.Join(notes, "\n")), attached
+}
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BitwaveCorp/shared-svcs/shared/mrkdwn"
+	"github.com/BitwaveCorp/shared-svcs/shared/utils/idgen"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+const commandHelpText = "*Wavie commands*\n" +
+	"• `/wavie ask <question>` - ask Wavie privately; only you see the answer\n" +
+	"• `/wavie reset` - forget the context of your `/wavie ask` conversation in this channel\n" +
+	"• `/wavie reset <message link>` - forget the context of a Wavie thread in this channel\n" +
+	"• `/wavie summarize <message link>` - summarize a thread privately: its decisions, open questions and owners\n" +
+	"• `/wavie status` - check whether Wavie's services are up\n" +
+	"• `/wavie help` - show this message"
+
+// handleSlashCommand serves the /wavie slash command. Slack needs a reply
+// within three seconds, so slow work answers later through the response_url.
+func (h *Handler) handleSlashCommand(w http.ResponseWriter, r *http.Request) {
+	if err := h.verifySlackSignature(r); err != nil {
+		h.logger.Error("Failed to verify Slack signature", "error", err)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.logger.Error("Failed to parse slash command", "error", err)
+		http.Error(w, "Failed to parse slash command", http.StatusBadRequest)
+		return
+	}
+
+	response := h.RunSlashCommand(r.Context(), slack.ParseSlashCommand(r.PostForm))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RunSlashCommand executes a /wavie command and returns the immediate reply.
+// It is shared by the HTTP endpoint and Socket Mode.
+func (h *Handler) RunSlashCommand(ctx context.Context, cmd slack.SlashCommand) slack.CommandResponse {
+	subcommand, args, _ := strings.Cut(strings.TrimSpace(cmd.Text), " ")
+	subcommand = strings.ToLower(subcommand)
+	args = strings.TrimSpace(args)
+
+	h.logger.Info("Processing slash command",
+		"command", cmd.Command,
+		"subcommand", subcommand,
+		"user", cmd.UserID,
+		"channel", cmd.ChannelID)
+
+	switch subcommand {
+	case "ask":
+		client, ok := h.clientFor(ctx, cmd.EnterpriseID, cmd.TeamID)
+		if !ok {
+			return ephemeral(notInstalledMessage)
+		}
+		return h.handleAskCommand(client, cmd, args)
+	case "summarize", "summarise":
+		client, ok := h.clientFor(ctx, cmd.EnterpriseID, cmd.TeamID)
+		if !ok {
+			return ephemeral(notInstalledMessage)
+		}
+		return h.handleSummarizeCommand(client, cmd, args)
+	case "reset":
+		return h.handleResetCommand(cmd, args)
+	case "status":
+		return h.handleStatusCommand(ctx, cmd)
+	case "", "help":
+		return ephemeral(commandHelpText)
+	default:
+		return ephemeral(fmt.Sprintf("I don't know the command `%s`.\n\n%s", subcommand, commandHelpText))
+	}
+}
+
+// handleAskCommand acknowledges the question and answers it asynchronously
+func (h *Handler) handleAskCommand(client *slack.Client, cmd slack.SlashCommand, question string) slack.CommandResponse {
+	if question == "" {
+		return ephemeral("Usage: `/wavie ask <question>`")
+	}
+	if !h.policies.For(cmd.ChannelID).Allowed {
+		return ephemeral(notAllowedMessage)
+	}
+	if decision := h.limiter.Allow(questionScopes(cmd.UserID, cmd.ChannelID, cmd.TeamID)...); !decision.Allowed {
+		h.logger.Info("Slash command refused by rate limit", "scope", decision.Scope.Kind, "reason", decision.Reason, "user", cmd.UserID)
+		return ephemeral(limitMessage(decision))
+	}
+
+	err := h.pool.Submit(commandConversationKey(cmd),
+		func() { h.answerCommand(client, cmd, question) },
+		func(ctx context.Context) { h.abandonCommand(ctx, client, cmd) })
+	if err != nil {
+		h.logger.Warn("Failed to queue slash command", "error", err, "user", cmd.UserID)
+		return ephemeral("I'm swamped right now. Please try again in a moment.")
+	}
+
+	return ephemeral("_Thinking about your question…_")
+}
+
+// answerCommand runs a /wavie ask question through Claude and replies
+// ephemerally through the command's response_url
+func (h *Handler) answerCommand(client *slack.Client, cmd slack.SlashCommand, question string) {
+	ctx := context.Background()
+	question = h.cleanMessageText(ctx, client, question, "")
+
+	correlationID, err := idgen.GenerateId("wv", 16)
+	if err != nil {
+		h.logger.Error("Failed to generate correlation ID", "error", err)
+		return
+	}
+
+	channelPolicy := h.policies.For(cmd.ChannelID)
+	claudeReq := slack.ClaudeRequest{
+		Message:       question,
+		UserID:        cmd.UserID,
+		ChannelID:     cmd.ChannelID,
+		CorrelationID: correlationID,
+		Persona:       channelPolicy.Persona,
+		Model:         channelPolicy.Model,
+	}
+
+	replies := []slack.CommandResponse{ephemeral("Sorry, I'm having trouble processing your request right now.")}
+
+	claudeResp, err := h.askClaude(commandConversationKey(cmd), claudeReq, nil)
+	switch {
+	case err != nil:
+		h.logger.Error("Failed to call GPT service", "error", err, "correlation_id", correlationID)
+	case claudeResp.Error != "":
+		h.logger.Error("Claude service returned error", "error", claudeResp.Error, "correlation_id", correlationID)
+		replies[0].Text = "Sorry, I encountered an error processing your request."
+	default:
+		h.limiter.RecordTokens(claudeResp.InputTokens+claudeResp.OutputTokens, questionScopes(cmd.UserID, cmd.ChannelID, cmd.TeamID)...)
+		replies = ephemeralAnswers(mrkdwn.Render(claudeResp.Response), correlationID)
+	}
+
+	// The first reply replaces "Thinking about your question…"
+	replies[0].ReplaceOriginal = true
+	if err := respondAll(ctx, client, cmd.ResponseURL, replies); err != nil {
+		h.logger.Error("Failed to respond to slash command", "error", err, "correlation_id", correlationID)
+		return
+	}
+
+	if claudeResp == nil || claudeResp.Error != "" {
+		return
+	}
+
+	if slack.IsDirectMessage(cmd.ChannelID) && !h.broadcastDirectMessages {
+		h.logger.Info("Skipping broadcast of direct message", "correlation_id", correlationID)
+		return
+	}
+
+	h.callBroadcastService(slack.BroadcastRequest{
+		TeamID:        cmd.TeamID,
+		UserID:        cmd.UserID,
+		ChannelID:     cmd.ChannelID,
+		Question:      question,
+		Response:      claudeResp.Response,
+		Timestamp:     time.Now(),
+		CorrelationID: correlationID,
+	})
+}
+
+// abandonCommand replaces the "Thinking" reply of a question that was still
+// queued when the shutdown grace period ran out
+func (h *Handler) abandonCommand(ctx context.Context, client *slack.Client, cmd slack.SlashCommand) {
+	response := slack.CommandResponse{
+		ResponseType:    "ephemeral",
+		Text:            restartMessage,
+		ReplaceOriginal: true,
+	}
+	if err := client.RespondToURL(ctx, cmd.ResponseURL, response); err != nil {
+		h.logger.Error("Failed to post restart notice", "error", err, "user", cmd.UserID)
+	}
+}
+
+// handleResetCommand clears either the user's /wavie ask conversation in this
+// channel (in a DM, the whole DM conversation) or, given a link to a message
+// in this channel, the context of that thread
+func (h *Handler) handleResetCommand(cmd slack.SlashCommand, args string) slack.CommandResponse {
+	key := commandConversationKey(cmd)
+	cleared := "your `/wavie ask` conversation in this channel"
+	if slack.IsDirectMessage(cmd.ChannelID) {
+		cleared = "our conversation here"
+	}
+
+	if args != "" {
+		channel, threadTS, err := slack.ParsePermalink(args)
+		if err != nil {
+			return ephemeral("That doesn't look like a Slack message link. Use *Copy link* on a message in the thread and try again.")
+		}
+		// Anyone can run the command, so only threads in the channel it was
+		// run in can be reset, as with /wavie summarize
+		if channel != cmd.ChannelID {
+			return ephemeral("I can only reset threads in this channel. Run the command in the channel the thread is in.")
+		}
+		key = threadTS
+		cleared = "that thread"
+	}
+
+	if err := h.conversationStore.Delete(key); err != nil {
+		h.logger.Error("Failed to reset conversation", "error", err, "thread_id", key)
+		return ephemeral("Sorry, I couldn't reset the conversation right now.")
+	}
+
+	h.logger.Info("Reset conversation context", "user", cmd.UserID, "thread_id", key)
+	return ephemeral(fmt.Sprintf("Done! I've forgotten the context of %s.", cleared))
+}
+
+// handleStatusCommand reports the health of the services Wavie depends on
+func (h *Handler) handleStatusCommand(ctx context.Context, cmd slack.SlashCommand) slack.CommandResponse {
+	// Stay well inside Slack's three second deadline
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	messages := len(h.conversationStore.GetMessages(commandConversationKey(cmd)))
+
+	return ephemeral(fmt.Sprintf("*Wavie status*\n• Claude proxy: %s\n• Broadcast service: %s\n• Your `/wavie ask` conversation here: %d messages",
+		h.checkServiceHealth(ctx, h.claudeProxyServiceURL),
+		h.checkServiceHealth(ctx, h.broadcastServiceURL),
+		messages))
+}
+
+// checkServiceHealth calls a service's /health endpoint
+func (h *Handler) checkServiceHealth(ctx context.Context, serviceURL string) string {
+	req, err := http.NewRequestWithContext(ctx, "GET", serviceURL+"/health", nil)
+	if err != nil {
+		return ":x: misconfigured"
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		h.logger.Warn("Health check failed", "url", serviceURL, "error", err)
+		return ":x: unreachable"
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Sprintf(":warning: unhealthy (%d)", resp.StatusCode)
+	}
+	return ":white_check_mark: ok"
+}
+
+// commandConversationKey is the conversation key for a user's /wavie ask
+// questions in a channel; slash commands carry no thread. In a DM it is the
+// key answerMessage keeps the DM's conversation under, so asking, resetting
+// and status all see one conversation.
+func commandConversationKey(cmd slack.SlashCommand) string {
+	if slack.IsDirectMessage(cmd.ChannelID) {
+		return directMessageKey(cmd.ChannelID)
+	}
+	return "cmd:" + cmd.ChannelID + ":" + cmd.UserID
+}
+
+// ephemeralAnswers lays out an answer, already rendered as mrkdwn, as
+// ephemeral response_url replies. Answers too long for one message continue
+// in further replies; the feedback buttons go on the last.
+func ephemeralAnswers(text, correlationID string) []slack.CommandResponse {
+	parts := slack.SplitAnswer(text)
+
+	replies := make([]slack.CommandResponse, len(parts))
+	for i, part := range parts {
+		replies[i] = slack.CommandResponse{ResponseType: "ephemeral", Text: part, Blocks: slack.TextBlocks(part)}
+		if i == len(parts)-1 {
+			replies[i].Blocks = slack.AnswerBlocks(part, correlationID)
+		}
+	}
+	return replies
+}
+
+// respondAll posts replies to a response_url in order, stopping at the
+// first that fails
+func respondAll(ctx context.Context, client *slack.Client, responseURL string, replies []slack.CommandResponse) error {
+	for _, reply := range replies {
+		if err := client.RespondToURL(ctx, responseURL, reply); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ephemeral(text string) slack.CommandResponse {
+	return slack.CommandResponse{ResponseType: "ephemeral", Text: text}
+}
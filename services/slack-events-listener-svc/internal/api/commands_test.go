@@ -0,0 +1,63 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/conversation"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+func TestResetCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel string
+		args    string
+		cleared string // conversation key that should be forgotten
+		kept    string // conversation key that should survive
+	}{
+		{
+			name:    "channel",
+			channel: "C1",
+			cleared: "cmd:C1:U1",
+			kept:    "1700000000.123456",
+		},
+		{
+			name:    "direct message",
+			channel: "D1",
+			cleared: directMessageKey("D1"),
+			kept:    "cmd:C1:U1",
+		},
+		{
+			name:    "thread link",
+			channel: "C1",
+			args:    "https://acme.slack.com/archives/C1/p1700000000123456",
+			cleared: "1700000000.123456",
+			kept:    "cmd:C1:U1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			store := conversation.NewStore(conversation.NewMemoryBackend(), 10, time.Hour, logger)
+			defer store.Close()
+			h := &Handler{conversationStore: store, logger: logger}
+
+			for _, key := range []string{tt.cleared, tt.kept} {
+				store.AddMessage(key, "user", "question")
+			}
+
+			h.handleResetCommand(slack.SlashCommand{ChannelID: tt.channel, UserID: "U1"}, tt.args)
+
+			if got := store.GetMessages(tt.cleared); len(got) != 0 {
+				t.Errorf("%s still has %d messages after reset", tt.cleared, len(got))
+			}
+			if got := store.GetMessages(tt.kept); len(got) != 1 {
+				t.Errorf("%s has %d messages after reset, want it untouched", tt.kept, len(got))
+			}
+		})
+	}
+}
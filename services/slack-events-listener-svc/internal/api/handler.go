@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"crypto/hmac"
@@ -13,44 +14,119 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/BitwaveCorp/shared-svcs/shared/dedup"
+	"github.com/BitwaveCorp/shared-svcs/shared/mrkdwn"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/activity"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/answers"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/attachments"
 	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/conversation"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/installations"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/outbox"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/policy"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/ratelimit"
 	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/workerpool"
 	"github.com/google/uuid"
 	"github.com/BitwaveCorp/shared-svcs/shared/utils/idgen"
 )
 
 type Handler struct {
-	slackClient         *slack.Client
+	slackClients        *slack.Clients
+	installations       *installations.Store // nil without OAuth installs
+	attachments         *attachments.Downloader
+	policies            *policy.Policies
+	limiter             *ratelimit.Limiter
 	signingSecret       string
 	claudeProxyServiceURL  string
 	broadcastServiceURL string
+	broadcastDirectMessages bool
+	broadcastAllWorkspaces bool
+	streamResponses     bool
 	logger              *slog.Logger
-	processedEvents     map[string]bool
-	eventsMutex         sync.RWMutex
+	events              *dedup.Deduplicator
+	pool                *workerpool.Pool
 	conversationStore   *conversation.Store
+	answerStore         *answers.Store
+	activity            *activity.Store
+	outbox              *outbox.Outbox
+	quietThreads        *quietThreads
+	placeholders        *openPlaceholders
+	proxyClient         *http.Client // for the proxy; calls set their own deadlines
 }
 
-func NewHandler(slackClient *slack.Client, signingSecret, claudeProxyServiceURL, broadcastServiceURL string, logger *slog.Logger) *Handler {
-	// Create conversation store with 20 message limit and 1 hour max age
-	conversationStore := conversation.NewStore(20, 1*time.Hour)
+// HandlerOptions are what a Handler is built from
+type HandlerOptions struct {
+	SlackClients      *slack.Clients
+	Installations     *installations.Store // nil without OAuth installs
+	Attachments       *attachments.Downloader
+	Policies          *policy.Policies
+	Limiter           *ratelimit.Limiter
+	ConversationStore *conversation.Store
+	AnswerStore       *answers.Store
+	Activity          *activity.Store
+	Outbox            *outbox.Outbox
+	Events            *dedup.Deduplicator
+	Pool              *workerpool.Pool
+
+	SigningSecret         string
+	ClaudeProxyServiceURL string
+	BroadcastServiceURL   string
+
+	// Whether DM conversations and other workspaces' conversations are
+	// broadcast, and whether answers stream into Slack as they are generated
+	BroadcastDirectMessages bool
+	BroadcastAllWorkspaces  bool
+	StreamResponses         bool
+
+	Logger *slog.Logger
+}
 
+func NewHandler(opts HandlerOptions) *Handler {
 	return &Handler{
-		slackClient:         slackClient,
-		signingSecret:       signingSecret,
-		claudeProxyServiceURL:  claudeProxyServiceURL,
-		broadcastServiceURL: broadcastServiceURL,
-		logger:              logger,
-		processedEvents:     make(map[string]bool),
-		conversationStore:   conversationStore,
+		slackClients:        opts.SlackClients,
+		installations:       opts.Installations,
+		attachments:         opts.Attachments,
+		policies:            opts.Policies,
+		limiter:             opts.Limiter,
+		signingSecret:       opts.SigningSecret,
+		claudeProxyServiceURL:  opts.ClaudeProxyServiceURL,
+		broadcastServiceURL: opts.BroadcastServiceURL,
+		broadcastDirectMessages: opts.BroadcastDirectMessages,
+		broadcastAllWorkspaces: opts.BroadcastAllWorkspaces,
+		streamResponses:     opts.StreamResponses,
+		logger:              opts.Logger,
+		events:              opts.Events,
+		pool:                opts.Pool,
+		conversationStore:   opts.ConversationStore,
+		answerStore:         opts.AnswerStore,
+		activity:            opts.Activity,
+		outbox:              opts.Outbox,
+		quietThreads:        newQuietThreads(),
+		placeholders:        newOpenPlaceholders(),
+		proxyClient:         &http.Client{},
 	}
 }
 
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	h.RegisterServiceRoutes(mux)
+	h.RegisterSlackRoutes(mux)
+}
+
+// RegisterServiceRoutes registers the routes that are served regardless of
+// how Slack delivers events
+func (h *Handler) RegisterServiceRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /health", h.handleHealthCheck)
+	mux.HandleFunc("GET /metrics", h.handleMetrics)
+}
+
+// RegisterSlackRoutes registers the public endpoints Slack calls over HTTP.
+// They are not needed in Socket Mode.
+func (h *Handler) RegisterSlackRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /slack/events", h.ProcessEvent)
+	mux.HandleFunc("POST /slack/commands", h.handleSlashCommand)
+	mux.HandleFunc("POST /slack/interactions", h.handleInteraction)
 }
 
 func (h *Handler) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
@@ -60,6 +136,13 @@ func (h *Handler) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleMetrics reports the worker pool's queue depth and throughput
+func (h *Handler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.pool.Stats())
+}
+
 func (h *Handler) verifySlackSignature(r *http.Request) error {
 	timestamp := r.Header.Get("X-Slack-Request-Timestamp")
 	signature := r.Header.Get("X-Slack-Signature")
@@ -82,6 +165,9 @@ func (h *Handler) verifySlackSignature(r *http.Request) error {
 		return fmt.Errorf("failed to read request body: %w", err)
 	}
 
+	// Put the body back so handlers can read it after verification
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
 	baseString := fmt.Sprintf("v0:%s:%s", timestamp, string(body))
 	mac := hmac.New(sha256.New, []byte(h.signingSecret))
 	mac.Write([]byte(baseString))
@@ -94,18 +180,6 @@ func (h *Handler) verifySlackSignature(r *http.Request) error {
 	return nil
 }
 
-func (h *Handler) isEventProcessed(eventID string) bool {
-	h.eventsMutex.RLock()
-	defer h.eventsMutex.RUnlock()
-	return h.processedEvents[eventID]
-}
-
-func (h *Handler) markEventProcessed(eventID string) {
-	h.eventsMutex.Lock()
-	defer h.eventsMutex.Unlock()
-	h.processedEvents[eventID] = true
-}
-
 func (h *Handler) ProcessEvent(w http.ResponseWriter, r *http.Request) {
 	// Verify Slack signature
 	if err := h.verifySlackSignature(r); err != nil {
@@ -137,35 +211,193 @@ func (h *Handler) ProcessEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Deduplicate events
-	if h.isEventProcessed(eventReq.EventID) {
-		h.logger.Info("Duplicate event received, ignoring", "event_id", eventReq.EventID)
-		w.WriteHeader(http.StatusOK)
+	if err := h.DispatchEvent(eventReq, dedup.SlackRetry(r)); err != nil {
+		// Slack retries non-2xx responses, by which time the queue may have
+		// room or another instance may take the event
+		http.Error(w, "Too busy to process event", http.StatusServiceUnavailable)
 		return
 	}
 
-	// Process event asynchronously
-	go func() {
-		switch eventReq.Event.Type {
-		case "app_mention":
-			h.handleAppMention(eventReq)
-		case "reaction_added":
-			h.handleReactionAdded(eventReq)
-		case "message":
-			// Only process messages in threads that might contain feedback
-			if eventReq.Event.ThreadTS != "" && strings.HasPrefix(eventReq.Event.Text, "***") {
-				h.handleTextFeedback(eventReq)
-			}
-		}
-		h.markEventProcessed(eventReq.EventID)
-	}()
-
 	// Respond immediately to Slack
 	w.WriteHeader(http.StatusOK)
 }
 
+// DispatchEvent deduplicates an Events API callback and queues it for
+// processing. It is shared by the HTTP endpoint and Socket Mode; an error
+// means the event was not accepted and Slack should deliver it again.
+func (h *Handler) DispatchEvent(eventReq slack.EventRequest, retry dedup.Retry) error {
+	// Claim the event before processing, so a Slack retry that arrives while
+	// the first delivery is still being answered is dropped
+	if !h.events.Claim(eventReq.EventID) {
+		h.logger.Info("Duplicate event received, ignoring",
+			"event_id", eventReq.EventID,
+			"retry_num", retry.Num,
+			"retry_reason", retry.Reason)
+		return nil
+	}
+
+	if retry.Num > 0 {
+		h.logger.Info("Processing retried event not seen before",
+			"event_id", eventReq.EventID,
+			"retry_num", retry.Num,
+			"retry_reason", retry.Reason)
+	}
+
+	key := eventOrderingKey(eventReq.Event)
+	if eventReq.Event.Type == "app_mention" && isSummarizeRequest(eventReq) {
+		key = summaryOrderingKey(eventReq.Event.ThreadTS)
+	}
+
+	err := h.pool.Submit(key,
+		func() { h.processEvent(eventReq) },
+		func(ctx context.Context) { h.abandonEvent(ctx, eventReq) })
+	if err != nil {
+		// Let the redelivery through
+		h.events.Release(eventReq.EventID)
+		h.logger.Warn("Failed to queue event", "error", err, "event_id", eventReq.EventID)
+		return fmt.Errorf("failed to queue event: %w", err)
+	}
+
+	return nil
+}
+
+// processEvent routes an event to its handler. It runs on a pool worker.
+func (h *Handler) processEvent(eventReq slack.EventRequest) {
+	switch eventReq.Event.Type {
+	case "app_uninstalled", "tokens_revoked":
+		h.handleUninstall(eventReq)
+		return
+	}
+
+	client, ok := h.eventClient(context.Background(), eventReq)
+	if !ok {
+		return
+	}
+
+	// The Home tab isn't a channel, so channel policies don't apply
+	if eventReq.Event.Type == "app_home_opened" {
+		h.publishHome(client, eventReq)
+		return
+	}
+
+	channel := eventReq.Event.Channel
+	if eventReq.Event.Type == "reaction_added" {
+		channel = eventReq.Event.Item.Channel
+	}
+	if !h.policies.For(channel).Allowed {
+		h.logger.Info("Ignoring event in a channel Wavie is not allowed in", "type", eventReq.Event.Type, "channel", channel)
+		if eventReq.Event.Type == "app_mention" || isDirectQuestion(eventReq.Event) {
+			h.declineMention(client, eventReq)
+		}
+		return
+	}
+
+	switch eventReq.Event.Type {
+	case "app_mention":
+		if isSummarizeRequest(eventReq) {
+			h.summarizeMention(client, eventReq)
+			return
+		}
+		h.answerMessage(client, eventReq)
+	case "reaction_added":
+		h.handleReactionAdded(client, eventReq)
+	case "message":
+		switch {
+		case isIgnoredMessage(eventReq.Event):
+			// Bot messages (including our own answers), edits, joins, etc.
+		case eventReq.Event.ChannelType == "im" && strings.HasPrefix(eventReq.Event.Text, "***"):
+			h.handleTextFeedback(client, eventReq)
+		case eventReq.Event.ChannelType == "im":
+			// Every direct message is a turn, no mention needed
+			h.answerMessage(client, eventReq)
+		case eventReq.Event.ThreadTS == "":
+			// Top-level channel chatter is only answered when Wavie is mentioned
+		case strings.HasPrefix(eventReq.Event.Text, "***"):
+			h.handleTextFeedback(client, eventReq)
+		default:
+			h.handleThreadReply(client, eventReq)
+		}
+	}
+}
+
+// restartMessage is posted in place of answers lost to a shutdown
+const restartMessage = "Sorry, I was restarting and couldn't get to your message. Please ask again."
+
+// notAllowedMessage answers questions in channels and DMs the channel policy
+// excludes
+const notAllowedMessage = "Sorry, I'm not enabled here."
+
+// notInstalledMessage answers commands from workspaces Wavie has no token for
+const notInstalledMessage = "Sorry, I'm not installed in this workspace. Ask an admin to install Wavie again."
+
+// abandonEvent is called for events still queued when the shutdown grace
+// period runs out. Slack has already been acknowledged and won't redeliver,
+// so a user waiting on an answer is asked to try again.
+func (h *Handler) abandonEvent(ctx context.Context, eventReq slack.EventRequest) {
+	h.events.Release(eventReq.EventID)
+	h.logger.Warn("Abandoning queued event during shutdown",
+		"event_id", eventReq.EventID,
+		"event_type", eventReq.Event.Type,
+		"channel", eventReq.Event.Channel)
+
+	event := eventReq.Event
+	if event.Type != "app_mention" && !isDirectQuestion(event) {
+		return
+	}
+
+	threadTS := event.ThreadTS
+	if threadTS == "" && event.ChannelType != "im" {
+		threadTS = event.TS
+	}
+
+	client, ok := h.eventClient(ctx, eventReq)
+	if !ok {
+		return
+	}
+	if _, err := client.PostMessage(ctx, event.Channel, restartMessage, threadTS); err != nil {
+		h.logger.Error("Failed to post restart notice", "error", err, "event_id", eventReq.EventID)
+	}
+}
+
+// isDirectQuestion reports whether an event is a direct message to Wavie
+// that expects an answer, rather than feedback or a message to ignore
+func isDirectQuestion(event slack.Event) bool {
+	return event.Type == "message" && event.ChannelType == "im" && !isIgnoredMessage(event) && !strings.HasPrefix(event.Text, "***")
+}
+
+// eventOrderingKey picks the worker for an event. Events for the same
+// conversation share a worker and run in order, so they don't race on its
+// history.
+func eventOrderingKey(event slack.Event) string {
+	switch {
+	case event.Type == "reaction_added":
+		return event.Item.Channel + ":" + event.Item.TS
+	case event.Type == "app_home_opened":
+		return "home:" + event.User
+	case event.ThreadTS != "":
+		return event.ThreadTS
+	case event.ChannelType == "im":
+		return directMessageKey(event.Channel)
+	default:
+		return event.TS
+	}
+}
+
+// declineMention tells the user, privately, that Wavie doesn't answer in
+// this channel or direct message
+func (h *Handler) declineMention(client *slack.Client, eventReq slack.EventRequest) {
+	_, err := client.PostEphemeral(context.Background(), eventReq.Event.User, slack.MessageResponse{
+		Channel:  eventReq.Event.Channel,
+		Text:     notAllowedMessage,
+		ThreadTS: eventReq.Event.ThreadTS,
+	})
+	if err != nil {
+		h.logger.Error("Failed to decline mention", "error", err, "channel", eventReq.Event.Channel)
+	}
+}
+
 // handleReactionAdded processes reaction events for feedback
-func (h *Handler) handleReactionAdded(eventReq slack.EventRequest) {
+func (h *Handler) handleReactionAdded(client *slack.Client, eventReq slack.EventRequest) {
 	// Only process thumbs up/down reactions
 	if eventReq.Event.Reaction != "+1" && eventReq.Event.Reaction != "-1" {
 		return
@@ -175,6 +407,21 @@ func (h *Handler) handleReactionAdded(eventReq slack.EventRequest) {
 	messageTS := eventReq.Event.Item.TS
 	channel := eventReq.Event.Item.Channel
 
+	// Reactions on other people's messages can't be feedback, so don't look
+	// them up
+	if botUser := botUserID(eventReq); botUser != "" && eventReq.Event.ItemUser != "" && eventReq.Event.ItemUser != botUser {
+		return
+	}
+
+	// Only Wavie's own answers can be rated
+	answer, ok := h.lookupAnswer(context.Background(), client, channel, messageTS)
+	if !ok {
+		h.logger.Info("Ignoring reaction on a message that is not a known answer",
+			"channel", channel,
+			"message_ts", messageTS)
+		return
+	}
+
 	// Create a correlation ID for this feedback
 	correlationID := "fb_" + uuid.New().String()
 
@@ -186,12 +433,19 @@ func (h *Handler) handleReactionAdded(eventReq slack.EventRequest) {
 
 	// Create feedback request
 	feedbackReq := slack.FeedbackRequest{
-		UserID:        eventReq.Event.User,
-		ChannelID:     channel,
-		MessageTS:     messageTS,
-		FeedbackType:  feedbackType,
-		Timestamp:     time.Now(),
-		CorrelationID: correlationID,
+		TeamID:              eventReq.TeamID,
+		UserID:              eventReq.Event.User,
+		ChannelID:           channel,
+		MessageTS:           messageTS,
+		ThreadTS:            answer.ThreadTS,
+		Question:            answer.Question,
+		Response:            answer.Response,
+		FeedbackType:        feedbackType,
+		Timestamp:           time.Now(),
+		CorrelationID:       correlationID,
+		AnswerCorrelationID: answer.CorrelationID,
+		Model:               answer.Model,
+		PromptVersion:       answer.PromptVersion,
 	}
 
 	// Send feedback to broadcast service
@@ -201,11 +455,13 @@ func (h *Handler) handleReactionAdded(eventReq slack.EventRequest) {
 		"feedback_type", feedbackType,
 		"user", eventReq.Event.User,
 		"channel", channel,
-		"correlation_id", correlationID)
+		"correlation_id", correlationID,
+		"answer_correlation_id", answer.CorrelationID)
 }
 
-// handleTextFeedback processes text feedback from thread replies
-func (h *Handler) handleTextFeedback(eventReq slack.EventRequest) {
+// handleTextFeedback processes text feedback from thread replies and direct
+// messages. The feedback is about Wavie's last answer before it.
+func (h *Handler) handleTextFeedback(client *slack.Client, eventReq slack.EventRequest) {
 	// Extract feedback text (remove the *** prefix)
 	feedbackText := strings.TrimPrefix(eventReq.Event.Text, "***")
 	feedbackText = strings.TrimSpace(feedbackText)
@@ -220,6 +476,7 @@ func (h *Handler) handleTextFeedback(eventReq slack.EventRequest) {
 
 	// Create feedback request
 	feedbackReq := slack.FeedbackRequest{
+		TeamID:        eventReq.TeamID,
 		UserID:        eventReq.Event.User,
 		ChannelID:     eventReq.Event.Channel,
 		MessageTS:     eventReq.Event.TS,
@@ -230,41 +487,116 @@ func (h *Handler) handleTextFeedback(eventReq slack.EventRequest) {
 		CorrelationID: correlationID,
 	}
 
+	// Rate the answer, like the buttons and reactions do
+	if answerTS := h.previousAnswerTS(client, eventReq); answerTS != "" {
+		feedbackReq.MessageTS = answerTS
+		h.attachAnswer(context.Background(), client, &feedbackReq)
+	}
+
 	// Send feedback to broadcast service
 	h.sendFeedbackToBroadcast(feedbackReq)
 
 	h.logger.Info("Processed text feedback",
 		"user", eventReq.Event.User,
 		"channel", eventReq.Event.Channel,
-		"correlation_id", correlationID)
+		"correlation_id", correlationID,
+		"answer_correlation_id", feedbackReq.AnswerCorrelationID)
 }
 
-// sendFeedbackToBroadcast sends feedback to the broadcast service
-func (h *Handler) sendFeedbackToBroadcast(feedback slack.FeedbackRequest) {
-	// Marshal feedback to JSON
-	feedbackJSON, err := json.Marshal(feedback)
+// previousAnswerTS finds the last message Wavie posted in the thread (or, for
+// top-level direct messages, the DM) before eventReq's message. It returns ""
+// if there is none.
+func (h *Handler) previousAnswerTS(client *slack.Client, eventReq slack.EventRequest) string {
+	ctx := context.Background()
+	event := eventReq.Event
+
+	var messages []slack.Message
+	var err error
+	if event.ThreadTS != "" {
+		messages, err = client.GetThreadReplies(ctx, event.Channel, event.ThreadTS)
+	} else {
+		messages, err = client.GetRecentMessages(ctx, event.Channel, event.TS, 20)
+	}
 	if err != nil {
-		h.logger.Error("Failed to marshal feedback", "error", err)
+		h.logger.Error("Failed to find the answer text feedback is about", "error", err, "channel", event.Channel)
+		return ""
+	}
+
+	botUser := botUserID(eventReq)
+	answerTS := ""
+	for _, message := range messages {
+		if isWavieMessage(message, botUser) && message.TS < event.TS && message.TS > answerTS {
+			answerTS = message.TS
+		}
+	}
+	return answerTS
+}
+
+// sendFeedbackToBroadcast records feedback in the outbox for delivery to the
+// broadcast service
+func (h *Handler) sendFeedbackToBroadcast(feedback slack.FeedbackRequest) {
+	h.activity.RecordFeedback(feedback.UserID, activity.Feedback{
+		ChannelID:    feedback.ChannelID,
+		MessageTS:    feedback.MessageTS,
+		ThreadTS:     feedback.ThreadTS,
+		FeedbackType: feedback.FeedbackType,
+		FeedbackText: feedback.FeedbackText,
+		Question:     feedback.Question,
+	})
+
+	if !h.broadcastsFrom(feedback.TeamID) {
+		h.logger.Info("Skipping feedback broadcast from another workspace", "team_id", feedback.TeamID, "correlation_id", feedback.CorrelationID)
 		return
 	}
+	channelPolicy := h.policies.For(feedback.ChannelID)
+	if !channelPolicy.Allowed || !channelPolicy.Broadcast {
+		h.logger.Info("Skipping feedback broadcast per channel policy", "channel", feedback.ChannelID, "correlation_id", feedback.CorrelationID)
+		return
+	}
+	feedback.BroadcastChannelID = channelPolicy.BroadcastChannel
 
-	// Send to broadcast service
-	resp, err := http.Post(h.broadcastServiceURL+"/api/feedback", "application/json", bytes.NewReader(feedbackJSON))
-	if err != nil {
-		h.logger.Error("Failed to send feedback to broadcast service", "error", err)
+	if err := h.outbox.Enqueue("/api/feedback", feedback.CorrelationID, feedback); err != nil {
+		h.logger.Error("Failed to queue feedback for broadcast service", "error", err, "correlation_id", feedback.CorrelationID)
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		h.logger.Error("Broadcast service returned non-OK status", "status", resp.Status)
+	h.logger.Info("Queued feedback for broadcast service", "correlation_id", feedback.CorrelationID)
+}
+
+// handleThreadReply answers a follow-up in a thread without an @mention, as
+// long as Wavie started or joined that thread
+func (h *Handler) handleThreadReply(client *slack.Client, eventReq slack.EventRequest) {
+	botUser := botUserID(eventReq)
+	if botUser != "" && eventReq.Event.User == botUser {
+		return
+	}
+
+	// Messages that mention Wavie also arrive as app_mention events
+	if botUser != "" && slack.Mentions(eventReq.Event.Text, botUser) {
 		return
 	}
 
-	h.logger.Info("Successfully sent feedback to broadcast service", "correlation_id", feedback.CorrelationID)
+	threadID := eventReq.Event.ThreadTS
+	if len(h.conversationStore.GetMessages(threadID)) == 0 {
+		quietKey := quietThreadKey(eventReq.Event.Channel, threadID)
+		if h.quietThreads.has(quietKey) {
+			return
+		}
+		participated, err := h.rebuildThreadHistory(context.Background(), client, eventReq, threadID)
+		if err != nil {
+			return
+		}
+		if !participated {
+			h.quietThreads.add(quietKey)
+			return
+		}
+	}
+
+	h.answerMessage(client, eventReq)
 }
 
-func (h *Handler) handleAppMention(eventReq slack.EventRequest) {
+// answerMessage runs a user message through Claude and replies in its thread
+func (h *Handler) answerMessage(client *slack.Client, eventReq slack.EventRequest) {
 	correlationID, err := idgen.GenerateId("wv", 16)
 	if err != nil {
 		h.logger.Error("Failed to generate correlation ID", "error", err)
@@ -277,25 +609,50 @@ func (h *Handler) handleAppMention(eventReq slack.EventRequest) {
 	if threadID == "" {
 		threadID = eventReq.Event.TS // Use message timestamp as thread ID for new messages
 	}
+	h.quietThreads.forget(quietThreadKey(eventReq.Event.Channel, threadID))
+
+	// Top-level DMs are one running conversation and are answered in place
+	isDirectMessage := eventReq.Event.ChannelType == "im"
+	conversationKey := threadID
+	replyThreadTS := threadID
+	if isDirectMessage && !isThreadReply {
+		conversationKey = directMessageKey(eventReq.Event.Channel)
+		replyThreadTS = ""
+	}
 
 	h.logger.Info("Processing wavie message", 
 		"correlation_id", correlationID, 
 		"user", eventReq.Event.User, 
 		"channel", eventReq.Event.Channel,
 		"is_thread", isThreadReply,
+		"is_dm", isDirectMessage,
 		"thread_id", threadID)
 
-	// Clean the message text
-	message := strings.ReplaceAll(eventReq.Event.Text, "<@", "")
-	message = strings.ReplaceAll(message, ">", "")
-	message = strings.ReplaceAll(message, "@wavie", "")
-	message = strings.TrimSpace(message)
+	scopes := questionScopes(eventReq.Event.User, eventReq.Event.Channel, eventReq.TeamID)
+	if decision := h.limiter.Allow(scopes...); !decision.Allowed {
+		h.declineLimited(client, eventReq, decision)
+		return
+	}
 
-	// Add user message to conversation context
-	h.conversationStore.AddMessage(threadID, "user", message)
+	// Let the user know we're on it while Claude works, unless the channel
+	// wants replies only the asker can see
+	channelPolicy := h.policies.For(eventReq.Event.Channel)
+	var reply *placeholder
+	if channelPolicy.Ephemeral {
+		reply = h.ephemeralReply(client, eventReq.Event.Channel, replyThreadTS, eventReq.Event.User, correlationID)
+	} else {
+		reply = h.postPlaceholder(client, eventReq.Event.Channel, replyThreadTS, correlationID)
+	}
 
-	// Get conversation history for this thread
-	conversationHistory := h.conversationStore.GetMessages(threadID)
+	// Clean the message text
+	message := h.cleanMessageText(context.Background(), client, eventReq.Event.Text, botUserID(eventReq))
+	message, files := h.attachFiles(context.Background(), client, message, eventReq.Event.Files)
+
+	// The store may have lost this thread (restart, expiry or another
+	// instance), so rebuild it from Slack before answering
+	if isThreadReply && len(h.conversationStore.GetMessages(threadID)) == 0 {
+		h.rebuildThreadHistory(context.Background(), client, eventReq, threadID)
+	}
 
 	claudeReq := slack.ClaudeRequest{
 		Message:            message,
@@ -303,49 +660,139 @@ func (h *Handler) handleAppMention(eventReq slack.EventRequest) {
 		ChannelID:          eventReq.Event.Channel,
 		MessageTS:          eventReq.Event.TS,
 		ThreadTS:           threadID,
-		ConversationHistory: conversationHistory,
 		CorrelationID:      correlationID,
+		Attachments:        files,
+		Persona:            channelPolicy.Persona,
+		Model:              channelPolicy.Model,
 	}
 
-	claudeResp, err := h.callClaudeService(claudeReq)
+	claudeResp, err := h.askClaude(conversationKey, claudeReq, func(partial string) {
+		// Only the first message is edited while streaming; the rest are
+		// posted once the answer is complete
+		reply.progress(slack.SplitAnswer(mrkdwn.Render(partial))[0])
+	})
 	if err != nil {
 		h.logger.Error("Failed to call GPT service", "error", err, "correlation_id", correlationID)
-		h.slackClient.PostMessage(context.Background(), eventReq.Event.Channel, "Sorry, I'm having trouble processing your request right now.", threadID)
+		reply.fail(context.Background(), "Sorry, I'm having trouble processing your request right now.")
 		return
 	}
 
 	if claudeResp.Error != "" {
 		h.logger.Error("Claude service returned error", "error", claudeResp.Error, "correlation_id", correlationID)
-		h.slackClient.PostMessage(context.Background(), eventReq.Event.Channel, "Sorry, I encountered an error processing your request.", threadID)
+		reply.fail(context.Background(), "Sorry, I encountered an error processing your request.")
 		return
 	}
 
-	// Add bot response to conversation context
-	h.conversationStore.AddMessage(threadID, "assistant", claudeResp.Response)
+	h.limiter.RecordTokens(claudeResp.InputTokens+claudeResp.OutputTokens, scopes...)
+
+	answer := claudeResp.Response
+	text := mrkdwn.Render(answer)
 
 	// For new conversations (not in a thread), append a hint to continue conversation in thread for new messages
-	if eventReq.Event.ThreadTS == "" {
-		claudeResp.Response += "\n\n_Reply in this thread to continue our conversation. React with 👍 or 👎 to provide feedback, or start your message with *** to leave detailed feedback._"
+	if eventReq.Event.ThreadTS == "" && !isDirectMessage && !channelPolicy.Ephemeral {
+		text += feedbackHint
 	}
 
-	// Always reply in the thread if there is one
-	err = h.slackClient.PostMessage(context.Background(), eventReq.Event.Channel, claudeResp.Response, threadID)
+	// Turn the placeholder into the answer, continued in further messages if
+	// it is too long for one
+	metadata := slack.AnswerMetadata{
+		CorrelationID: correlationID,
+		Model:         claudeResp.Model,
+		PromptVersion: claudeResp.PromptVersion,
+	}
+	messages := slack.AnswerMessages(eventReq.Event.Channel, text, replyThreadTS, metadata)
+	answerTSs, err := reply.finishAll(context.Background(), messages)
 	if err != nil {
 		h.logger.Error("Failed to post response to Slack", "error", err, "correlation_id", correlationID)
+	}
+
+	// Remember what was posted so feedback on any part can be linked back
+	for _, answerTS := range answerTSs {
+		h.answerStore.Record(answers.Answer{
+			ChannelID:     eventReq.Event.Channel,
+			MessageTS:     answerTS,
+			ThreadTS:      replyThreadTS,
+			CorrelationID: correlationID,
+			Model:         claudeResp.Model,
+			PromptVersion: claudeResp.PromptVersion,
+			Question:      message,
+			Response:      answer,
+		})
+	}
+	if len(answerTSs) == 0 {
+		return
+	}
+
+	// Ephemeral answers can't be linked to, so they aren't listed on the Home tab
+	if !channelPolicy.Ephemeral {
+		h.activity.RecordQuestion(eventReq.Event.User, activity.Question{
+			ChannelID: eventReq.Event.Channel,
+			MessageTS: answerTSs[0],
+			ThreadTS:  replyThreadTS,
+			Question:  message,
+		})
+	}
+
+	if isDirectMessage && !h.broadcastDirectMessages {
+		h.logger.Info("Skipping broadcast of direct message", "correlation_id", correlationID)
 		return
 	}
 
 	broadcastReq := slack.BroadcastRequest{
+		TeamID:        eventReq.TeamID,
 		UserID:        eventReq.Event.User,
 		ChannelID:     eventReq.Event.Channel,
 		ThreadID:      threadID,
 		Question:      message,
-		Response:      claudeResp.Response,
+		Response:      answer,
 		Timestamp:     time.Now(),
 		CorrelationID: correlationID,
 	}
 
-	go h.callBroadcastService(broadcastReq)
+	h.callBroadcastService(broadcastReq)
+}
+
+// askClaude records the question in the conversation, calls the proxy with the
+// conversation history and records the answer if there is one. When streaming
+// is enabled and onProgress is not nil, it is called with the answer so far
+// as it is generated.
+func (h *Handler) askClaude(conversationKey string, req slack.ClaudeRequest, onProgress func(answer string)) (*slack.ClaudeResponse, error) {
+	// Add user message to conversation context
+	h.conversationStore.AddMessage(conversationKey, "user", req.Message)
+
+	// Get conversation history for this thread
+	req.ConversationHistory = toConversationMessages(h.conversationStore.GetMessages(conversationKey))
+
+	var claudeResp *slack.ClaudeResponse
+	var err error
+	if h.streamResponses && onProgress != nil {
+		claudeResp, err = h.callClaudeServiceStream(req, onProgress)
+	} else {
+		claudeResp, err = h.callClaudeService(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Add bot response to conversation context
+	if claudeResp.Error == "" {
+		h.conversationStore.AddMessage(conversationKey, "assistant", claudeResp.Response)
+	}
+
+	return claudeResp, nil
+}
+
+// toConversationMessages converts stored history into the proxy request format
+func toConversationMessages(messages []conversation.Message) []slack.ConversationMessage {
+	history := make([]slack.ConversationMessage, 0, len(messages))
+	for _, msg := range messages {
+		history = append(history, slack.ConversationMessage{
+			Role:      msg.Role,
+			Content:   msg.Content,
+			Timestamp: msg.Timestamp,
+		})
+	}
+	return history
 }
 
 func (h *Handler) callClaudeService(req slack.ClaudeRequest) (*slack.ClaudeResponse, error) {
@@ -354,15 +801,17 @@ func (h *Handler) callClaudeService(req slack.ClaudeRequest) (*slack.ClaudeRespo
 		return nil, fmt.Errorf("failed to marshal GPT request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", h.claudeProxyServiceURL+"/api/chat", bytes.NewBuffer(jsonData))
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", h.claudeProxyServiceURL+"/api/chat", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GPT request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(httpReq)
+	resp, err := h.proxyClient.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call GPT service: %w", err)
 	}
@@ -381,34 +830,108 @@ func (h *Handler) callClaudeService(req slack.ClaudeRequest) (*slack.ClaudeRespo
 	return &claudeResp, nil
 }
 
-func (h *Handler) callBroadcastService(req slack.BroadcastRequest) {
+// callClaudeServiceStream calls the proxy's streaming endpoint, passing the
+// answer accumulated so far to onProgress after every piece
+func (h *Handler) callClaudeServiceStream(req slack.ClaudeRequest, onProgress func(answer string)) (*slack.ClaudeResponse, error) {
 	jsonData, err := json.Marshal(req)
 	if err != nil {
-		h.logger.Error("Failed to marshal broadcast request", "error", err, "correlation_id", req.CorrelationID)
-		return
+		return nil, fmt.Errorf("failed to marshal GPT request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", h.broadcastServiceURL+"/api/broadcast", bytes.NewBuffer(jsonData))
+	// Progress is visible, so a long answer may take longer than a blocking one
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", h.claudeProxyServiceURL+"/api/chat/stream", bytes.NewBuffer(jsonData))
 	if err != nil {
-		h.logger.Error("Failed to create broadcast request", "error", err, "correlation_id", req.CorrelationID)
-		return
+		return nil, fmt.Errorf("failed to create GPT request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(httpReq)
+	resp, err := h.proxyClient.Do(httpReq)
 	if err != nil {
-		h.logger.Error("Failed to call broadcast service", "error", err, "correlation_id", req.CorrelationID)
-		return
+		return nil, fmt.Errorf("failed to call GPT service: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		// A proxy without streaming support
+		h.logger.Warn("Claude proxy does not support streaming, falling back", "correlation_id", req.CorrelationID)
+		return h.callClaudeService(req)
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		h.logger.Error("Broadcast service error", "status", resp.StatusCode, "body", string(body), "correlation_id", req.CorrelationID)
+		return nil, fmt.Errorf("GPT service error: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var answer strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event slack.ClaudeStreamEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("failed to decode GPT stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "delta":
+			answer.WriteString(event.Text)
+			onProgress(answer.String())
+		case "done":
+			return &slack.ClaudeResponse{
+				Response:      event.Response,
+				CorrelationID: event.CorrelationID,
+				Model:         event.Model,
+				PromptVersion: event.PromptVersion,
+				InputTokens:   event.InputTokens,
+				OutputTokens:  event.OutputTokens,
+			}, nil
+		case "error":
+			return &slack.ClaudeResponse{
+				CorrelationID: event.CorrelationID,
+				Error:         event.Error,
+			}, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read GPT stream: %w", err)
+	}
+	return nil, fmt.Errorf("GPT stream ended without a result")
+}
+
+// callBroadcastService records an interaction in the outbox for delivery to
+// the broadcast service. Recording is a local write and the outbox delivers
+// it, so callers wait for it: a goroutine could still be writing when
+// shutdown closes the outbox.
+func (h *Handler) callBroadcastService(req slack.BroadcastRequest) {
+	if !h.broadcastsFrom(req.TeamID) {
+		h.logger.Info("Skipping broadcast from another workspace", "team_id", req.TeamID, "correlation_id", req.CorrelationID)
+		return
+	}
+	channelPolicy := h.policies.For(req.ChannelID)
+	if !channelPolicy.Broadcast {
+		h.logger.Info("Skipping broadcast per channel policy", "channel", req.ChannelID, "correlation_id", req.CorrelationID)
+		return
+	}
+	req.BroadcastChannelID = channelPolicy.BroadcastChannel
+
+	if err := h.outbox.Enqueue("/api/broadcast", req.CorrelationID, req); err != nil {
+		h.logger.Error("Failed to queue broadcast request", "error", err, "correlation_id", req.CorrelationID)
 		return
 	}
 
-	h.logger.Info("Successfully sent to broadcast service", "correlation_id", req.CorrelationID)
+	h.logger.Info("Queued broadcast request", "correlation_id", req.CorrelationID)
+}
+
+// broadcastsFrom reports whether questions and feedback from a workspace may
+// be sent to the broadcast channel. It is in the home workspace, so other
+// workspaces' conversations only go there when BROADCAST_ALL_WORKSPACES is set.
+func (h *Handler) broadcastsFrom(teamID string) bool {
+	if h.broadcastAllWorkspaces {
+		return true
+	}
+	return teamID != "" && teamID == h.slackClients.HomeTeamID()
 }
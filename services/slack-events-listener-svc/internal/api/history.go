@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/conversation"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+// feedbackHint is appended to the first answer in a thread. Slack may rewrite
+// the emoji when it stores the message, so stripHint matches on the opening
+// sentence only.
+const (
+	feedbackHintPrefix = "_Reply in this thread to continue our conversation."
+	feedbackHint       = "\n\n" + feedbackHintPrefix + " React with 👍 or 👎 to provide feedback, or start your message with *** to leave detailed feedback._"
+)
+
+// stripHint removes the feedback hint from a posted answer
+func stripHint(text string) string {
+	if i := strings.Index(text, feedbackHintPrefix); i >= 0 {
+		text = text[:i]
+	}
+	return strings.TrimSpace(text)
+}
+
+// cleanMessageText turns a user message's markup into the text Claude sees:
+// Wavie's own mention is dropped and other mentions are resolved to names
+func (h *Handler) cleanMessageText(ctx context.Context, client *slack.Client, text, botUser string) string {
+	return slack.PlainText(ctx, text, botUser, client.Names())
+}
+
+// directMessageKey is the conversation key for top-level messages in a DM
+func directMessageKey(channel string) string {
+	return "im:" + channel
+}
+
+// botUserID returns Wavie's own user ID from the event authorizations
+func botUserID(eventReq slack.EventRequest) string {
+	for _, auth := range eventReq.Auths {
+		if auth.IsBot {
+			return auth.UserID
+		}
+	}
+	if len(eventReq.Auths) > 0 {
+		return eventReq.Auths[0].UserID
+	}
+	return ""
+}
+
+// isIgnoredMessage reports whether a message event should never be answered:
+// anything posted by a bot, and subtypes other than plain user messages
+func isIgnoredMessage(event slack.Event) bool {
+	if event.BotID != "" {
+		return true
+	}
+	switch event.Subtype {
+	case "", "thread_broadcast", "file_share":
+		return false
+	default:
+		return true
+	}
+}
+
+// isWavieMessage reports whether a thread message was posted by Wavie
+func isWavieMessage(message slack.Message, botUser string) bool {
+	if botUser != "" {
+		return message.User == botUser
+	}
+	return message.BotID != ""
+}
+
+// rebuildThreadHistory seeds the conversation store from the thread's replies
+// in Slack. The message being answered is skipped because the caller adds it.
+// It reports whether Wavie has posted in the thread.
+func (h *Handler) rebuildThreadHistory(ctx context.Context, client *slack.Client, eventReq slack.EventRequest, threadID string) (bool, error) {
+	replies, err := client.GetThreadReplies(ctx, eventReq.Event.Channel, threadID)
+	if err != nil {
+		h.logger.Error("Failed to rebuild thread history", "error", err, "thread_id", threadID)
+		return false, err
+	}
+
+	botUser := botUserID(eventReq)
+
+	participated := false
+	var messages []conversation.Message
+	for _, reply := range replies {
+		if reply.TS == eventReq.Event.TS {
+			continue
+		}
+
+		role := "user"
+		content := h.cleanMessageText(ctx, client, reply.Text, botUser)
+		if isWavieMessage(reply, botUser) {
+			participated = true
+			if h.isPlaceholder(eventReq.Event.Channel, reply) {
+				// An answer still in progress, possibly this one
+				continue
+			}
+			role = "assistant"
+			content = stripHint(reply.Text)
+		} else if reply.BotID != "" {
+			// Other bots' posts are neither Wavie's answers nor user questions
+			continue
+		}
+
+		if content == "" {
+			continue
+		}
+
+		messages = append(messages, conversation.Message{
+			Role:      role,
+			Content:   content,
+			Timestamp: slack.ParseTimestamp(reply.TS),
+		})
+	}
+
+	if len(messages) == 0 {
+		return participated, nil
+	}
+
+	h.conversationStore.Seed(threadID, messages)
+
+	h.logger.Info("Rebuilt thread history from Slack", "thread_id", threadID, "messages", len(messages))
+	return participated, nil
+}
+
+// threadMessages prepares messages of a thread in channel for Claude, with
+// authors and mentions resolved to names. Unfinished answers and empty
+// messages are left out.
+func (h *Handler) threadMessages(ctx context.Context, client *slack.Client, channel string, messages []slack.Message) []slack.ThreadMessage {
+	var thread []slack.ThreadMessage
+	for _, message := range messages {
+		if h.isPlaceholder(channel, message) {
+			continue
+		}
+		text := h.cleanMessageText(ctx, client, message.Text, "")
+		if text == "" {
+			continue
+		}
+		thread = append(thread, slack.ThreadMessage{
+			Author:    authorName(ctx, client, message),
+			Text:      text,
+			Timestamp: slack.ParseTimestamp(message.TS),
+		})
+	}
+	return thread
+}
+
+// threadTranscript renders messages of a thread in channel as "@name: text"
+// lines
+func (h *Handler) threadTranscript(ctx context.Context, client *slack.Client, channel string, messages []slack.Message) string {
+	var lines []string
+	for _, message := range h.threadMessages(ctx, client, channel, messages) {
+		lines = append(lines, message.Author+": "+message.Text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// authorName names who posted a message, falling back to the user ID
+func authorName(ctx context.Context, client *slack.Client, message slack.Message) string {
+	if message.User == "" {
+		return "A bot"
+	}
+	if names := client.Names(); names != nil {
+		if name := names.UserName(ctx, message.User); name != "" {
+			return "@" + name
+		}
+	}
+	return "@" + message.User
+}
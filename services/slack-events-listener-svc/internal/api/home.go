@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/activity"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/ratelimit"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+// homePrompts are the quick-start questions offered on the Home tab
+var homePrompts = []string{
+	"What can you help me with?",
+	"How do I reconcile wallet balances in Bitwave?",
+	"Explain the difference between FIFO, LIFO and specific identification",
+	"What steps should I follow to close the books for a month?",
+}
+
+// How many of the channels a user recently asked in have their quota shown
+const homeQuotaChannels = 3
+
+// publishHome renders a user's Home tab when they open it: their recent
+// questions and feedback, what's left of the daily quotas they ask under, and
+// prompts to get started
+func (h *Handler) publishHome(client *slack.Client, eventReq slack.EventRequest) {
+	if eventReq.Event.Tab != "home" {
+		return
+	}
+
+	ctx := context.Background()
+	user := eventReq.Event.User
+	userActivity := h.activity.Get(user)
+
+	// Links are built from the workspace's address rather than looked up one
+	// by one with chat.getPermalink
+	workspaceURL, err := client.WorkspaceURL(ctx)
+	if err != nil {
+		h.logger.Warn("Failed to look up workspace URL; Home tab links are left out", "error", err, "user", user)
+	}
+
+	home := slack.Home{
+		Quota:   quotaText(h.homeQuotas(eventReq.TeamID, user, userActivity.Questions)),
+		Prompts: homePrompts,
+	}
+	for _, question := range userActivity.Questions {
+		home.Questions = append(home.Questions, slack.HomeEntry{
+			Text: slack.HomeSnippet(question.Question),
+			Link: slack.MessageLink(workspaceURL, question.ChannelID, question.MessageTS, question.ThreadTS),
+			At:   question.AskedAt,
+		})
+	}
+	for _, feedback := range userActivity.Feedback {
+		text := "An answer"
+		if feedback.Question != "" {
+			text = slack.HomeSnippet(feedback.Question)
+		}
+		home.Feedback = append(home.Feedback, slack.HomeEntry{
+			Text: text,
+			Note: feedbackNote(feedback),
+			Link: slack.MessageLink(workspaceURL, feedback.ChannelID, feedback.MessageTS, feedback.ThreadTS),
+			At:   feedback.GivenAt,
+		})
+	}
+
+	if err := client.PublishView(ctx, user, slack.HomeView(home)); err != nil {
+		h.logger.Error("Failed to publish Home tab", "error", err, "user", user)
+	}
+}
+
+// scopeQuota is a quota shown on the Home tab and whose it is
+type scopeQuota struct {
+	label string // mrkdwn
+	quota ratelimit.Quota
+}
+
+// homeQuotas returns the quotas a user's questions count against: their own,
+// those of the channels they recently asked in, and their workspace's
+func (h *Handler) homeQuotas(team, user string, questions []activity.Question) []scopeQuota {
+	quotas := []scopeQuota{{label: "You", quota: h.limiter.Remaining(ratelimit.Scope{Kind: "user", ID: user})}}
+
+	seen := make(map[string]bool)
+	for _, question := range questions {
+		if len(seen) == homeQuotaChannels {
+			break
+		}
+		if question.ChannelID == "" || seen[question.ChannelID] {
+			continue
+		}
+		seen[question.ChannelID] = true
+
+		label := "<#" + question.ChannelID + ">"
+		if slack.IsDirectMessage(question.ChannelID) {
+			label = "Your direct messages"
+		}
+		quotas = append(quotas, scopeQuota{label: label, quota: h.limiter.Remaining(ratelimit.Scope{Kind: "channel", ID: question.ChannelID})})
+	}
+
+	if team != "" {
+		quotas = append(quotas, scopeQuota{label: "Your workspace", quota: h.limiter.Remaining(ratelimit.Scope{Kind: "workspace", ID: team})})
+	}
+	return quotas
+}
+
+// quotaText describes what's left of the daily quotas that have a limit
+func quotaText(quotas []scopeQuota) string {
+	var lines []string
+	var resetAt time.Time
+	for _, q := range quotas {
+		var limits []string
+		if q.quota.RequestLimit > 0 {
+			limits = append(limits, fmt.Sprintf("questions left today: *%d* of %d", q.quota.RequestsLeft, q.quota.RequestLimit))
+		}
+		if q.quota.TokenLimit > 0 {
+			limits = append(limits, fmt.Sprintf("usage left today: *%d%%*", q.quota.TokensLeft*100/q.quota.TokenLimit))
+		}
+		if len(limits) == 0 {
+			continue
+		}
+		lines = append(lines, "• "+q.label+": "+strings.Join(limits, ", "))
+		resetAt = q.quota.ResetAt
+	}
+	if len(lines) == 0 {
+		return "You have no daily limit."
+	}
+
+	// Every daily quota resets at the start of the UTC day
+	lines = append(lines, fmt.Sprintf("Resets <!date^%d^{date_short_pretty} at {time}|%s>.", resetAt.Unix(), resetAt.UTC().Format("Jan 2 15:04 UTC")))
+	return strings.Join(lines, "\n")
+}
+
+// feedbackNote says what feedback was given
+func feedbackNote(feedback activity.Feedback) string {
+	switch feedback.FeedbackType {
+	case "positive":
+		return ":thumbsup: You found this helpful"
+	case "negative":
+		return ":thumbsdown: You found this not helpful"
+	default:
+		return ":speech_balloon: _" + slack.HomeSnippet(feedback.FeedbackText) + "_"
+	}
+}
+
+// askFromHome answers a quick-start prompt clicked on the Home tab in the
+// user's direct messages with Wavie, as if they had sent it there
+func (h *Handler) askFromHome(ctx context.Context, client *slack.Client, payload slack.InteractionPayload, prompt string) {
+	channel, err := client.OpenDirectMessage(ctx, payload.User.ID)
+	if err != nil {
+		h.logger.Error("Failed to open direct message for Home prompt", "error", err, "user", payload.User.ID)
+		return
+	}
+
+	if !h.policies.For(channel).Allowed {
+		h.logger.Info("Ignoring Home prompt; direct messages are not allowed", "user", payload.User.ID)
+		if _, err := client.PostEphemeral(ctx, payload.User.ID, slack.MessageResponse{Channel: channel, Text: notAllowedMessage}); err != nil {
+			h.logger.Error("Failed to decline Home prompt", "error", err, "user", payload.User.ID)
+		}
+		return
+	}
+
+	ts, err := client.PostMessage(ctx, channel, "*You asked:* "+prompt)
+	if err != nil {
+		h.logger.Error("Failed to post Home prompt", "error", err, "user", payload.User.ID)
+		return
+	}
+
+	eventReq := slack.EventRequest{
+		TeamID: payload.Team.ID,
+		Event: slack.Event{
+			Type:        "message",
+			User:        payload.User.ID,
+			Text:        prompt,
+			Channel:     channel,
+			ChannelType: "im",
+			TS:          ts,
+		},
+	}
+
+	if payload.Enterprise != nil {
+		eventReq.EnterpriseID = payload.Enterprise.ID
+	}
+
+	err = h.pool.Submit(directMessageKey(channel),
+		func() { h.answerMessage(client, eventReq) },
+		func(ctx context.Context) { h.abandonEvent(ctx, eventReq) })
+	if err != nil {
+		h.logger.Warn("Failed to queue Home prompt", "error", err, "user", payload.User.ID)
+	}
+}
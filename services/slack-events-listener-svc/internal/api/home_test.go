@@ -0,0 +1,48 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/ratelimit"
+)
+
+func TestQuotaText(t *testing.T) {
+	reset := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		quotas []scopeQuota
+		want   []string
+	}{
+		{
+			name:   "no limits",
+			quotas: []scopeQuota{{label: "You"}, {label: "Your workspace"}},
+			want:   []string{"You have no daily limit."},
+		},
+		{
+			name: "user, channel and workspace limits",
+			quotas: []scopeQuota{
+				{label: "You", quota: ratelimit.Quota{RequestLimit: 200, RequestsLeft: 150, ResetAt: reset}},
+				{label: "<#C1>", quota: ratelimit.Quota{TokenLimit: 1000, TokensLeft: 250, ResetAt: reset}},
+				{label: "<#C2>"},
+				{label: "Your workspace", quota: ratelimit.Quota{RequestLimit: 1000, RequestsLeft: 10, TokenLimit: 100, TokensLeft: 50, ResetAt: reset}},
+			},
+			want: []string{
+				"• You: questions left today: *150* of 200",
+				"• <#C1>: usage left today: *25%*",
+				"• Your workspace: questions left today: *10* of 1000, usage left today: *50%*",
+				"Resets <!date^1767312000^{date_short_pretty} at {time}|Jan 2 00:00 UTC>.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, want := quotaText(tt.quotas), strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("quotaText =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+	"github.com/google/uuid"
+)
+
+// feedbackContext identifies the rated answer; it round-trips through the
+// feedback modal's private_metadata
+type feedbackContext struct {
+	ChannelID           string `json:"channel_id"`
+	MessageTS           string `json:"message_ts"`
+	ThreadTS            string `json:"thread_ts,omitempty"`
+	AnswerCorrelationID string `json:"answer_correlation_id"`
+}
+
+// handleInteraction serves Block Kit interactivity: the feedback buttons on
+// Wavie's answers, the detailed feedback modal and the "Ask Wavie about this
+// message" shortcut
+func (h *Handler) handleInteraction(w http.ResponseWriter, r *http.Request) {
+	if err := h.verifySlackSignature(r); err != nil {
+		h.logger.Error("Failed to verify Slack signature", "error", err)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.logger.Error("Failed to parse interaction", "error", err)
+		http.Error(w, "Failed to parse interaction", http.StatusBadRequest)
+		return
+	}
+
+	var payload slack.InteractionPayload
+	if err := json.Unmarshal([]byte(r.PostForm.Get("payload")), &payload); err != nil {
+		h.logger.Error("Failed to parse interaction payload", "error", err)
+		http.Error(w, "Failed to parse interaction payload", http.StatusBadRequest)
+		return
+	}
+
+	h.DispatchInteraction(r.Context(), payload)
+
+	// An empty 200 acknowledges the action and closes submitted modals
+	w.WriteHeader(http.StatusOK)
+}
+
+// DispatchInteraction handles an interactivity payload. It is shared by the
+// HTTP endpoint and Socket Mode.
+func (h *Handler) DispatchInteraction(ctx context.Context, payload slack.InteractionPayload) {
+	enterpriseID := ""
+	if payload.Enterprise != nil {
+		enterpriseID = payload.Enterprise.ID
+	}
+	client, ok := h.clientFor(ctx, enterpriseID, payload.Team.ID)
+	if !ok {
+		return
+	}
+
+	switch payload.Type {
+	case "block_actions":
+		h.handleBlockActions(ctx, client, payload)
+	case "message_action":
+		if payload.CallbackID == slack.CallbackAskAboutMessage {
+			h.openAskModal(ctx, client, payload)
+		}
+	case "view_submission":
+		if payload.View == nil {
+			return
+		}
+		switch payload.View.CallbackID {
+		case slack.CallbackFeedbackModal:
+			h.handleFeedbackSubmission(client, payload)
+		case slack.CallbackAskModal:
+			h.handleAskSubmission(client, payload)
+		}
+	default:
+		h.logger.Info("Ignoring unsupported interaction", "type", payload.Type)
+	}
+}
+
+// handleBlockActions turns feedback button clicks into feedback requests and
+// answers the quick-start prompts on the Home tab. The modal is opened
+// synchronously because trigger IDs expire after three seconds.
+func (h *Handler) handleBlockActions(ctx context.Context, client *slack.Client, payload slack.InteractionPayload) {
+	fbCtx := feedbackContext{
+		ChannelID: payload.Container.ChannelID,
+		MessageTS: payload.Container.MessageTS,
+		ThreadTS:  payload.Container.ThreadTS,
+	}
+	if fbCtx.ChannelID == "" {
+		fbCtx.ChannelID = payload.Channel.ID
+	}
+	if payload.Message != nil && payload.Message.ThreadTS != "" {
+		fbCtx.ThreadTS = payload.Message.ThreadTS
+	}
+
+	for _, action := range payload.Actions {
+		if strings.HasPrefix(action.ActionID, slack.ActionHomePrompt) {
+			go h.askFromHome(context.Background(), client, payload, action.Value)
+			continue
+		}
+
+		fbCtx.AnswerCorrelationID = action.Value
+
+		switch action.ActionID {
+		case slack.ActionFeedbackPositive, slack.ActionFeedbackNegative:
+			feedbackType := "positive"
+			if action.ActionID == slack.ActionFeedbackNegative {
+				feedbackType = "negative"
+			}
+
+			h.sendButtonFeedback(client, payload, fbCtx, feedbackType)
+
+			go h.acknowledgeFeedback(client, payload.ResponseURL)
+
+		case slack.ActionFeedbackText:
+			metadata, err := json.Marshal(fbCtx)
+			if err != nil {
+				h.logger.Error("Failed to marshal feedback context", "error", err)
+				continue
+			}
+
+			if err := client.OpenView(ctx, payload.TriggerID, slack.FeedbackModal(string(metadata))); err != nil {
+				h.logger.Error("Failed to open feedback modal", "error", err, "answer_correlation_id", action.Value)
+			}
+		}
+	}
+}
+
+// sendButtonFeedback forwards a Helpful/Not helpful click to the broadcast service
+func (h *Handler) sendButtonFeedback(client *slack.Client, payload slack.InteractionPayload, fbCtx feedbackContext, feedbackType string) {
+	correlationID := "fb_" + uuid.New().String()
+
+	feedbackReq := slack.FeedbackRequest{
+		TeamID:              payload.Team.ID,
+		UserID:              payload.User.ID,
+		ChannelID:           fbCtx.ChannelID,
+		MessageTS:           fbCtx.MessageTS,
+		ThreadTS:            fbCtx.ThreadTS,
+		FeedbackType:        feedbackType,
+		Timestamp:           time.Now(),
+		CorrelationID:       correlationID,
+		AnswerCorrelationID: fbCtx.AnswerCorrelationID,
+	}
+	h.queueFeedback(client, feedbackReq)
+
+	h.logger.Info("Received button feedback",
+		"feedback_type", feedbackType,
+		"user", payload.User.ID,
+		"channel", fbCtx.ChannelID,
+		"correlation_id", correlationID,
+		"answer_correlation_id", fbCtx.AnswerCorrelationID)
+}
+
+// handleFeedbackSubmission forwards the text entered in the feedback modal
+func (h *Handler) handleFeedbackSubmission(client *slack.Client, payload slack.InteractionPayload) {
+	var fbCtx feedbackContext
+	if err := json.Unmarshal([]byte(payload.View.PrivateMetadata), &fbCtx); err != nil {
+		h.logger.Error("Failed to parse feedback modal metadata", "error", err)
+		return
+	}
+
+	feedbackText := strings.TrimSpace(payload.View.State.Values[slack.FeedbackInputBlockID][slack.FeedbackInputActionID].Value)
+	if feedbackText == "" {
+		return
+	}
+
+	correlationID := "fb_" + uuid.New().String()
+
+	feedbackReq := slack.FeedbackRequest{
+		TeamID:              payload.Team.ID,
+		UserID:              payload.User.ID,
+		ChannelID:           fbCtx.ChannelID,
+		MessageTS:           fbCtx.MessageTS,
+		ThreadTS:            fbCtx.ThreadTS,
+		FeedbackType:        "text",
+		FeedbackText:        feedbackText,
+		Timestamp:           time.Now(),
+		CorrelationID:       correlationID,
+		AnswerCorrelationID: fbCtx.AnswerCorrelationID,
+	}
+	h.queueFeedback(client, feedbackReq)
+
+	h.logger.Info("Received modal feedback",
+		"user", payload.User.ID,
+		"channel", fbCtx.ChannelID,
+		"correlation_id", correlationID,
+		"answer_correlation_id", fbCtx.AnswerCorrelationID)
+}
+
+// queueFeedback records feedback on the worker pool, so the interaction is
+// acknowledged before the rated answer is looked up, which may mean a call to
+// Slack. If the pool can't take it, or shuts down first, the feedback is
+// recorded without the answer rather than lost.
+func (h *Handler) queueFeedback(client *slack.Client, feedbackReq slack.FeedbackRequest) {
+	err := h.pool.Submit(feedbackOrderingKey(feedbackReq.ChannelID, feedbackReq.MessageTS),
+		func() {
+			h.attachAnswer(context.Background(), client, &feedbackReq)
+			h.sendFeedbackToBroadcast(feedbackReq)
+		},
+		func(ctx context.Context) { h.sendFeedbackToBroadcast(feedbackReq) })
+	if err != nil {
+		h.logger.Warn("Failed to queue feedback, recording it without the answer", "error", err, "correlation_id", feedbackReq.CorrelationID)
+		h.sendFeedbackToBroadcast(feedbackReq)
+	}
+}
+
+// feedbackOrderingKey picks the worker queue for feedback on a message
+func feedbackOrderingKey(channel, messageTS string) string {
+	return "feedback:" + channel + ":" + messageTS
+}
+
+// attachAnswer fills in the rated question and answer when the message is a
+// known answer. Answers to /wavie ask are ephemeral and are never recorded.
+func (h *Handler) attachAnswer(ctx context.Context, client *slack.Client, feedbackReq *slack.FeedbackRequest) {
+	answer, ok := h.lookupAnswer(ctx, client, feedbackReq.ChannelID, feedbackReq.MessageTS)
+	if !ok {
+		return
+	}
+	feedbackReq.Question = answer.Question
+	feedbackReq.Response = answer.Response
+	feedbackReq.AnswerCorrelationID = answer.CorrelationID
+	feedbackReq.Model = answer.Model
+	feedbackReq.PromptVersion = answer.PromptVersion
+}
+
+// acknowledgeFeedback thanks the user with an ephemeral message
+func (h *Handler) acknowledgeFeedback(client *slack.Client, responseURL string) {
+	if responseURL == "" {
+		return
+	}
+
+	response := slack.CommandResponse{
+		ResponseType: "ephemeral",
+		Text:         "Thanks for the feedback! :pray:",
+	}
+	if err := client.RespondToURL(context.Background(), responseURL, response); err != nil {
+		h.logger.Error("Failed to acknowledge feedback", "error", err)
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/ratelimit"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+// questionScopes are the scopes a question counts against
+func questionScopes(user, channel, team string) []ratelimit.Scope {
+	return []ratelimit.Scope{
+		{Kind: "user", ID: user},
+		{Kind: "channel", ID: channel},
+		{Kind: "workspace", ID: team},
+	}
+}
+
+// limitMessage tells the user which limit their question hit and when they
+// can ask again. The time is shown in the reader's own time zone.
+func limitMessage(decision ratelimit.Decision) string {
+	who := "You've"
+	switch decision.Scope.Kind {
+	case "channel":
+		who = "This channel has"
+	case "workspace":
+		who = "Your workspace has"
+	}
+
+	reset := decision.ResetAt.Unix()
+	when := fmt.Sprintf("<!date^%d^{date_short_pretty} at {time}|%s>", reset, decision.ResetAt.UTC().Format("Jan 2 15:04 UTC"))
+
+	switch decision.Reason {
+	case "rate":
+		return fmt.Sprintf("%s hit the limit for how fast questions can be asked. Please try again at %s.", who, when)
+	case "tokens":
+		return fmt.Sprintf("%s hit today's usage limit. It resets at %s.", who, when)
+	default:
+		return fmt.Sprintf("%s hit today's question limit. It resets at %s.", who, when)
+	}
+}
+
+// declineLimited tells the user, privately, that their question hit a limit
+func (h *Handler) declineLimited(client *slack.Client, eventReq slack.EventRequest, decision ratelimit.Decision) {
+	h.logger.Info("Question refused by rate limit",
+		"scope", decision.Scope.Kind,
+		"scope_id", decision.Scope.ID,
+		"reason", decision.Reason,
+		"user", eventReq.Event.User,
+		"channel", eventReq.Event.Channel)
+
+	_, err := client.PostEphemeral(context.Background(), eventReq.Event.User, slack.MessageResponse{
+		Channel:  eventReq.Event.Channel,
+		Text:     limitMessage(decision),
+		ThreadTS: eventReq.Event.ThreadTS,
+	})
+	if err != nil {
+		h.logger.Error("Failed to post rate limit notice", "error", err, "channel", eventReq.Event.Channel)
+	}
+}
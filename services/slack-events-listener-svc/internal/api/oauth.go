@@ -0,0 +1,187 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/installations"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+const (
+	// How long a user has to approve the install on Slack's page
+	oauthStateTTL = 10 * time.Minute
+
+	oauthStateCookie = "wavie_oauth_state"
+)
+
+// OAuthHandler serves the "Add to Slack" flow that installs Wavie in a
+// workspace and stores its bot token
+type OAuthHandler struct {
+	oauth         *slack.OAuth
+	installations *installations.Store
+	logger        *slog.Logger
+}
+
+func NewOAuthHandler(oauth *slack.OAuth, installations *installations.Store, logger *slog.Logger) *OAuthHandler {
+	return &OAuthHandler{
+		oauth:         oauth,
+		installations: installations,
+		logger:        logger,
+	}
+}
+
+func (h *OAuthHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /slack/install", h.handleInstall)
+	mux.HandleFunc("GET /slack/oauth/callback", h.handleCallback)
+}
+
+// handleInstall sends the user to Slack to approve the install. The state is
+// signed so any instance can check it, and is also set as a cookie so the
+// callback must come from the browser that started the install.
+func (h *OAuthHandler) handleInstall(w http.ResponseWriter, r *http.Request) {
+	state, err := h.newState(time.Now())
+	if err != nil {
+		h.logger.Error("Failed to create OAuth state", "error", err)
+		http.Error(w, "Failed to start install", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/slack/oauth",
+		MaxAge:   int(oauthStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, h.oauth.AuthorizeURL(state), http.StatusFound)
+}
+
+// handleCallback exchanges the code Slack redirects back with for the
+// workspace's bot token and saves the installation
+func (h *OAuthHandler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if denied := query.Get("error"); denied != "" {
+		h.logger.Info("Install was cancelled", "error", denied)
+		http.Error(w, "Wavie was not installed: "+denied, http.StatusBadRequest)
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(oauthStateCookie)
+	if err != nil || !hmac.Equal([]byte(cookie.Value), []byte(state)) {
+		h.logger.Warn("OAuth callback state does not match the browser's")
+		http.Error(w, "Invalid install session; please start again", http.StatusBadRequest)
+		return
+	}
+	if err := h.verifyState(state, time.Now()); err != nil {
+		h.logger.Warn("Invalid OAuth state", "error", err)
+		http.Error(w, "Invalid install session; please start again", http.StatusBadRequest)
+		return
+	}
+
+	// The state is single use
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Path: "/slack/oauth", MaxAge: -1})
+
+	code := query.Get("code")
+	if code == "" {
+		http.Error(w, "Missing code", http.StatusBadRequest)
+		return
+	}
+
+	accessResp, err := h.oauth.Exchange(r.Context(), code)
+	if err != nil {
+		h.logger.Error("Failed to exchange OAuth code", "error", err)
+		http.Error(w, "Failed to install Wavie", http.StatusBadGateway)
+		return
+	}
+
+	installation := installations.Installation{
+		IsEnterpriseInstall: accessResp.IsEnterpriseInstall,
+		AppID:               accessResp.AppID,
+		BotUserID:           accessResp.BotUserID,
+		BotToken:            accessResp.AccessToken,
+		Scope:               accessResp.Scope,
+		InstalledBy:         accessResp.AuthedUser.ID,
+		InstalledAt:         time.Now(),
+	}
+	if accessResp.Team != nil {
+		installation.TeamID = accessResp.Team.ID
+		installation.TeamName = accessResp.Team.Name
+	}
+	if accessResp.Enterprise != nil {
+		installation.EnterpriseID = accessResp.Enterprise.ID
+		installation.EnterpriseName = accessResp.Enterprise.Name
+	}
+
+	if err := h.installations.Save(installation); err != nil {
+		h.logger.Error("Failed to save installation", "error", err, "team_id", installation.TeamID)
+		http.Error(w, "Failed to install Wavie", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("Wavie installed",
+		"team_id", installation.TeamID,
+		"enterprise_id", installation.EnterpriseID,
+		"installed_by", installation.InstalledBy)
+
+	// Open Wavie in the workspace it was just installed in
+	redirect := url.Values{}
+	redirect.Set("app", installation.AppID)
+	if installation.TeamID != "" {
+		redirect.Set("team", installation.TeamID)
+	}
+	http.Redirect(w, r, "https://slack.com/app_redirect?"+redirect.Encode(), http.StatusFound)
+}
+
+// newState returns "<unix time>.<nonce>.<signature>", signed with the client
+// secret
+func (h *OAuthHandler) newState(now time.Time) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	payload := strconv.FormatInt(now.Unix(), 10) + "." + hex.EncodeToString(nonce)
+	return payload + "." + h.signState(payload), nil
+}
+
+// verifyState checks a state's signature and that it hasn't expired
+func (h *OAuthHandler) verifyState(state string, now time.Time) error {
+	i := strings.LastIndexByte(state, '.')
+	if i < 0 {
+		return fmt.Errorf("malformed state")
+	}
+	payload, signature := state[:i], state[i+1:]
+	if !hmac.Equal([]byte(h.signState(payload)), []byte(signature)) {
+		return fmt.Errorf("invalid state signature")
+	}
+
+	issued, _, _ := strings.Cut(payload, ".")
+	unix, err := strconv.ParseInt(issued, 10, 64)
+	if err != nil {
+		return fmt.Errorf("malformed state: %w", err)
+	}
+	if now.Sub(time.Unix(unix, 0)) > oauthStateTTL {
+		return fmt.Errorf("state expired")
+	}
+	return nil
+}
+
+func (h *OAuthHandler) signState(payload string) string {
+	mac := hmac.New(sha256.New, []byte(h.oauth.ClientSecret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+func newTestOAuthHandler(secret string) *OAuthHandler {
+	return NewOAuthHandler(slack.NewOAuth("", "client-id", secret, "", "chat:write", slog.New(slog.NewTextHandler(io.Discard, nil))), nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestVerifyState(t *testing.T) {
+	h := newTestOAuthHandler("secret")
+	now := time.Now()
+
+	state, err := h.newState(now)
+	if err != nil {
+		t.Fatalf("newState failed: %v", err)
+	}
+	payload := state[:strings.LastIndexByte(state, '.')]
+	otherSecret, _ := newTestOAuthHandler("other-secret").newState(now)
+
+	tests := []struct {
+		name    string
+		state   string
+		at      time.Time
+		wantErr bool
+	}{
+		{"fresh", state, now, false},
+		{"nearly expired", state, now.Add(oauthStateTTL - time.Second), false},
+		{"expired", state, now.Add(oauthStateTTL + time.Second), true},
+		{"tampered signature", payload + ".00", now, true},
+		{"tampered timestamp", "1" + state, now, true},
+		{"signed with another secret", otherSecret, now, true},
+		{"unsigned", payload, now, true},
+		{"empty", "", now, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := h.verifyState(tt.state, tt.at)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyState(%q) = %v, want error: %v", tt.state, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInstallSetsStateCookie(t *testing.T) {
+	h := newTestOAuthHandler("secret")
+
+	rec := httptest.NewRecorder()
+	h.handleInstall(rec, httptest.NewRequest("GET", "/slack/install", nil))
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusFound)
+	}
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect: %v", err)
+	}
+	state := location.Query().Get("state")
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oauthStateCookie || cookies[0].Value != state {
+		t.Fatalf("cookies = %v, want %s=%s", cookies, oauthStateCookie, state)
+	}
+	if !cookies[0].HttpOnly {
+		t.Error("state cookie is readable from scripts")
+	}
+	if err := h.verifyState(state, time.Now()); err != nil {
+		t.Errorf("redirect carries an invalid state: %v", err)
+	}
+}
+
+func TestCallbackRejectsBadState(t *testing.T) {
+	h := newTestOAuthHandler("secret")
+	state, _ := h.newState(time.Now())
+	expired, _ := h.newState(time.Now().Add(-2 * oauthStateTTL))
+	other, _ := h.newState(time.Now())
+
+	tests := []struct {
+		name   string
+		query  string
+		cookie string
+		want   string
+	}{
+		{"no cookie", "state=" + state, "", "Invalid install session"},
+		{"cookie from another install", "state=" + state, other, "Invalid install session"},
+		{"no state", "", state, "Invalid install session"},
+		{"expired state", "state=" + expired, expired, "Invalid install session"},
+		{"forged state", "state=123.abc.def", "123.abc.def", "Invalid install session"},
+		{"cancelled install", "error=access_denied&state=" + state, state, "Wavie was not installed"},
+		// Past the state checks, so the code is looked at
+		{"valid state", "state=" + state, state, "Missing code"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/slack/oauth/callback?"+tt.query, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: oauthStateCookie, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			h.handleCallback(rec, req)
+
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("response = %d %q, want 400 %q", rec.Code, rec.Body.String(), tt.want)
+			}
+		})
+	}
+}
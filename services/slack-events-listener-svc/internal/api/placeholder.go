@@ -0,0 +1,284 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+const (
+	placeholderPrefix = ":hourglass_flowing_sand:"
+	placeholderText   = placeholderPrefix + " _Thinking…_"
+
+	// How often a slow answer's placeholder shows the elapsed time; well
+	// inside chat.update's rate limit
+	placeholderUpdateInterval = 10 * time.Second
+
+	// How often a streamed answer is pushed to Slack, about chat.update's
+	// ~50 per minute limit. When Slack rate limits an update, the interval
+	// backs off.
+	streamUpdateInterval    = 1200 * time.Millisecond
+	maxStreamUpdateInterval = 5 * time.Second
+
+	// Appended to a partial answer while it is still streaming
+	streamingCursor = " ▍"
+)
+
+// openPlaceholders tracks the placeholders whose answer is still being
+// written, by channel and timestamp, so thread history can leave them out
+type openPlaceholders struct {
+	mutex sync.Mutex
+	keys  map[string]bool
+}
+
+func newOpenPlaceholders() *openPlaceholders {
+	return &openPlaceholders{keys: make(map[string]bool)}
+}
+
+func (o *openPlaceholders) add(channel, ts string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.keys[channel+":"+ts] = true
+}
+
+func (o *openPlaceholders) remove(channel, ts string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	delete(o.keys, channel+":"+ts)
+}
+
+func (o *openPlaceholders) has(channel, ts string) bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.keys[channel+":"+ts]
+}
+
+// placeholder is the "thinking" message posted as soon as a question arrives.
+// It counts up while the answer is generated, shows a streamed answer as it
+// arrives, and is then edited in place into the answer or an error.
+type placeholder struct {
+	client        *slack.Client
+	logger        *slog.Logger
+	open          *openPlaceholders
+	channel       string
+	threadTS      string
+	correlationID string
+	ts            string // empty if the placeholder couldn't be posted
+	user          string // set when replies are ephemeral, visible to this user only
+	stop          chan struct{}
+	stopped       sync.WaitGroup
+
+	// Progress updates are sent with updates, which finish cancels so an
+	// update in flight can't hold up the reply
+	updates       context.Context
+	cancelUpdates context.CancelFunc
+
+	mutex     sync.Mutex
+	partial   string // latest streamed text not yet pushed to Slack
+	pending   bool
+	streaming bool
+}
+
+// postPlaceholder posts the placeholder and starts its elapsed-time updates.
+// If posting fails, the final reply is simply posted as a new message.
+func (h *Handler) postPlaceholder(client *slack.Client, channel, threadTS, correlationID string) *placeholder {
+	p := &placeholder{
+		client:        client,
+		logger:        h.logger,
+		open:          h.placeholders,
+		channel:       channel,
+		threadTS:      threadTS,
+		correlationID: correlationID,
+		stop:          make(chan struct{}),
+	}
+
+	ts, err := client.PostMessage(context.Background(), channel, placeholderText, threadTS)
+	if err != nil {
+		h.logger.Warn("Failed to post placeholder", "error", err, "correlation_id", correlationID)
+		return p
+	}
+	p.ts = ts
+	p.open.add(channel, ts)
+
+	p.updates, p.cancelUpdates = context.WithCancel(context.Background())
+	p.stopped.Add(1)
+	go p.tick(time.Now())
+
+	return p
+}
+
+// ephemeralReply is a reply only user can see. Ephemeral messages can't be
+// edited, so there is no placeholder: the answer is posted when it's ready.
+func (h *Handler) ephemeralReply(client *slack.Client, channel, threadTS, user, correlationID string) *placeholder {
+	return &placeholder{
+		client:        client,
+		logger:        h.logger,
+		channel:       channel,
+		threadTS:      threadTS,
+		correlationID: correlationID,
+		user:          user,
+		stop:          make(chan struct{}),
+	}
+}
+
+// progress records the answer streamed so far; it is pushed to Slack on the
+// next stream tick
+func (p *placeholder) progress(text string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.partial = text
+	p.pending = true
+	p.streaming = true
+}
+
+// takePartial returns streamed text that hasn't been shown yet
+func (p *placeholder) takePartial() (string, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !p.pending {
+		return "", false
+	}
+	p.pending = false
+	return p.partial, true
+}
+
+func (p *placeholder) isStreaming() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.streaming
+}
+
+// tick shows how long the answer has been in progress until text starts
+// streaming, then shows the streamed text
+func (p *placeholder) tick(start time.Time) {
+	defer p.stopped.Done()
+
+	elapsedTicker := time.NewTicker(placeholderUpdateInterval)
+	defer elapsedTicker.Stop()
+
+	interval := streamUpdateInterval
+	streamTicker := time.NewTicker(interval)
+	defer streamTicker.Stop()
+
+	for {
+		select {
+		case <-elapsedTicker.C:
+			if p.isStreaming() {
+				continue
+			}
+			elapsed := time.Since(start).Round(time.Second)
+			p.show(fmt.Sprintf("%s _Still thinking… (%s)_", placeholderPrefix, elapsed))
+
+		case <-streamTicker.C:
+			partial, ok := p.takePartial()
+			if !ok {
+				continue
+			}
+			// Rate limited: slow down, at least as much as Slack asks, and
+			// catch up on the next tick
+			if wait, limited := slack.RateLimitWait(p.show(partial + streamingCursor)); limited {
+				interval = max(min(interval*2, maxStreamUpdateInterval), wait)
+				streamTicker.Reset(interval)
+			}
+
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// show replaces the placeholder's text. It isn't retried: a failed progress
+// update is caught up by the next one or by the reply.
+func (p *placeholder) show(text string) error {
+	err := p.client.UpdateMessageOnce(p.updates, slack.MessageResponse{
+		Channel: p.channel,
+		TS:      p.ts,
+		Text:    text,
+	})
+	if err != nil && p.updates.Err() == nil {
+		p.logger.Warn("Failed to update placeholder", "error", err, "correlation_id", p.correlationID)
+	}
+	return err
+}
+
+// finish replaces the placeholder with msg and returns the timestamp of the
+// message that now carries it. The progress updates are stopped first so a
+// late tick can't overwrite the reply; only this final update is retried.
+func (p *placeholder) finish(ctx context.Context, msg slack.MessageResponse) (string, error) {
+	if p.ts == "" {
+		return p.post(ctx, msg)
+	}
+
+	close(p.stop)
+	p.cancelUpdates()
+	p.stopped.Wait()
+	defer p.open.remove(p.channel, p.ts)
+
+	msg.Channel = p.channel
+	msg.TS = p.ts
+	msg.ThreadTS = ""
+	if err := p.client.UpdateMessage(ctx, msg); err != nil {
+		// Don't leave the user staring at "Thinking…": post the reply instead
+		p.logger.Warn("Failed to update placeholder, posting reply instead", "error", err, "correlation_id", p.correlationID)
+		if err := p.client.DeleteMessage(ctx, p.channel, p.ts); err != nil {
+			p.logger.Warn("Failed to delete placeholder", "error", err, "correlation_id", p.correlationID)
+		}
+		return p.post(ctx, msg)
+	}
+
+	return p.ts, nil
+}
+
+// post sends msg as a new message in the reply's thread
+func (p *placeholder) post(ctx context.Context, msg slack.MessageResponse) (string, error) {
+	msg.Channel = p.channel
+	msg.TS = ""
+	msg.ThreadTS = p.threadTS
+	if p.user != "" {
+		return p.client.PostEphemeral(ctx, p.user, msg)
+	}
+	return p.client.Post(ctx, msg)
+}
+
+// finishAll replaces the placeholder with the first of msgs and posts the
+// rest after it, in the same thread, returning the timestamps of the messages posted.
+// If a continuation fails, the timestamps posted so far are returned with
+// the error.
+func (p *placeholder) finishAll(ctx context.Context, msgs []slack.MessageResponse) ([]string, error) {
+	ts, err := p.finish(ctx, msgs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	posted := []string{ts}
+	for _, msg := range msgs[1:] {
+		ts, err := p.post(ctx, msg)
+		if err != nil {
+			return posted, fmt.Errorf("failed to post answer continuation: %w", err)
+		}
+		posted = append(posted, ts)
+	}
+	return posted, nil
+}
+
+// fail replaces the placeholder with an error message for the user
+func (p *placeholder) fail(ctx context.Context, text string) {
+	if _, err := p.finish(ctx, slack.MessageResponse{Channel: p.channel, Text: text}); err != nil {
+		p.logger.Error("Failed to post error reply", "error", err, "correlation_id", p.correlationID)
+	}
+}
+
+// isPlaceholder reports whether a message in channel is a placeholder that
+// hasn't been replaced yet: one of this instance's, even while it shows a
+// partially streamed answer, or one left behind by an instance that stopped
+// while still thinking
+func (h *Handler) isPlaceholder(channel string, message slack.Message) bool {
+	return h.placeholders.has(channel, message.TS) || strings.HasPrefix(message.Text, placeholderPrefix)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+func TestFinishDoesntWaitForProgress(t *testing.T) {
+	streamed := make(chan struct{})
+	var once sync.Once
+	var mutex sync.Mutex
+	var final []string
+
+	// chat.update hangs for a streamed update, as Slack might when it is slow
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/chat.postMessage") {
+			w.Write([]byte(`{"ok":true,"channel":"C1","ts":"1.1"}`))
+			return
+		}
+
+		var msg slack.MessageResponse
+		json.NewDecoder(r.Body).Decode(&msg)
+		if strings.HasSuffix(msg.Text, streamingCursor) {
+			once.Do(func() { close(streamed) })
+			<-r.Context().Done()
+			return
+		}
+		mutex.Lock()
+		final = append(final, msg.Text)
+		mutex.Unlock()
+		w.Write([]byte(`{"ok":true,"channel":"C1","ts":"1.1"}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := &Handler{logger: logger, placeholders: newOpenPlaceholders()}
+	p := h.postPlaceholder(slack.NewClient(server.URL, "xoxb-test", logger), "C1", "1.0", "wv-test")
+	p.progress("partial answer")
+
+	select {
+	case <-streamed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the streamed answer was never sent")
+	}
+
+	start := time.Now()
+	ts, err := p.finish(context.Background(), slack.MessageResponse{Text: "full answer"})
+	if err != nil || ts != "1.1" {
+		t.Fatalf("finish = %q, %v; want the placeholder's timestamp", ts, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("finish took %s waiting for a progress update", elapsed)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(final) != 1 || final[0] != "full answer" {
+		t.Errorf("final updates = %q, want the full answer once", final)
+	}
+	if h.placeholders.has("C1", "1.1") {
+		t.Error("placeholder is still open after finish")
+	}
+}
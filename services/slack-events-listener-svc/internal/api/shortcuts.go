@@ -0,0 +1,368 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BitwaveCorp/shared-svcs/shared/mrkdwn"
+	"github.com/BitwaveCorp/shared-svcs/shared/utils/idgen"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/activity"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/answers"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/policy"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+const (
+	// Slack caps a view's private_metadata at 3000 characters
+	maxPrivateMetadata = 3000
+
+	// Most other thread messages given to Claude with the selected one; a
+	// longer thread keeps its first message and the latest replies
+	maxShortcutThreadMessages = 50
+
+	// Asked when the user leaves the modal's question empty
+	defaultShortcutQuestion = "Explain this message."
+)
+
+// shortcutContext identifies the message the "Ask Wavie about this message"
+// shortcut was used on; it round-trips through the ask modal's
+// private_metadata
+type shortcutContext struct {
+	ChannelID       string `json:"channel_id"`
+	MessageTS       string `json:"message_ts"`
+	ThreadTS        string `json:"thread_ts,omitempty"`
+	ResponseURL     string `json:"response_url"`
+	IsDirectMessage bool   `json:"is_dm,omitempty"`
+
+	// The selected message as Slack sent it, used when Wavie can't read the
+	// channel itself. Dropped if it would overflow the metadata.
+	Author string `json:"author,omitempty"`
+	Text   string `json:"text,omitempty"`
+}
+
+// threadTS is the thread the selected message is in, or starts
+func (s shortcutContext) threadTS() string {
+	if s.ThreadTS != "" {
+		return s.ThreadTS
+	}
+	return s.MessageTS
+}
+
+// openAskModal asks what the user wants to know about the message the
+// shortcut was used on. The modal is opened synchronously because trigger
+// IDs expire after three seconds.
+func (h *Handler) openAskModal(ctx context.Context, client *slack.Client, payload slack.InteractionPayload) {
+	if payload.Message == nil {
+		h.logger.Warn("Message shortcut without a message", "user", payload.User.ID)
+		return
+	}
+
+	if !h.policies.For(payload.Channel.ID).Allowed {
+		go h.respondEphemeral(context.Background(), client, payload.ResponseURL, notAllowedMessage)
+		return
+	}
+
+	shortcut := shortcutContext{
+		ChannelID:       payload.Channel.ID,
+		MessageTS:       payload.Message.TS,
+		ThreadTS:        payload.Message.ThreadTS,
+		ResponseURL:     payload.ResponseURL,
+		IsDirectMessage: slack.IsDirectMessage(payload.Channel.ID),
+		Author:          payload.Message.User,
+		Text:            payload.Message.Text,
+	}
+	if shortcut.MessageTS == "" {
+		shortcut.MessageTS = payload.MessageTS
+	}
+
+	metadata, err := json.Marshal(shortcut)
+	if err == nil && len(metadata) > maxPrivateMetadata {
+		shortcut.Text = ""
+		metadata, err = json.Marshal(shortcut)
+	}
+	if err != nil {
+		h.logger.Error("Failed to marshal shortcut context", "error", err)
+		return
+	}
+
+	if err := client.OpenView(ctx, payload.TriggerID, slack.AskModal(string(metadata))); err != nil {
+		h.logger.Error("Failed to open ask modal", "error", err, "user", payload.User.ID)
+	}
+}
+
+// handleAskSubmission queues the question asked in the modal. The modal
+// closes straight away; the answer follows in the thread or ephemerally.
+func (h *Handler) handleAskSubmission(client *slack.Client, payload slack.InteractionPayload) {
+	var shortcut shortcutContext
+	if err := json.Unmarshal([]byte(payload.View.PrivateMetadata), &shortcut); err != nil {
+		h.logger.Error("Failed to parse ask modal metadata", "error", err)
+		return
+	}
+
+	values := payload.View.State.Values
+	question := strings.TrimSpace(values[slack.AskQuestionBlockID][slack.AskQuestionActionID].Value)
+	if question == "" {
+		question = defaultShortcutQuestion
+	}
+
+	// Channels that want replies only the asker can see get them regardless
+	// of what was picked
+	inThread := false
+	if option := values[slack.AskVisibilityBlockID][slack.AskVisibilityActionID].SelectedOption; option != nil {
+		inThread = option.Value == slack.AskVisibilityThread
+	}
+	if h.policies.For(shortcut.ChannelID).Ephemeral {
+		inThread = false
+	}
+
+	scopes := questionScopes(payload.User.ID, shortcut.ChannelID, payload.Team.ID)
+	if decision := h.limiter.Allow(scopes...); !decision.Allowed {
+		h.logger.Info("Shortcut question refused by rate limit", "scope", decision.Scope.Kind, "reason", decision.Reason, "user", payload.User.ID)
+		go h.respondEphemeral(context.Background(), client, shortcut.ResponseURL, limitMessage(decision))
+		return
+	}
+
+	err := h.pool.Submit(shortcut.threadTS(),
+		func() { h.answerShortcut(client, payload, shortcut, question, inThread) },
+		func(ctx context.Context) { h.respondEphemeral(ctx, client, shortcut.ResponseURL, restartMessage) })
+	if err != nil {
+		h.logger.Warn("Failed to queue shortcut question", "error", err, "user", payload.User.ID)
+		go h.respondEphemeral(context.Background(), client, shortcut.ResponseURL, "I'm swamped right now. Please try again in a moment.")
+	}
+}
+
+// answerShortcut runs a question about a message, with its thread as context,
+// through Claude. Answers in the thread continue as a normal Wavie thread;
+// if Wavie can't post there, the answer is sent ephemerally through the
+// shortcut's response_url, which works in any channel.
+func (h *Handler) answerShortcut(client *slack.Client, payload slack.InteractionPayload, shortcut shortcutContext, question string, inThread bool) {
+	ctx := context.Background()
+	user := payload.User.ID
+	threadTS := shortcut.threadTS()
+
+	correlationID, err := idgen.GenerateId("wv", 16)
+	if err != nil {
+		h.logger.Error("Failed to generate correlation ID", "error", err)
+		return
+	}
+
+	h.logger.Info("Processing shortcut question",
+		"correlation_id", correlationID,
+		"user", user,
+		"channel", shortcut.ChannelID,
+		"message_ts", shortcut.MessageTS,
+		"in_thread", inThread)
+
+	conversationKey := threadTS
+	var reply *placeholder
+	if inThread {
+		reply = h.postPlaceholder(client, shortcut.ChannelID, threadTS, correlationID)
+	} else {
+		conversationKey = "shortcut:" + shortcut.ChannelID + ":" + shortcut.MessageTS + ":" + user
+		reply = h.ephemeralReply(client, shortcut.ChannelID, threadTS, user, correlationID)
+	}
+
+	fail := func(text string) {
+		if _, err := reply.finish(ctx, slack.MessageResponse{Channel: shortcut.ChannelID, Text: text}); err != nil {
+			h.respondEphemeral(context.Background(), client, shortcut.ResponseURL, text)
+		}
+	}
+
+	prompt, ok := h.shortcutPrompt(ctx, client, shortcut, question)
+	if !ok {
+		fail("Sorry, I can't read that message. Invite me to the channel and try again.")
+		return
+	}
+
+	channelPolicy := h.policies.For(shortcut.ChannelID)
+	claudeReq := slack.ClaudeRequest{
+		Message:       prompt,
+		UserID:        user,
+		ChannelID:     shortcut.ChannelID,
+		MessageTS:     shortcut.MessageTS,
+		ThreadTS:      threadTS,
+		CorrelationID: correlationID,
+		Persona:       channelPolicy.Persona,
+		Model:         channelPolicy.Model,
+	}
+
+	var onProgress func(string)
+	if inThread {
+		onProgress = func(partial string) {
+			reply.progress(slack.SplitAnswer(mrkdwn.Render(partial))[0])
+		}
+	}
+
+	claudeResp, err := h.askClaude(conversationKey, claudeReq, onProgress)
+	if err != nil {
+		h.logger.Error("Failed to call GPT service", "error", err, "correlation_id", correlationID)
+		fail("Sorry, I'm having trouble processing your request right now.")
+		return
+	}
+	if claudeResp.Error != "" {
+		h.logger.Error("Claude service returned error", "error", claudeResp.Error, "correlation_id", correlationID)
+		fail("Sorry, I encountered an error processing your request.")
+		return
+	}
+
+	h.limiter.RecordTokens(claudeResp.InputTokens+claudeResp.OutputTokens, questionScopes(user, shortcut.ChannelID, payload.Team.ID)...)
+
+	answer := claudeResp.Response
+	text := mrkdwn.Render(answer)
+
+	metadata := slack.AnswerMetadata{
+		CorrelationID: correlationID,
+		Model:         claudeResp.Model,
+		PromptVersion: claudeResp.PromptVersion,
+	}
+	answerTSs, err := reply.finishAll(ctx, slack.AnswerMessages(shortcut.ChannelID, text, threadTS, metadata))
+	if err != nil && len(answerTSs) == 0 {
+		h.logger.Warn("Failed to post shortcut answer, responding ephemerally instead", "error", err, "correlation_id", correlationID)
+		if err := respondAll(ctx, client, shortcut.ResponseURL, ephemeralAnswers(text, correlationID)); err != nil {
+			h.logger.Error("Failed to respond to shortcut", "error", err, "correlation_id", correlationID)
+			return
+		}
+		inThread = false
+	} else if err != nil {
+		h.logger.Error("Failed to post response to Slack", "error", err, "correlation_id", correlationID)
+	}
+
+	for _, answerTS := range answerTSs {
+		h.answerStore.Record(answers.Answer{
+			ChannelID:     shortcut.ChannelID,
+			MessageTS:     answerTS,
+			ThreadTS:      threadTS,
+			CorrelationID: correlationID,
+			Model:         claudeResp.Model,
+			PromptVersion: claudeResp.PromptVersion,
+			Question:      prompt,
+			Response:      answer,
+		})
+	}
+
+	// Ephemeral answers can't be linked to, so they aren't listed on the Home tab
+	if inThread && len(answerTSs) > 0 {
+		h.activity.RecordQuestion(user, activity.Question{
+			ChannelID: shortcut.ChannelID,
+			MessageTS: answerTSs[0],
+			ThreadTS:  threadTS,
+			Question:  question,
+		})
+	}
+
+	if !h.shortcutBroadcasts(ctx, client, shortcut, channelPolicy, inThread, correlationID) {
+		return
+	}
+
+	// The selected message and its thread stay where they were posted; the
+	// broadcast links to them
+	broadcastQuestion := question
+	workspaceURL, err := client.WorkspaceURL(ctx)
+	if err != nil {
+		h.logger.Warn("Failed to look up workspace URL; broadcasting without a link", "error", err, "correlation_id", correlationID)
+	}
+	if link := slack.MessageLink(workspaceURL, shortcut.ChannelID, shortcut.MessageTS, shortcut.ThreadTS); link != "" {
+		broadcastQuestion += fmt.Sprintf("\n_About <%s|this message>_", link)
+	}
+
+	h.callBroadcastService(slack.BroadcastRequest{
+		TeamID:        payload.Team.ID,
+		UserID:        user,
+		ChannelID:     shortcut.ChannelID,
+		ThreadID:      threadTS,
+		Question:      broadcastQuestion,
+		Response:      answer,
+		Timestamp:     time.Now(),
+		CorrelationID: correlationID,
+	})
+}
+
+// shortcutBroadcasts reports whether a shortcut question may be broadcast.
+// Answers only the asker saw, and questions about messages in private
+// channels, stay out of the broadcast channel, as do direct messages unless
+// BROADCAST_DIRECT_MESSAGES is set.
+func (h *Handler) shortcutBroadcasts(ctx context.Context, client *slack.Client, shortcut shortcutContext, channelPolicy policy.Policy, inThread bool, correlationID string) bool {
+	switch {
+	case !channelPolicy.Allowed || !channelPolicy.Broadcast:
+		h.logger.Info("Skipping shortcut broadcast per channel policy", "channel", shortcut.ChannelID, "correlation_id", correlationID)
+		return false
+	case !inThread:
+		h.logger.Info("Skipping broadcast of ephemeral shortcut answer", "correlation_id", correlationID)
+		return false
+	case shortcut.IsDirectMessage:
+		if !h.broadcastDirectMessages {
+			h.logger.Info("Skipping broadcast of direct message", "correlation_id", correlationID)
+		}
+		return h.broadcastDirectMessages
+	}
+
+	// Wavie answered in the thread, so it can read the channel
+	conversation, err := client.GetConversation(ctx, shortcut.ChannelID)
+	if err != nil {
+		h.logger.Warn("Failed to look up channel; not broadcasting shortcut answer", "error", err, "channel", shortcut.ChannelID, "correlation_id", correlationID)
+		return false
+	}
+	if conversation.IsPrivate {
+		h.logger.Info("Skipping broadcast of shortcut answer in private channel", "channel", shortcut.ChannelID, "correlation_id", correlationID)
+		return false
+	}
+	return true
+}
+
+// shortcutPrompt puts the selected message, the rest of its thread and the
+// user's question together for Claude. It reports false if the selected
+// message's text is unknown: Wavie can't read the channel and the text was
+// too long to carry through the modal.
+func (h *Handler) shortcutPrompt(ctx context.Context, client *slack.Client, shortcut shortcutContext, question string) (string, bool) {
+	selected := slack.Message{User: shortcut.Author, Text: shortcut.Text, TS: shortcut.MessageTS}
+
+	thread, err := client.GetThreadReplies(ctx, shortcut.ChannelID, shortcut.threadTS())
+	if err != nil {
+		// Usually because Wavie isn't in the channel
+		h.logger.Warn("Failed to fetch thread for shortcut, using the message alone", "error", err, "channel", shortcut.ChannelID)
+	}
+
+	var others []slack.Message
+	for _, message := range thread {
+		if message.TS == shortcut.MessageTS {
+			selected = message
+			continue
+		}
+		others = append(others, message)
+	}
+	if len(others) > maxShortcutThreadMessages {
+		others = append(others[:1], others[len(others)-maxShortcutThreadMessages+1:]...)
+	}
+
+	quoted := h.threadTranscript(ctx, client, shortcut.ChannelID, []slack.Message{selected})
+	if quoted == "" {
+		return "", false
+	}
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "I'm asking about this Slack message:\n\n> %s\n", strings.ReplaceAll(quoted, "\n", "\n> "))
+	if transcript := h.threadTranscript(ctx, client, shortcut.ChannelID, others); transcript != "" {
+		fmt.Fprintf(&prompt, "\nThe other messages in its thread, in order:\n\n%s\n", transcript)
+	}
+	fmt.Fprintf(&prompt, "\n%s", question)
+
+	return prompt.String(), true
+}
+
+// respondEphemeral sends the user a short ephemeral message through an
+// interaction's response_url
+func (h *Handler) respondEphemeral(ctx context.Context, client *slack.Client, responseURL, text string) {
+	if responseURL == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := client.RespondToURL(ctx, responseURL, ephemeral(text)); err != nil {
+		h.logger.Error("Failed to respond to interaction", "error", err)
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/policy"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+func TestShortcutBroadcasts(t *testing.T) {
+	// conversations.info for a public channel C_PUBLIC and a private C_PRIVATE
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("channel") == "C_PRIVATE" {
+			w.Write([]byte(`{"ok":true,"channel":{"id":"C_PRIVATE","is_private":true}}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"channel":{"id":"C_PUBLIC","is_private":false}}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := slack.NewClient(server.URL, "xoxb-test", logger)
+	noBroadcast := false
+	policies := policy.New(policy.File{Channels: map[string]policy.Settings{"C_QUIET": {Broadcast: &noBroadcast}}})
+
+	tests := []struct {
+		name       string
+		channel    string
+		inThread   bool
+		directMsgs bool
+		want       bool
+	}{
+		{name: "public channel thread", channel: "C_PUBLIC", inThread: true, want: true},
+		{name: "ephemeral answer", channel: "C_PUBLIC", inThread: false, want: false},
+		{name: "private channel", channel: "C_PRIVATE", inThread: true, want: false},
+		{name: "channel policy turns broadcast off", channel: "C_QUIET", inThread: true, want: false},
+		{name: "direct message", channel: "D1", inThread: true, want: false},
+		{name: "direct message with broadcasts on", channel: "D1", inThread: true, directMsgs: true, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{policies: policies, broadcastDirectMessages: tt.directMsgs, logger: logger}
+			shortcut := shortcutContext{ChannelID: tt.channel, MessageTS: "1.1", IsDirectMessage: slack.IsDirectMessage(tt.channel)}
+
+			got := h.shortcutBroadcasts(context.Background(), client, shortcut, policies.For(tt.channel), tt.inThread, "wv-test")
+			if got != tt.want {
+				t.Errorf("shortcutBroadcasts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
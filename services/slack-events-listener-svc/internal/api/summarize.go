@@ -0,0 +1,355 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/BitwaveCorp/shared-svcs/shared/mrkdwn"
+	"github.com/BitwaveCorp/shared-svcs/shared/utils/idgen"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/activity"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/answers"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+// summarizeQuestion is recorded as the question of a summary, for feedback
+// and the Home tab
+const summarizeQuestion = "Summarize this thread"
+
+// summaryCommands are the words that, leading a mention, ask for a summary
+// rather than an answer
+var summaryCommands = map[string]bool{
+	"summarize": true,
+	"summarise": true,
+	"tldr":      true,
+	"tl;dr":     true,
+}
+
+// summaryPhrases are whole mentions that ask for a summary without leading
+// with a command word
+var summaryPhrases = map[string]bool{
+	"can you summarize this thread": true,
+	"can you summarise this thread": true,
+	"please summarize this thread":  true,
+	"please summarise this thread":  true,
+	"give me a summary":             true,
+	"can i get a summary":           true,
+	"can you give me a summary":     true,
+	"summary please":                true,
+}
+
+// summaryOrderingKey picks the worker queue for summarizing a thread.
+// Summaries neither read nor change the thread's conversation history, so
+// they don't wait behind, or hold up, questions in the thread.
+func summaryOrderingKey(threadTS string) string {
+	return "summary:" + threadTS
+}
+
+var (
+	errThreadUnreadable = errors.New("thread can't be read")
+	errThreadEmpty      = errors.New("thread has nothing to summarize")
+)
+
+// isSummarizeRequest reports whether a mention in a thread asks Wavie to
+// summarize it
+func isSummarizeRequest(eventReq slack.EventRequest) bool {
+	if eventReq.Event.ThreadTS == "" {
+		return false
+	}
+	return asksForSummary(slack.PlainText(context.Background(), eventReq.Event.Text, botUserID(eventReq), nil))
+}
+
+// asksForSummary reports whether a mention, once Wavie's own mention is
+// removed, starts with a summary command ("summarize the decisions", "tl;dr")
+// or is one of the summaryPhrases ("can I get a summary?"). A question that
+// merely mentions a summary, such as "what's in the Q3 summary report?", is
+// answered as usual.
+func asksForSummary(text string) bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ';'
+	})
+	if len(words) == 0 {
+		return false
+	}
+	return summaryCommands[words[0]] || summaryPhrases[strings.Join(words, " ")]
+}
+
+// summarizeMention posts a summary of the thread Wavie was asked to
+// summarize in, or privately when the channel wants ephemeral replies
+func (h *Handler) summarizeMention(client *slack.Client, eventReq slack.EventRequest) {
+	ctx := context.Background()
+	event := eventReq.Event
+
+	correlationID, err := idgen.GenerateId("wv", 16)
+	if err != nil {
+		h.logger.Error("Failed to generate correlation ID", "error", err)
+		return
+	}
+
+	h.logger.Info("Processing summarize request",
+		"correlation_id", correlationID,
+		"user", event.User,
+		"channel", event.Channel,
+		"thread_id", event.ThreadTS)
+
+	scopes := questionScopes(event.User, event.Channel, eventReq.TeamID)
+	if decision := h.limiter.Allow(scopes...); !decision.Allowed {
+		h.declineLimited(client, eventReq, decision)
+		return
+	}
+
+	channelPolicy := h.policies.For(event.Channel)
+	var reply *placeholder
+	if channelPolicy.Ephemeral {
+		reply = h.ephemeralReply(client, event.Channel, event.ThreadTS, event.User, correlationID)
+	} else {
+		reply = h.postPlaceholder(client, event.Channel, event.ThreadTS, correlationID)
+	}
+
+	summaryResp, messages, err := h.summarizeThread(ctx, client, event.Channel, event.ThreadTS, event.TS, event.User, correlationID)
+	if err != nil {
+		h.logger.Error("Failed to summarize thread", "error", err, "correlation_id", correlationID)
+		reply.fail(ctx, summaryFailureMessage(err))
+		return
+	}
+
+	h.limiter.RecordTokens(summaryResp.InputTokens+summaryResp.OutputTokens, scopes...)
+
+	summary := summaryMarkdown(summaryResp.Summary, messages)
+	text := mrkdwn.Render(summary)
+	metadata := slack.AnswerMetadata{
+		CorrelationID: correlationID,
+		Model:         summaryResp.Model,
+		PromptVersion: summaryResp.PromptVersion,
+	}
+	answerTSs, err := reply.finishAll(ctx, slack.AnswerMessages(event.Channel, text, event.ThreadTS, metadata))
+	if err != nil {
+		h.logger.Error("Failed to post summary to Slack", "error", err, "correlation_id", correlationID)
+	}
+
+	for _, answerTS := range answerTSs {
+		h.answerStore.Record(answers.Answer{
+			ChannelID:     event.Channel,
+			MessageTS:     answerTS,
+			ThreadTS:      event.ThreadTS,
+			CorrelationID: correlationID,
+			Model:         summaryResp.Model,
+			PromptVersion: summaryResp.PromptVersion,
+			Question:      summarizeQuestion,
+			Response:      summary,
+		})
+	}
+
+	// Ephemeral answers can't be linked to, so they aren't listed on the Home tab
+	if len(answerTSs) > 0 && !channelPolicy.Ephemeral {
+		h.activity.RecordQuestion(event.User, activity.Question{
+			ChannelID: event.Channel,
+			MessageTS: answerTSs[0],
+			ThreadTS:  event.ThreadTS,
+			Question:  summarizeQuestion,
+		})
+	}
+}
+
+// handleSummarizeCommand acknowledges /wavie summarize <message link> and
+// summarizes the linked thread asynchronously. Slash commands don't say which
+// thread they were sent from, so the thread is given as a link.
+func (h *Handler) handleSummarizeCommand(client *slack.Client, cmd slack.SlashCommand, link string) slack.CommandResponse {
+	if link == "" {
+		return ephemeral("Usage: `/wavie summarize <message link>`. Use *Copy link* on any message in the thread, or mention me with `summarize` in the thread itself.")
+	}
+
+	channel, threadTS, err := slack.ParsePermalink(link)
+	if err != nil {
+		return ephemeral("That doesn't look like a Slack message link. Use *Copy link* on a message in the thread and try again.")
+	}
+
+	// Wavie can read channels the user can't, so only threads in the channel
+	// the command was run in are summarized
+	if channel != cmd.ChannelID {
+		return ephemeral("I can only summarize threads in this channel. Run the command in the channel the thread is in.")
+	}
+
+	if !h.policies.For(channel).Allowed {
+		return ephemeral(notAllowedMessage)
+	}
+	scopes := questionScopes(cmd.UserID, channel, cmd.TeamID)
+	if decision := h.limiter.Allow(scopes...); !decision.Allowed {
+		h.logger.Info("Slash command refused by rate limit", "scope", decision.Scope.Kind, "reason", decision.Reason, "user", cmd.UserID)
+		return ephemeral(limitMessage(decision))
+	}
+
+	err = h.pool.Submit(summaryOrderingKey(threadTS),
+		func() { h.answerSummarizeCommand(client, cmd, channel, threadTS) },
+		func(ctx context.Context) { h.abandonCommand(ctx, client, cmd) })
+	if err != nil {
+		h.logger.Warn("Failed to queue slash command", "error", err, "user", cmd.UserID)
+		return ephemeral("I'm swamped right now. Please try again in a moment.")
+	}
+
+	return ephemeral("_Reading the thread…_")
+}
+
+// answerSummarizeCommand replies to /wavie summarize ephemerally through the
+// command's response_url
+func (h *Handler) answerSummarizeCommand(client *slack.Client, cmd slack.SlashCommand, channel, threadTS string) {
+	ctx := context.Background()
+
+	correlationID, err := idgen.GenerateId("wv", 16)
+	if err != nil {
+		h.logger.Error("Failed to generate correlation ID", "error", err)
+		return
+	}
+
+	var replies []slack.CommandResponse
+	summaryResp, messages, err := h.summarizeThread(ctx, client, channel, threadTS, "", cmd.UserID, correlationID)
+	if err != nil {
+		h.logger.Error("Failed to summarize thread", "error", err, "correlation_id", correlationID)
+		replies = []slack.CommandResponse{ephemeral(summaryFailureMessage(err))}
+	} else {
+		h.limiter.RecordTokens(summaryResp.InputTokens+summaryResp.OutputTokens, questionScopes(cmd.UserID, channel, cmd.TeamID)...)
+		replies = ephemeralAnswers(mrkdwn.Render(summaryMarkdown(summaryResp.Summary, messages)), correlationID)
+	}
+
+	// The first reply replaces "Reading the thread…"
+	replies[0].ReplaceOriginal = true
+	if err := respondAll(ctx, client, cmd.ResponseURL, replies); err != nil {
+		h.logger.Error("Failed to respond to slash command", "error", err, "correlation_id", correlationID)
+	}
+}
+
+// summarizeThread fetches every message of a thread, skipping skipTS (the
+// request to summarize it), and has the proxy summarize them. It also
+// returns how many messages were summarized.
+func (h *Handler) summarizeThread(ctx context.Context, client *slack.Client, channel, threadTS, skipTS, user, correlationID string) (*slack.SummarizeResponse, int, error) {
+	replies, err := client.GetThreadReplies(ctx, channel, threadTS)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %w", errThreadUnreadable, err)
+	}
+
+	var thread []slack.Message
+	for _, reply := range replies {
+		if reply.TS != skipTS {
+			thread = append(thread, reply)
+		}
+	}
+
+	messages := h.threadMessages(ctx, client, channel, thread)
+	if len(messages) == 0 {
+		return nil, 0, errThreadEmpty
+	}
+
+	summaryResp, err := h.callSummarizeService(slack.SummarizeRequest{
+		Messages:      messages,
+		UserID:        user,
+		ChannelID:     channel,
+		ThreadTS:      threadTS,
+		CorrelationID: correlationID,
+		Model:         h.policies.For(channel).Model,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if summaryResp.Error != "" {
+		return nil, 0, fmt.Errorf("summarization failed: %s", summaryResp.Error)
+	}
+
+	h.logger.Info("Summarized thread",
+		"correlation_id", correlationID,
+		"messages", len(messages),
+		"chunks", summaryResp.Chunks)
+	return summaryResp, len(messages), nil
+}
+
+// summaryFailureMessage tells the user why there is no summary
+func summaryFailureMessage(err error) string {
+	switch {
+	case errors.Is(err, errThreadUnreadable):
+		return "Sorry, I can't read that thread. Invite me to the channel and try again."
+	case errors.Is(err, errThreadEmpty):
+		return "There's nothing in this thread for me to summarize yet."
+	default:
+		return "Sorry, I couldn't summarize this thread right now."
+	}
+}
+
+// summaryMarkdown lays out a summary: the overview, then decisions, open
+// questions and owners of action items
+func summaryMarkdown(summary slack.Summary, messages int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Thread summary** (%d messages)\n\n", messages)
+	if summary.Overview != "" {
+		b.WriteString(summary.Overview + "\n\n")
+	}
+
+	list := func(title string, items []string) {
+		b.WriteString("**" + title + "**\n")
+		if len(items) == 0 {
+			b.WriteString("- _None_\n\n")
+			return
+		}
+		for _, item := range items {
+			b.WriteString("- " + item + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	list("Decisions", summary.Decisions)
+	list("Open questions", summary.OpenQuestions)
+
+	var owners []string
+	for _, item := range summary.ActionItems {
+		owner := item.Owner
+		if owner == "" {
+			owner = "_Unassigned_"
+		}
+		owners = append(owners, owner+": "+item.Task)
+	}
+	list("Owners", owners)
+
+	return strings.TrimSpace(b.String())
+}
+
+// callSummarizeService calls the proxy's summarization endpoint. Long threads
+// are summarized in several rounds, so it waits longer than an answer.
+func (h *Handler) callSummarizeService(req slack.SummarizeRequest) (*slack.SummarizeResponse, error) {
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal summarize request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", h.claudeProxyServiceURL+"/api/summarize", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create summarize request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.proxyClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call summarize service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("summarize service error: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var summaryResp slack.SummarizeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&summaryResp); err != nil {
+		return nil, fmt.Errorf("failed to decode summarize response: %w", err)
+	}
+
+	return &summaryResp, nil
+}
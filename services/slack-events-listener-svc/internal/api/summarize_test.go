@@ -0,0 +1,30 @@
+package api
+
+import "testing"
+
+func TestAsksForSummary(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"summarize", true},
+		{"Summarise this thread, please!", true},
+		{"summarize the decisions so far", true},
+		{"can you give me a summary?", true},
+		{"Can you summarize this thread?", true},
+		{"TL;DR", true},
+		{"tldr?", true},
+		{"what's in the Q3 summary report?", false},
+		{"can you summarize the Q3 report for me?", false},
+		{"where is the summary of the board meeting?", false},
+		{"what's the status of the migration?", false},
+		{"how do I sum these balances?", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := asksForSummary(tt.text); got != tt.want {
+			t.Errorf("asksForSummary(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// How long a thread Wavie isn't part of is remembered. Short, because
+	// another instance may answer a mention in it meanwhile.
+	quietThreadTTL = time.Minute
+
+	maxQuietThreads = 10000
+)
+
+// quietThreads remembers threads Wavie hasn't posted in, so every reply in a
+// busy thread doesn't call conversations.replies to find that out again
+type quietThreads struct {
+	mutex   sync.Mutex
+	expires map[string]time.Time
+}
+
+func newQuietThreads() *quietThreads {
+	return &quietThreads{expires: make(map[string]time.Time)}
+}
+
+func quietThreadKey(channel, threadTS string) string {
+	return channel + ":" + threadTS
+}
+
+// has reports whether the thread was found quiet within quietThreadTTL
+func (q *quietThreads) has(key string) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	expires, ok := q.expires[key]
+	if ok && time.Now().After(expires) {
+		delete(q.expires, key)
+		return false
+	}
+	return ok
+}
+
+// add remembers a quiet thread. When full, expired threads are dropped
+// first, then arbitrary ones.
+func (q *quietThreads) add(key string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := time.Now()
+	if len(q.expires) >= maxQuietThreads {
+		for k, expires := range q.expires {
+			if now.After(expires) {
+				delete(q.expires, k)
+			}
+		}
+		for k := range q.expires {
+			if len(q.expires) < maxQuietThreads {
+				break
+			}
+			delete(q.expires, k)
+		}
+	}
+	q.expires[key] = now.Add(quietThreadTTL)
+}
+
+// forget drops a thread Wavie has just been asked to join
+func (q *quietThreads) forget(key string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	delete(q.expires, key)
+}
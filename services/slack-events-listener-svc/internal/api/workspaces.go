@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+// eventWorkspace returns the organization and workspace whose installation
+// an event was delivered for. For org-wide installs teamID is empty.
+func eventWorkspace(eventReq slack.EventRequest) (enterpriseID, teamID string) {
+	if len(eventReq.Auths) > 0 {
+		auth := eventReq.Auths[0]
+		if auth.IsEnterpriseInstall {
+			return auth.EnterpriseID, ""
+		}
+		return auth.EnterpriseID, auth.TeamID
+	}
+	return eventReq.EnterpriseID, eventReq.TeamID
+}
+
+// eventClient returns the Web API client for the workspace an event came
+// from. It reports false, after logging, if Wavie has no token there.
+func (h *Handler) eventClient(ctx context.Context, eventReq slack.EventRequest) (*slack.Client, bool) {
+	enterpriseID, teamID := eventWorkspace(eventReq)
+	return h.clientFor(ctx, enterpriseID, teamID)
+}
+
+// clientFor returns the Web API client for a workspace. It reports false,
+// after logging, if Wavie has no token there.
+func (h *Handler) clientFor(ctx context.Context, enterpriseID, teamID string) (*slack.Client, bool) {
+	client, err := h.slackClients.ForTeam(ctx, enterpriseID, teamID)
+	if err != nil {
+		h.logger.Error("Failed to get Slack client for workspace", "error", err, "team_id", teamID, "enterprise_id", enterpriseID)
+		return nil, false
+	}
+	return client, true
+}
+
+// handleUninstall forgets a workspace's installation when Wavie is removed
+// from it or its bot token is revoked
+func (h *Handler) handleUninstall(eventReq slack.EventRequest) {
+	if h.installations == nil {
+		return
+	}
+	if eventReq.Event.Type == "tokens_revoked" && (eventReq.Event.Tokens == nil || len(eventReq.Event.Tokens.Bot) == 0) {
+		// Only user tokens were revoked; the bot token still works
+		return
+	}
+
+	enterpriseID, teamID := eventWorkspace(eventReq)
+	if err := h.installations.Delete(enterpriseID, teamID); err != nil {
+		h.logger.Error("Failed to delete installation", "error", err, "team_id", teamID, "enterprise_id", enterpriseID)
+		return
+	}
+
+	h.logger.Info("Wavie was uninstalled", "event_type", eventReq.Event.Type, "team_id", teamID, "enterprise_id", enterpriseID)
+}
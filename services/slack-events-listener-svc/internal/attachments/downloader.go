@@ -0,0 +1,82 @@
+// Package attachments fetches the files shared with a question so they can
+// be sent to Claude along with it.
+package attachments
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"mime"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+// supportedTypes are the file types the Messages API accepts: images, PDFs,
+// and plain text documents such as CSV exports
+var supportedTypes = map[string]bool{
+	"image/png":        true,
+	"image/jpeg":       true,
+	"image/gif":        true,
+	"image/webp":       true,
+	"application/pdf":  true,
+	"text/plain":       true,
+	"text/csv":         true,
+	"text/markdown":    true,
+	"application/json": true,
+}
+
+// Downloader downloads shared files with the workspace's bot token,
+// enforcing the number, size and type limits
+type Downloader struct {
+	maxFiles int
+	maxBytes int64
+	logger   *slog.Logger
+}
+
+// NewDownloader creates a downloader that accepts up to maxFiles files of up
+// to maxBytes each per message
+func NewDownloader(maxFiles int, maxBytes int64, logger *slog.Logger) *Downloader {
+	return &Downloader{
+		maxFiles: maxFiles,
+		maxBytes: maxBytes,
+		logger:   logger,
+	}
+}
+
+// Download fetches the files that can be sent to Claude with the client of
+// the workspace they were shared in. For each file that can't, it returns a
+// note saying why, so Claude can tell the user.
+func (d *Downloader) Download(ctx context.Context, client *slack.Client, files []slack.File) ([]slack.Attachment, []string) {
+	var attachments []slack.Attachment
+	var skipped []string
+
+	for _, file := range files {
+		mimeType, _, err := mime.ParseMediaType(file.Mimetype)
+		switch {
+		case len(attachments) >= d.maxFiles:
+			skipped = append(skipped, fmt.Sprintf("%s (only %d files are read per message)", file.Name, d.maxFiles))
+			continue
+		case err != nil || !supportedTypes[mimeType]:
+			skipped = append(skipped, fmt.Sprintf("%s (unsupported file type)", file.Name))
+			continue
+		case file.Size > d.maxBytes:
+			skipped = append(skipped, fmt.Sprintf("%s (larger than %d MB)", file.Name, d.maxBytes>>20))
+			continue
+		}
+
+		data, err := client.DownloadFile(ctx, file.URLPrivateDownload, d.maxBytes)
+		if err != nil {
+			d.logger.Error("Failed to download attachment", "error", err, "file_id", file.ID)
+			skipped = append(skipped, fmt.Sprintf("%s (couldn't be downloaded)", file.Name))
+			continue
+		}
+
+		attachments = append(attachments, slack.Attachment{
+			Name:     file.Name,
+			MimeType: mimeType,
+			Data:     data,
+		})
+	}
+
+	return attachments, skipped
+}
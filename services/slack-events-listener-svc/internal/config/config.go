@@ -1,12 +1,114 @@
 package config
 
+import "time"
+
 type Config struct {
 	LogLevel string `envconfig:"LOG_LEVEL" default:"info"`
 	Port     int    `envconfig:"PORT" default:"8080"`
 
-	SlackBotToken      string `envconfig:"SLACK_BOT_TOKEN" required:"true"`
-	SlackSigningSecret string `envconfig:"SLACK_SIGNING_SECRET" required:"true"`
+	// Bot token of the workspace Wavie was first set up in, its home
+	// workspace; used for it when it has no OAuth installation. Optional once
+	// every workspace installs through /slack/install.
+	SlackBotToken      string `envconfig:"SLACK_BOT_TOKEN"`
+	SlackSigningSecret string `envconfig:"SLACK_SIGNING_SECRET"` // required in http mode
+
+	// The home workspace's ID; looked up from SLACK_BOT_TOKEN when empty
+	SlackTeamID string `envconfig:"SLACK_TEAM_ID"`
+
+	// OAuth install flow (/slack/install); disabled when the client ID is empty
+	SlackClientID         string `envconfig:"SLACK_CLIENT_ID"`
+	SlackClientSecret     string `envconfig:"SLACK_CLIENT_SECRET"`
+	SlackOAuthRedirectURL string `envconfig:"SLACK_OAUTH_REDIRECT_URL"` // https://<listener>/slack/oauth/callback
+	SlackOAuthScopes      string `envconfig:"SLACK_OAUTH_SCOPES" default:"app_mentions:read,channels:history,channels:read,chat:write,commands,files:read,groups:history,groups:read,im:history,im:write,reactions:read,users:read"`
+
+	// Installed workspaces' bot tokens: "memory" or "bolt", encrypted with a
+	// base64 encoded 32-byte key
+	InstallationStoreBackend  string `envconfig:"INSTALLATION_STORE_BACKEND" default:"bolt"`
+	InstallationStorePath     string `envconfig:"INSTALLATION_STORE_PATH" default:"installations.db"`
+	InstallationEncryptionKey string `envconfig:"INSTALLATION_ENCRYPTION_KEY"`
+
+	// How Slack delivers events: "http" (public /slack/* endpoints) or "socket" (Socket Mode)
+	SlackTransport  string `envconfig:"SLACK_TRANSPORT" default:"http"`
+	SlackAppToken   string `envconfig:"SLACK_APP_TOKEN"` // xapp- token, required in socket mode
+	SlackAPIBaseURL string `envconfig:"SLACK_API_BASE_URL" default:"https://slack.com/api"` // Web API, for every Slack call
 
 	ClaudeProxyServiceURL  string `envconfig:"CLAUDE_PROXY_SERVICE_URL" required:"true"`
 	BroadcastServiceURL string `envconfig:"BROADCAST_SERVICE_URL" required:"true"`
+
+	// How long user and channel names resolved for mentions are cached
+	NameCacheTTL time.Duration `envconfig:"NAME_CACHE_TTL" default:"1h"`
+
+	// Files shared with a question are sent to Claude: at most this many per
+	// message, each at most this size (Claude takes images up to 5 MB)
+	MaxAttachments     int   `envconfig:"MAX_ATTACHMENTS" default:"5"`
+	MaxAttachmentBytes int64 `envconfig:"MAX_ATTACHMENT_BYTES" default:"5242880"`
+
+	// Stream answers from the Claude proxy into Slack as they are generated
+	ClaudeStreaming bool `envconfig:"CLAUDE_STREAMING" default:"true"`
+
+	// JSON file with per-channel behaviour: allow/deny lists, broadcasting,
+	// persona, model and ephemeral replies; empty means the same everywhere
+	ChannelPolicyPath string `envconfig:"CHANNEL_POLICY_PATH"`
+
+	// Whether questions and answers from direct messages are sent to the broadcast channel
+	BroadcastDirectMessages bool `envconfig:"BROADCAST_DIRECT_MESSAGES" default:"true"`
+
+	// The broadcast channel is in the home workspace, so by default only its
+	// questions and feedback are sent there
+	BroadcastAllWorkspaces bool `envconfig:"BROADCAST_ALL_WORKSPACES" default:"false"`
+
+	// Durable outbox for broadcast and feedback delivery
+	OutboxPath        string `envconfig:"OUTBOX_PATH" default:"outbox.db"`
+	OutboxMaxAttempts int    `envconfig:"OUTBOX_MAX_ATTEMPTS" default:"10"`
+
+	// Bearer token for /admin endpoints; they are disabled when empty
+	AdminToken string `envconfig:"ADMIN_TOKEN"`
+
+	// Async processing: a fixed set of workers with a bounded queue; on
+	// shutdown queued work gets this long to finish
+	WorkerCount         int           `envconfig:"WORKER_COUNT" default:"8"`
+	WorkerQueueSize     int           `envconfig:"WORKER_QUEUE_SIZE" default:"100"`
+	ShutdownGracePeriod time.Duration `envconfig:"SHUTDOWN_GRACE_PERIOD" default:"10s"`
+
+	// Rate limits and daily quotas per user, channel and workspace; 0 turns a
+	// limit off. State is kept in "memory" or "bolt", per instance, or in
+	// "redis", shared by every instance using the same server.
+	RateLimitBackend       string  `envconfig:"RATE_LIMIT_BACKEND" default:"memory"`
+	RateLimitPath          string  `envconfig:"RATE_LIMIT_PATH" default:"ratelimit.db"`
+	RateLimitRedisURL      string  `envconfig:"RATE_LIMIT_REDIS_URL"`
+	UserRatePerMinute      float64 `envconfig:"USER_RATE_PER_MINUTE" default:"5"`
+	UserRateBurst          int     `envconfig:"USER_RATE_BURST" default:"10"`
+	UserDailyRequests      int     `envconfig:"USER_DAILY_REQUESTS" default:"200"`
+	UserDailyTokens        int     `envconfig:"USER_DAILY_TOKENS" default:"0"`
+	ChannelRatePerMinute   float64 `envconfig:"CHANNEL_RATE_PER_MINUTE" default:"0"`
+	ChannelRateBurst       int     `envconfig:"CHANNEL_RATE_BURST" default:"0"`
+	ChannelDailyRequests   int     `envconfig:"CHANNEL_DAILY_REQUESTS" default:"0"`
+	ChannelDailyTokens     int     `envconfig:"CHANNEL_DAILY_TOKENS" default:"0"`
+	WorkspaceRatePerMinute float64 `envconfig:"WORKSPACE_RATE_PER_MINUTE" default:"0"`
+	WorkspaceRateBurst     int     `envconfig:"WORKSPACE_RATE_BURST" default:"0"`
+	WorkspaceDailyRequests int     `envconfig:"WORKSPACE_DAILY_REQUESTS" default:"0"`
+	WorkspaceDailyTokens   int     `envconfig:"WORKSPACE_DAILY_TOKENS" default:"0"`
+
+	// Event deduplication: "memory" or "bolt"; TTL should cover Slack's retry window
+	DedupBackend string        `envconfig:"DEDUP_BACKEND" default:"memory"`
+	DedupPath    string        `envconfig:"DEDUP_PATH" default:"dedup.db"`
+	DedupTTL     time.Duration `envconfig:"DEDUP_TTL" default:"1h"`
+
+	// Posted answers, so feedback can be linked to the question and answer:
+	// "memory" or "bolt"; feedback on answers older than the retention is ignored
+	AnswerStoreBackend string        `envconfig:"ANSWER_STORE_BACKEND" default:"memory"`
+	AnswerStorePath    string        `envconfig:"ANSWER_STORE_PATH" default:"answers.db"`
+	AnswerRetention    time.Duration `envconfig:"ANSWER_RETENTION" default:"720h"`
+
+	// Each user's recent questions and feedback, shown on their App Home tab:
+	// "memory" or "bolt"; users quiet for longer than the retention are forgotten
+	ActivityStoreBackend string        `envconfig:"ACTIVITY_STORE_BACKEND" default:"memory"`
+	ActivityStorePath    string        `envconfig:"ACTIVITY_STORE_PATH" default:"activity.db"`
+	ActivityRetention    time.Duration `envconfig:"ACTIVITY_RETENTION" default:"720h"`
+
+	// Conversation history storage: "memory" or "bolt" (embedded on-disk database)
+	ConversationStoreBackend string        `envconfig:"CONVERSATION_STORE_BACKEND" default:"memory"`
+	ConversationStorePath    string        `envconfig:"CONVERSATION_STORE_PATH" default:"conversations.db"`
+	ConversationMaxMessages  int           `envconfig:"CONVERSATION_MAX_MESSAGES" default:"20"`
+	ConversationMaxAge       time.Duration `envconfig:"CONVERSATION_MAX_AGE" default:"1h"`
 }
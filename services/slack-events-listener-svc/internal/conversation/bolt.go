@@ -0,0 +1,82 @@
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BitwaveCorp/shared-svcs/shared/boltbucket"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltBackend stores conversation contexts in an embedded bbolt database so
+// history survives restarts. Point the path at a mounted volume to keep it
+// across revisions; only one instance can have the file open at a time.
+type BoltBackend struct {
+	bucket *boltbucket.Bucket
+}
+
+// NewBoltBackend opens (or creates) the database file at path
+func NewBoltBackend(path string) (*BoltBackend, error) {
+	bucket, err := boltbucket.Open(path, "conversations")
+	if err != nil {
+		return nil, err
+	}
+	return &BoltBackend{bucket: bucket}, nil
+}
+
+func (b *BoltBackend) Get(threadID string) (*ConversationContext, error) {
+	var context *ConversationContext
+	err := b.bucket.View(func(bucket *bolt.Bucket) error {
+		data := bucket.Get([]byte(threadID))
+		if data == nil {
+			return nil
+		}
+		context = &ConversationContext{}
+		return json.Unmarshal(data, context)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conversation: %w", err)
+	}
+	return context, nil
+}
+
+func (b *BoltBackend) Put(context *ConversationContext) error {
+	data, err := json.Marshal(context)
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversation: %w", err)
+	}
+
+	err = b.bucket.Update(func(bucket *bolt.Bucket) error {
+		return bucket.Put([]byte(context.ThreadID), data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write conversation: %w", err)
+	}
+	return nil
+}
+
+func (b *BoltBackend) Delete(threadID string) error {
+	err := b.bucket.Update(func(bucket *bolt.Bucket) error {
+		return bucket.Delete([]byte(threadID))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete conversation: %w", err)
+	}
+	return nil
+}
+
+func (b *BoltBackend) DeleteExpired(cutoff time.Time) (int, error) {
+	removed, err := b.bucket.DeleteIf(func(_, data []byte) bool {
+		var context ConversationContext
+		return json.Unmarshal(data, &context) != nil || context.LastAccessed.Before(cutoff)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired conversations: %w", err)
+	}
+	return removed, nil
+}
+
+func (b *BoltBackend) Close() error {
+	return b.bucket.Close()
+}
@@ -0,0 +1,73 @@
+package conversation
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryBackend keeps conversation contexts in an in-process map. History is
+// lost on restart and is not shared between instances.
+type MemoryBackend struct {
+	conversations map[string]*ConversationContext
+	mutex         sync.RWMutex
+}
+
+// NewMemoryBackend creates an empty in-memory backend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		conversations: make(map[string]*ConversationContext),
+	}
+}
+
+func (b *MemoryBackend) Get(threadID string) (*ConversationContext, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	context, exists := b.conversations[threadID]
+	if !exists {
+		return nil, nil
+	}
+	return copyContext(context), nil
+}
+
+func (b *MemoryBackend) Put(context *ConversationContext) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.conversations[context.ThreadID] = copyContext(context)
+	return nil
+}
+
+func (b *MemoryBackend) Delete(threadID string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.conversations, threadID)
+	return nil
+}
+
+func (b *MemoryBackend) DeleteExpired(cutoff time.Time) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	removed := 0
+	for threadID, context := range b.conversations {
+		if context.LastAccessed.Before(cutoff) {
+			delete(b.conversations, threadID)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (b *MemoryBackend) Close() error {
+	return nil
+}
+
+// copyContext returns a copy that does not share its message slice with the
+// original, so callers can't mutate stored history behind the backend's lock.
+func copyContext(context *ConversationContext) *ConversationContext {
+	clone := *context
+	clone.Messages = append([]Message(nil), context.Messages...)
+	return &clone
+}
@@ -1,6 +1,8 @@
 package conversation
 
 import (
+	"fmt"
+	"log/slog"
 	"sync"
 	"time"
 )
@@ -19,20 +21,53 @@ type ConversationContext struct {
 	LastAccessed time.Time `json:"last_accessed"`
 }
 
+// Backend persists conversation contexts. Implementations must be safe for
+// concurrent use; the Store serializes read-modify-write cycles on top of it.
+type Backend interface {
+	// Get returns the context for a thread, or nil if none is stored.
+	Get(threadID string) (*ConversationContext, error)
+	// Put creates or replaces the context for context.ThreadID.
+	Put(context *ConversationContext) error
+	// Delete removes the context for a thread, if any.
+	Delete(threadID string) error
+	// DeleteExpired removes contexts last accessed before cutoff and returns
+	// how many were removed.
+	DeleteExpired(cutoff time.Time) (int, error)
+	// Close closes the conversation database, if any.
+	Close() error
+}
+
+// OpenBackend opens the conversation store for kind ("memory", the default,
+// or "bolt"); path is the bolt database file.
+func OpenBackend(kind, path string) (Backend, error) {
+	switch kind {
+	case "", "memory":
+		return NewMemoryBackend(), nil
+	case "bolt":
+		return NewBoltBackend(path)
+	default:
+		return nil, fmt.Errorf("unknown conversation store backend: %q", kind)
+	}
+}
+
 // Store manages conversation contexts with thread-based storage
 type Store struct {
-	conversations map[string]*ConversationContext
-	mutex         sync.RWMutex
-	maxMessages   int
-	maxAge        time.Duration
+	backend     Backend
+	mutex       sync.Mutex
+	maxMessages int
+	maxAge      time.Duration
+	logger      *slog.Logger
+	done        chan struct{}
 }
 
 // NewStore creates a new conversation store with specified limits
-func NewStore(maxMessages int, maxAge time.Duration) *Store {
+func NewStore(backend Backend, maxMessages int, maxAge time.Duration, logger *slog.Logger) *Store {
 	store := &Store{
-		conversations: make(map[string]*ConversationContext),
-		maxMessages:   maxMessages,
-		maxAge:        maxAge,
+		backend:     backend,
+		maxMessages: maxMessages,
+		maxAge:      maxAge,
+		logger:      logger,
+		done:        make(chan struct{}),
 	}
 
 	// Start cleanup routine
@@ -46,32 +81,20 @@ func (s *Store) GetOrCreate(threadID string) *ConversationContext {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	context, exists := s.conversations[threadID]
-	if !exists {
-		context = &ConversationContext{
-			ThreadID:     threadID,
-			Messages:     []Message{},
-			LastAccessed: time.Now(),
-		}
-		s.conversations[threadID] = context
-	}
-
-	// Check if context is too old
-	if time.Since(context.LastAccessed) > s.maxAge {
-		context.Messages = []Message{} // Reset if older than max age
+	context := s.load(threadID)
+	if err := s.backend.Put(context); err != nil {
+		s.logger.Error("Failed to save conversation", "error", err, "thread_id", threadID)
 	}
-
-	context.LastAccessed = time.Now()
 	return context
 }
 
 // AddMessage adds a message to a conversation context
 func (s *Store) AddMessage(threadID, role, content string) {
-	context := s.GetOrCreate(threadID)
-
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	context := s.load(threadID)
+
 	// Add new message
 	context.Messages = append(context.Messages, Message{
 		Role:      role,
@@ -83,15 +106,49 @@ func (s *Store) AddMessage(threadID, role, content string) {
 	if len(context.Messages) > s.maxMessages {
 		context.Messages = context.Messages[len(context.Messages)-s.maxMessages:]
 	}
+
+	if err := s.backend.Put(context); err != nil {
+		s.logger.Error("Failed to save conversation", "error", err, "thread_id", threadID)
+	}
+}
+
+// Seed replaces the history of a thread with messages recovered from another
+// source, keeping their original timestamps and the max messages limit
+func (s *Store) Seed(threadID string, messages []Message) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(messages) > s.maxMessages {
+		messages = messages[len(messages)-s.maxMessages:]
+	}
+
+	context := &ConversationContext{
+		ThreadID:     threadID,
+		Messages:     append([]Message{}, messages...),
+		LastAccessed: time.Now(),
+	}
+
+	if err := s.backend.Put(context); err != nil {
+		s.logger.Error("Failed to save conversation", "error", err, "thread_id", threadID)
+	}
+}
+
+// Delete removes all history for a thread
+func (s *Store) Delete(threadID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.backend.Delete(threadID)
 }
 
 // GetMessages returns all messages for a thread, or empty slice if not found or expired
 func (s *Store) GetMessages(threadID string) []Message {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-
-	context, exists := s.conversations[threadID]
-	if !exists {
+	context, err := s.backend.Get(threadID)
+	if err != nil {
+		s.logger.Error("Failed to load conversation", "error", err, "thread_id", threadID)
+		return []Message{}
+	}
+	if context == nil {
 		return []Message{}
 	}
 
@@ -103,24 +160,59 @@ func (s *Store) GetMessages(threadID string) []Message {
 	return context.Messages
 }
 
+// Close stops the cleanup routine and closes the backend
+func (s *Store) Close() error {
+	close(s.done)
+	return s.backend.Close()
+}
+
+// load fetches the context for a thread, creating it or resetting it when it
+// is older than maxAge, and marks it as accessed. Callers must hold s.mutex.
+func (s *Store) load(threadID string) *ConversationContext {
+	context, err := s.backend.Get(threadID)
+	if err != nil {
+		s.logger.Error("Failed to load conversation", "error", err, "thread_id", threadID)
+	}
+	if context == nil {
+		context = &ConversationContext{
+			ThreadID:     threadID,
+			Messages:     []Message{},
+			LastAccessed: time.Now(),
+		}
+	}
+
+	// Check if context is too old
+	if time.Since(context.LastAccessed) > s.maxAge {
+		context.Messages = []Message{} // Reset if older than max age
+	}
+
+	context.LastAccessed = time.Now()
+	return context
+}
+
 // cleanupRoutine periodically removes old conversations
 func (s *Store) cleanupRoutine() {
 	ticker := time.NewTicker(15 * time.Minute)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		s.cleanup()
+	for {
+		select {
+		case <-ticker.C:
+			s.cleanup()
+		case <-s.done:
+			return
+		}
 	}
 }
 
 // cleanup removes conversations older than maxAge
 func (s *Store) cleanup() {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	for threadID, context := range s.conversations {
-		if time.Since(context.LastAccessed) > s.maxAge {
-			delete(s.conversations, threadID)
-		}
+	removed, err := s.backend.DeleteExpired(time.Now().Add(-s.maxAge))
+	if err != nil {
+		s.logger.Error("Failed to clean up conversations", "error", err)
+		return
+	}
+	if removed > 0 {
+		s.logger.Info("Removed expired conversations", "count", removed)
 	}
 }
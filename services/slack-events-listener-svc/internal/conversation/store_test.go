@@ -0,0 +1,175 @@
+package conversation
+
+import (
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// backends returns a fresh backend of every kind
+func backends(t *testing.T) map[string]Backend {
+	t.Helper()
+	bolt, err := NewBoltBackend(filepath.Join(t.TempDir(), "conversations.db"))
+	if err != nil {
+		t.Fatalf("failed to open bolt backend: %v", err)
+	}
+	t.Cleanup(func() { bolt.Close() })
+
+	return map[string]Backend{
+		"memory": NewMemoryBackend(),
+		"bolt":   bolt,
+	}
+}
+
+func newTestStore(t *testing.T, backend Backend, maxMessages int) *Store {
+	t.Helper()
+	store := NewStore(backend, maxMessages, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// contents returns the role and content of each message
+func contents(messages []Message) []string {
+	var out []string
+	for _, message := range messages {
+		out = append(out, message.Role+": "+message.Content)
+	}
+	return out
+}
+
+func TestAddMessageTrims(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newTestStore(t, backend, 3)
+
+			store.AddMessage("T1", "user", "one")
+			store.AddMessage("T1", "assistant", "two")
+			store.AddMessage("T2", "user", "other thread")
+			store.AddMessage("T1", "user", "three")
+			store.AddMessage("T1", "assistant", "four")
+
+			want := []string{"assistant: two", "user: three", "assistant: four"}
+			if got := contents(store.GetMessages("T1")); !slices.Equal(got, want) {
+				t.Errorf("T1 history = %q, want the latest 3: %q", got, want)
+			}
+			if got := contents(store.GetMessages("T2")); !slices.Equal(got, []string{"user: other thread"}) {
+				t.Errorf("T2 history = %q, want its one message", got)
+			}
+			if got := store.GetMessages("T3"); len(got) != 0 {
+				t.Errorf("unknown thread has history %q", contents(got))
+			}
+		})
+	}
+}
+
+func TestSeed(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newTestStore(t, backend, 2)
+			asked := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+
+			store.AddMessage("T1", "user", "replaced")
+			store.Seed("T1", []Message{
+				{Role: "user", Content: "first", Timestamp: asked},
+				{Role: "assistant", Content: "second", Timestamp: asked},
+				{Role: "user", Content: "third", Timestamp: asked},
+			})
+
+			messages := store.GetMessages("T1")
+			if got, want := contents(messages), []string{"assistant: second", "user: third"}; !slices.Equal(got, want) {
+				t.Fatalf("seeded history = %q, want %q", got, want)
+			}
+			if !messages[0].Timestamp.Equal(asked) {
+				t.Errorf("seeded message timestamp = %v, want %v", messages[0].Timestamp, asked)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newTestStore(t, backend, 10)
+
+			store.AddMessage("T1", "user", "question")
+			store.AddMessage("T2", "user", "kept")
+			if err := store.Delete("T1"); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if err := store.Delete("missing"); err != nil {
+				t.Errorf("Delete of an unknown thread failed: %v", err)
+			}
+
+			if got := store.GetMessages("T1"); len(got) != 0 {
+				t.Errorf("deleted thread still has history %q", contents(got))
+			}
+			if got := store.GetMessages("T2"); len(got) != 1 {
+				t.Errorf("other thread has %d messages after delete, want 1", len(got))
+			}
+		})
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newTestStore(t, backend, 10)
+			stale := time.Now().Add(-2 * time.Hour)
+
+			for _, threadID := range []string{"stale", "stale-reused"} {
+				err := backend.Put(&ConversationContext{
+					ThreadID:     threadID,
+					Messages:     []Message{{Role: "user", Content: "old", Timestamp: stale}},
+					LastAccessed: stale,
+				})
+				if err != nil {
+					t.Fatalf("Put failed: %v", err)
+				}
+			}
+			store.AddMessage("fresh", "user", "new")
+
+			if got := store.GetMessages("stale"); len(got) != 0 {
+				t.Errorf("history older than the max age was returned: %q", contents(got))
+			}
+
+			// A thread picked up again starts over
+			store.AddMessage("stale-reused", "user", "again")
+			if got := contents(store.GetMessages("stale-reused")); !slices.Equal(got, []string{"user: again"}) {
+				t.Errorf("reused stale thread history = %q, want only the new message", got)
+			}
+
+			store.cleanup()
+			if context, err := backend.Get("stale"); err != nil || context != nil {
+				t.Errorf("stale thread after cleanup = %+v, %v; want removed", context, err)
+			}
+			for _, threadID := range []string{"fresh", "stale-reused"} {
+				if context, err := backend.Get(threadID); err != nil || context == nil {
+					t.Errorf("recent thread %q after cleanup = %+v, %v; want kept", threadID, context, err)
+				}
+			}
+		})
+	}
+}
+
+func TestGetReturnsCopy(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newTestStore(t, backend, 10)
+			store.AddMessage("T1", "user", "question")
+
+			context, err := backend.Get("T1")
+			if err != nil || context == nil {
+				t.Fatalf("Get = %+v, %v", context, err)
+			}
+			context.Messages[0].Content = "changed"
+			context.Messages = append(context.Messages, Message{Role: "user", Content: "added"})
+
+			if got := contents(store.GetMessages("T1")); !slices.Equal(got, []string{"user: question"}) {
+				t.Errorf("stored history = %q after changing a copy", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package installations
+
+import (
+	"fmt"
+
+	"github.com/BitwaveCorp/shared-svcs/shared/boltbucket"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltBackend stores installations in an embedded bbolt database
+type BoltBackend struct {
+	bucket *boltbucket.Bucket
+}
+
+// NewBoltBackend opens (or creates) the database file at path
+func NewBoltBackend(path string) (*BoltBackend, error) {
+	bucket, err := boltbucket.Open(path, "installations")
+	if err != nil {
+		return nil, err
+	}
+	return &BoltBackend{bucket: bucket}, nil
+}
+
+func (b *BoltBackend) Get(key string) ([]byte, error) {
+	var data []byte
+	err := b.bucket.View(func(bucket *bolt.Bucket) error {
+		// Copy out; the slice is only valid during the transaction
+		if value := bucket.Get([]byte(key)); value != nil {
+			data = append([]byte(nil), value...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read installation: %w", err)
+	}
+	return data, nil
+}
+
+func (b *BoltBackend) Put(key string, data []byte) error {
+	err := b.bucket.Update(func(bucket *bolt.Bucket) error {
+		return bucket.Put([]byte(key), data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write installation: %w", err)
+	}
+	return nil
+}
+
+func (b *BoltBackend) Delete(key string) error {
+	err := b.bucket.Update(func(bucket *bolt.Bucket) error {
+		return bucket.Delete([]byte(key))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete installation: %w", err)
+	}
+	return nil
+}
+
+func (b *BoltBackend) Close() error {
+	return b.bucket.Close()
+}
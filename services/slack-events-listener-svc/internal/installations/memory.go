@@ -0,0 +1,44 @@
+package installations
+
+import "sync"
+
+// MemoryBackend keeps installations in an in-process map. They are lost on
+// restart, so workspaces have to install Wavie again; use it for development.
+type MemoryBackend struct {
+	items map[string][]byte
+	mutex sync.RWMutex
+}
+
+// NewMemoryBackend creates an empty in-memory backend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		items: make(map[string][]byte),
+	}
+}
+
+func (b *MemoryBackend) Get(key string) ([]byte, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return b.items[key], nil
+}
+
+func (b *MemoryBackend) Put(key string, data []byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.items[key] = data
+	return nil
+}
+
+func (b *MemoryBackend) Delete(key string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.items, key)
+	return nil
+}
+
+func (b *MemoryBackend) Close() error {
+	return nil
+}
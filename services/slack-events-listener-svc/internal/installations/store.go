@@ -0,0 +1,225 @@
+// Package installations keeps the bot tokens of the workspaces and Enterprise
+// Grid organizations Wavie is installed in, encrypted at rest.
+package installations
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Installation is the result of one workspace (or, for org-wide installs,
+// one Enterprise Grid organization) installing Wavie
+type Installation struct {
+	TeamID              string    `json:"team_id,omitempty"`
+	TeamName            string    `json:"team_name,omitempty"`
+	EnterpriseID        string    `json:"enterprise_id,omitempty"`
+	EnterpriseName      string    `json:"enterprise_name,omitempty"`
+	IsEnterpriseInstall bool      `json:"is_enterprise_install"`
+	AppID               string    `json:"app_id"`
+	BotUserID           string    `json:"bot_user_id"`
+	BotToken            string    `json:"bot_token"`
+	Scope               string    `json:"scope"`
+	InstalledBy         string    `json:"installed_by"`
+	InstalledAt         time.Time `json:"installed_at"`
+}
+
+// Key is the key the installation is stored under: the organization for
+// org-wide installs, otherwise the workspace
+func (i *Installation) Key() string {
+	if i.IsEnterpriseInstall {
+		return enterpriseKey(i.EnterpriseID)
+	}
+	return teamKey(i.TeamID)
+}
+
+func teamKey(teamID string) string {
+	return "team:" + teamID
+}
+
+func enterpriseKey(enterpriseID string) string {
+	return "enterprise:" + enterpriseID
+}
+
+// Backend persists encrypted installations. Implementations must be safe for
+// concurrent use.
+type Backend interface {
+	// Get returns the data stored under key, or nil if there is none.
+	Get(key string) ([]byte, error)
+	// Put creates or replaces the data stored under key.
+	Put(key string, data []byte) error
+	// Delete removes key; removing a missing key is not an error.
+	Delete(key string) error
+	// Close closes the installation database, if any.
+	Close() error
+}
+
+// OpenBackend opens where installations are kept: in memory, or for "bolt"
+// in the database file at path.
+func OpenBackend(kind, path string) (Backend, error) {
+	switch kind {
+	case "", "memory":
+		return NewMemoryBackend(), nil
+	case "bolt":
+		return NewBoltBackend(path)
+	default:
+		return nil, fmt.Errorf("unknown installation store backend: %q", kind)
+	}
+}
+
+// Store saves and finds installations, encrypting them with AES-256-GCM so a
+// copy of the database doesn't leak bot tokens
+type Store struct {
+	backend Backend
+	aead    cipher.AEAD
+	logger  *slog.Logger
+}
+
+// ParseKey decodes a base64 encoded 32-byte encryption key
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encryption key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// NewStore creates an installation store that encrypts with key
+func NewStore(backend Backend, key []byte, logger *slog.Logger) (*Store, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return &Store{
+		backend: backend,
+		aead:    aead,
+		logger:  logger,
+	}, nil
+}
+
+// Save stores an installation, replacing any earlier one for the same
+// workspace or organization
+func (s *Store) Save(installation Installation) error {
+	if installation.InstalledAt.IsZero() {
+		installation.InstalledAt = time.Now()
+	}
+
+	data, err := json.Marshal(installation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal installation: %w", err)
+	}
+
+	// The key is bound as additional data so a record can't be moved to
+	// another workspace's key
+	key := installation.Key()
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := s.aead.Seal(nonce, nonce, data, []byte(key))
+
+	if err := s.backend.Put(key, sealed); err != nil {
+		return fmt.Errorf("failed to save installation: %w", err)
+	}
+
+	s.logger.Info("Saved installation",
+		"team_id", installation.TeamID,
+		"enterprise_id", installation.EnterpriseID,
+		"is_enterprise_install", installation.IsEnterpriseInstall)
+	return nil
+}
+
+// Find returns the installation that covers a workspace: its own, or else an
+// org-wide install of its Enterprise Grid organization. It returns nil if
+// Wavie isn't installed there.
+func (s *Store) Find(enterpriseID, teamID string) (*Installation, error) {
+	var keys []string
+	if teamID != "" {
+		keys = append(keys, teamKey(teamID))
+	}
+	if enterpriseID != "" {
+		keys = append(keys, enterpriseKey(enterpriseID))
+	}
+
+	for _, key := range keys {
+		installation, err := s.get(key)
+		if err != nil {
+			return nil, err
+		}
+		if installation != nil {
+			return installation, nil
+		}
+	}
+	return nil, nil
+}
+
+// BotToken returns the bot token for a workspace, or "" if Wavie isn't
+// installed there
+func (s *Store) BotToken(ctx context.Context, enterpriseID, teamID string) (string, error) {
+	installation, err := s.Find(enterpriseID, teamID)
+	if err != nil || installation == nil {
+		return "", err
+	}
+	return installation.BotToken, nil
+}
+
+// Delete removes the installation of a workspace, or of an organization when
+// teamID is empty. It is called when Wavie is uninstalled or its tokens are
+// revoked.
+func (s *Store) Delete(enterpriseID, teamID string) error {
+	key := teamKey(teamID)
+	if teamID == "" {
+		key = enterpriseKey(enterpriseID)
+	}
+
+	if err := s.backend.Delete(key); err != nil {
+		return fmt.Errorf("failed to delete installation: %w", err)
+	}
+
+	s.logger.Info("Deleted installation", "team_id", teamID, "enterprise_id", enterpriseID)
+	return nil
+}
+
+// Close closes the backend
+func (s *Store) Close() error {
+	return s.backend.Close()
+}
+
+func (s *Store) get(key string) (*Installation, error) {
+	sealed, err := s.backend.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read installation: %w", err)
+	}
+	if sealed == nil {
+		return nil, nil
+	}
+
+	nonceSize := s.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, fmt.Errorf("installation %s is corrupt", key)
+	}
+	data, err := s.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt installation %s: %w", key, err)
+	}
+
+	var installation Installation
+	if err := json.Unmarshal(data, &installation); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal installation: %w", err)
+	}
+	return &installation, nil
+}
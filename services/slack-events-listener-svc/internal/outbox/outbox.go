@@ -0,0 +1,330 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	pendingBucket = []byte("pending")
+	deadBucket    = []byte("dead")
+)
+
+// ErrNotFound is returned when replaying an item that is not dead-lettered
+var ErrNotFound = errors.New("outbox item not found")
+
+const (
+	pollInterval = 1 * time.Second
+	baseDelay    = 2 * time.Second
+	maxDelay     = 10 * time.Minute
+)
+
+// Item is a request waiting to be delivered to the broadcast service. The ID
+// is the request's correlation ID and doubles as its idempotency key.
+type Item struct {
+	ID          string          `json:"id"`
+	Path        string          `json:"path"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	LastError   string          `json:"last_error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Outbox durably records requests for the broadcast service and delivers
+// them in the background, retrying with exponential backoff and jitter.
+// Items that exhaust their attempts are dead-lettered until replayed.
+type Outbox struct {
+	db          *bolt.DB
+	baseURL     string
+	maxAttempts int
+	logger      *slog.Logger
+	client      *http.Client
+	wake        chan struct{}
+}
+
+// Open opens (or creates) the outbox database at path. Items are POSTed to
+// baseURL joined with their path.
+func Open(path, baseURL string, maxAttempts int, logger *slog.Logger) (*Outbox, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{pendingBucket, deadBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create outbox buckets: %w", err)
+	}
+
+	return &Outbox{
+		db:          db,
+		baseURL:     baseURL,
+		maxAttempts: maxAttempts,
+		logger:      logger,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		wake: make(chan struct{}, 1),
+	}, nil
+}
+
+// Enqueue records a request for delivery. Enqueuing an ID that is already
+// pending is a no-op.
+func (o *Outbox) Enqueue(path, id string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox payload: %w", err)
+	}
+
+	now := time.Now()
+	item := Item{
+		ID:          id,
+		Path:        path,
+		Payload:     data,
+		NextAttempt: now,
+		CreatedAt:   now,
+	}
+
+	err = o.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(pendingBucket)
+		if bucket.Get([]byte(id)) != nil {
+			return nil
+		}
+		return putItem(bucket, item)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enqueue outbox item: %w", err)
+	}
+
+	o.notify()
+	return nil
+}
+
+// Run delivers due items until ctx is cancelled
+func (o *Outbox) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		o.deliverDue(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-o.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// DeadLetters returns the items that exhausted their delivery attempts
+func (o *Outbox) DeadLetters() ([]Item, error) {
+	items, err := o.list(deadBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	return items, nil
+}
+
+// Replay moves a dead-lettered item back to the pending queue with a fresh
+// set of attempts
+func (o *Outbox) Replay(id string) error {
+	err := o.db.Update(func(tx *bolt.Tx) error {
+		dead := tx.Bucket(deadBucket)
+		data := dead.Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+
+		var item Item
+		if err := json.Unmarshal(data, &item); err != nil {
+			return err
+		}
+		item.Attempts = 0
+		item.NextAttempt = time.Now()
+
+		if err := putItem(tx.Bucket(pendingBucket), item); err != nil {
+			return err
+		}
+		return dead.Delete([]byte(id))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replay outbox item: %w", err)
+	}
+
+	o.logger.Info("Replaying dead-lettered outbox item", "correlation_id", id)
+	o.notify()
+	return nil
+}
+
+// Close closes the outbox database
+func (o *Outbox) Close() error {
+	return o.db.Close()
+}
+
+func (o *Outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliverDue attempts every pending item whose next attempt is due
+func (o *Outbox) deliverDue(ctx context.Context) {
+	items, err := o.list(pendingBucket)
+	if err != nil {
+		o.logger.Error("Failed to list outbox items", "error", err)
+		return
+	}
+
+	now := time.Now()
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return
+		}
+		if item.NextAttempt.After(now) {
+			continue
+		}
+		o.attempt(ctx, item)
+	}
+}
+
+// attempt delivers one item and records the outcome
+func (o *Outbox) attempt(ctx context.Context, item Item) {
+	err := o.deliver(ctx, item)
+	if err == nil {
+		if err := o.remove(pendingBucket, item.ID); err != nil {
+			o.logger.Error("Failed to remove delivered outbox item", "error", err, "correlation_id", item.ID)
+		}
+		o.logger.Info("Delivered outbox item", "path", item.Path, "correlation_id", item.ID, "attempts", item.Attempts+1)
+		return
+	}
+
+	item.Attempts++
+	item.LastError = err.Error()
+
+	var permanent *permanentError
+	if errors.As(err, &permanent) || item.Attempts >= o.maxAttempts {
+		o.logger.Error("Dead-lettering outbox item", "error", err, "path", item.Path, "correlation_id", item.ID, "attempts", item.Attempts)
+		if err := o.move(item, pendingBucket, deadBucket); err != nil {
+			o.logger.Error("Failed to dead-letter outbox item", "error", err, "correlation_id", item.ID)
+		}
+		return
+	}
+
+	item.NextAttempt = time.Now().Add(backoff(item.Attempts))
+	o.logger.Warn("Outbox delivery failed, will retry", "error", err, "path", item.Path, "correlation_id", item.ID, "attempts", item.Attempts, "next_attempt", item.NextAttempt)
+
+	err = o.db.Update(func(tx *bolt.Tx) error {
+		return putItem(tx.Bucket(pendingBucket), item)
+	})
+	if err != nil {
+		o.logger.Error("Failed to update outbox item", "error", err, "correlation_id", item.ID)
+	}
+}
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+// deliver POSTs an item to the broadcast service, using its ID as the
+// idempotency key so the receiver can drop duplicates
+func (o *Outbox) deliver(ctx context.Context, item Item) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+item.Path, bytes.NewReader(item.Payload))
+	if err != nil {
+		return &permanentError{fmt.Errorf("failed to create request: %w", err)}
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", item.ID)
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call broadcast service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	err = fmt.Errorf("broadcast service error: %d - %s", resp.StatusCode, string(body))
+
+	// Other client errors mean the request itself is bad
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return &permanentError{err}
+	}
+	return err
+}
+
+// backoff returns the delay before the given retry: exponential growth capped
+// at maxDelay, with "equal jitter" so instances don't retry in lockstep
+func backoff(attempts int) time.Duration {
+	delay := maxDelay
+	if attempts < 20 {
+		delay = min(baseDelay<<(attempts-1), maxDelay)
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+func (o *Outbox) list(bucketName []byte) ([]Item, error) {
+	var items []Item
+	err := o.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).ForEach(func(_, data []byte) error {
+			var item Item
+			if err := json.Unmarshal(data, &item); err != nil {
+				return err
+			}
+			items = append(items, item)
+			return nil
+		})
+	})
+	return items, err
+}
+
+func (o *Outbox) remove(bucketName []byte, id string) error {
+	return o.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Delete([]byte(id))
+	})
+}
+
+func (o *Outbox) move(item Item, from, to []byte) error {
+	return o.db.Update(func(tx *bolt.Tx) error {
+		if err := putItem(tx.Bucket(to), item); err != nil {
+			return err
+		}
+		return tx.Bucket(from).Delete([]byte(item.ID))
+	})
+}
+
+func putItem(bucket *bolt.Bucket, item Item) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(item.ID), data)
+}
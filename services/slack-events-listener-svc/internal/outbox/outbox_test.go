@@ -0,0 +1,141 @@
+package outbox
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func newTestOutbox(t *testing.T, handler http.HandlerFunc, maxAttempts int) *Outbox {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	o, err := Open(filepath.Join(t.TempDir(), "outbox.db"), server.URL, maxAttempts, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("failed to open outbox: %v", err)
+	}
+	t.Cleanup(func() { o.Close() })
+	return o
+}
+
+func mustList(t *testing.T, o *Outbox, bucket []byte) []Item {
+	t.Helper()
+	items, err := o.list(bucket)
+	if err != nil {
+		t.Fatalf("failed to list outbox items: %v", err)
+	}
+	return items
+}
+
+func TestDeliveryOutcomeByStatus(t *testing.T) {
+	tests := []struct {
+		status  int
+		pending bool
+		dead    bool
+	}{
+		{status: http.StatusOK},
+		{status: http.StatusAccepted},
+		{status: http.StatusBadRequest, dead: true},
+		{status: http.StatusUnauthorized, dead: true},
+		{status: http.StatusNotFound, dead: true},
+		{status: http.StatusUnprocessableEntity, dead: true},
+		{status: http.StatusRequestTimeout, pending: true},
+		{status: http.StatusTooManyRequests, pending: true},
+		{status: http.StatusInternalServerError, pending: true},
+		{status: http.StatusServiceUnavailable, pending: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			var idempotencyKey string
+			o := newTestOutbox(t, func(w http.ResponseWriter, r *http.Request) {
+				idempotencyKey = r.Header.Get("Idempotency-Key")
+				w.WriteHeader(tt.status)
+			}, 5)
+
+			if err := o.Enqueue("/api/broadcast", "wv-1", map[string]string{"question": "hi"}); err != nil {
+				t.Fatalf("failed to enqueue: %v", err)
+			}
+			o.deliverDue(context.Background())
+
+			if idempotencyKey != "wv-1" {
+				t.Errorf("Idempotency-Key = %q, want the item ID", idempotencyKey)
+			}
+
+			pending := mustList(t, o, pendingBucket)
+			dead := mustList(t, o, deadBucket)
+			if got := len(pending) == 1; got != tt.pending {
+				t.Errorf("pending = %v, want %v", got, tt.pending)
+			}
+			if got := len(dead) == 1; got != tt.dead {
+				t.Errorf("dead-lettered = %v, want %v", got, tt.dead)
+			}
+			for _, item := range append(pending, dead...) {
+				if item.Attempts != 1 || item.LastError == "" {
+					t.Errorf("item = %+v, want one failed attempt with its error", item)
+				}
+			}
+		})
+	}
+}
+
+func TestExhaustedItemsAreDeadLetteredAndReplayed(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	o := newTestOutbox(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}, 2)
+
+	if err := o.Enqueue("/api/feedback", "wv-2", map[string]string{}); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+
+	// Retried items wait for their backoff, so attempt them directly
+	for range 2 {
+		items := mustList(t, o, pendingBucket)
+		if len(items) != 1 {
+			t.Fatalf("pending items = %d, want 1", len(items))
+		}
+		o.attempt(context.Background(), items[0])
+	}
+
+	dead, err := o.DeadLetters()
+	if err != nil {
+		t.Fatalf("failed to list dead letters: %v", err)
+	}
+	if len(dead) != 1 || dead[0].Attempts != 2 {
+		t.Fatalf("dead letters = %+v, want the item after 2 attempts", dead)
+	}
+
+	if err := o.Replay("wv-2"); err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+	if err := o.Replay("wv-2"); err == nil {
+		t.Error("replaying an item that isn't dead-lettered succeeded")
+	}
+
+	status = http.StatusOK
+	o.deliverDue(context.Background())
+	if pending, dead := mustList(t, o, pendingBucket), mustList(t, o, deadBucket); len(pending) != 0 || len(dead) != 0 {
+		t.Errorf("after replay: %d pending and %d dead, want the item delivered", len(pending), len(dead))
+	}
+}
+
+func TestEnqueueIgnoresPendingDuplicates(t *testing.T) {
+	o := newTestOutbox(t, func(w http.ResponseWriter, r *http.Request) {}, 5)
+
+	for _, question := range []string{"first", "second"} {
+		if err := o.Enqueue("/api/broadcast", "wv-3", map[string]string{"question": question}); err != nil {
+			t.Fatalf("failed to enqueue: %v", err)
+		}
+	}
+
+	items := mustList(t, o, pendingBucket)
+	if len(items) != 1 || string(items[0].Payload) != `{"question":"first"}` {
+		t.Errorf("pending items = %+v, want only the first", items)
+	}
+}
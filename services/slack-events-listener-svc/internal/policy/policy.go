@@ -0,0 +1,127 @@
+// Package policy decides how Wavie behaves in each channel: whether it
+// answers there at all, whether answers are broadcast and where, which
+// persona and model answer, and whether replies are only visible to the
+// asker.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+)
+
+// Settings are the behaviour settings that can be given as defaults and per
+// channel. Unset fields inherit from the defaults.
+type Settings struct {
+	// Whether questions and answers are sent to the broadcast channel, and
+	// to which channel instead of the broadcast service's own
+	Broadcast        *bool  `json:"broadcast,omitempty"`
+	BroadcastChannel string `json:"broadcast_channel,omitempty"`
+
+	// Persona names a system prompt configured on the Claude proxy; Model
+	// overrides the proxy's default model
+	Persona string `json:"persona,omitempty"`
+	Model   string `json:"model,omitempty"`
+
+	// Answer with messages only the asker can see
+	Ephemeral *bool `json:"ephemeral,omitempty"`
+}
+
+// File is the channel policy file. Channels are identified by ID, since
+// names can change.
+type File struct {
+	// If not empty, Wavie only answers in these channels and in direct
+	// messages; it never answers in denied channels, DMs included
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+
+	Defaults Settings            `json:"defaults"`
+	Channels map[string]Settings `json:"channels,omitempty"`
+}
+
+// Policy is the resolved behaviour for one channel
+type Policy struct {
+	Allowed          bool
+	Broadcast        bool
+	BroadcastChannel string
+	Persona          string
+	Model            string
+	Ephemeral        bool
+}
+
+// Policies resolves the policy for each channel
+type Policies struct {
+	allow    map[string]bool
+	deny     map[string]bool
+	defaults Settings
+	channels map[string]Settings
+}
+
+// Load reads the policy file at path. With an empty path every channel gets
+// the default behaviour: allowed, broadcast, default persona and model.
+func Load(path string) (*Policies, error) {
+	var file File
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read channel policy file: %w", err)
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse channel policy file: %w", err)
+		}
+	}
+	return New(file), nil
+}
+
+// New creates policies from a parsed policy file
+func New(file File) *Policies {
+	policies := &Policies{
+		allow:    make(map[string]bool),
+		deny:     make(map[string]bool),
+		defaults: file.Defaults,
+		channels: file.Channels,
+	}
+	for _, channel := range file.Allow {
+		policies.allow[channel] = true
+	}
+	for _, channel := range file.Deny {
+		policies.deny[channel] = true
+	}
+	return policies
+}
+
+// For returns the policy for a channel
+func (p *Policies) For(channelID string) Policy {
+	// DM IDs differ per user, so an allow list couldn't name them
+	listed := len(p.allow) == 0 || p.allow[channelID] || slack.IsDirectMessage(channelID)
+	policy := Policy{
+		Allowed:   !p.deny[channelID] && listed,
+		Broadcast: true,
+	}
+	policy.apply(p.defaults)
+	if settings, ok := p.channels[channelID]; ok {
+		policy.apply(settings)
+	}
+	return policy
+}
+
+// apply overrides the policy with the settings that are set
+func (p *Policy) apply(settings Settings) {
+	if settings.Broadcast != nil {
+		p.Broadcast = *settings.Broadcast
+	}
+	if settings.BroadcastChannel != "" {
+		p.BroadcastChannel = settings.BroadcastChannel
+	}
+	if settings.Persona != "" {
+		p.Persona = settings.Persona
+	}
+	if settings.Model != "" {
+		p.Model = settings.Model
+	}
+	if settings.Ephemeral != nil {
+		p.Ephemeral = *settings.Ephemeral
+	}
+}
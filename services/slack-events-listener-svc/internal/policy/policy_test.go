@@ -0,0 +1,27 @@
+package policy
+
+import "testing"
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    File
+		channel string
+		want    bool
+	}{
+		{"no lists", File{}, "C1", true},
+		{"denied", File{Deny: []string{"C1"}}, "C1", false},
+		{"allowed", File{Allow: []string{"C1"}}, "C1", true},
+		{"not on the allow list", File{Allow: []string{"C1"}}, "C2", false},
+		{"direct message with an allow list", File{Allow: []string{"C1"}}, "D1", true},
+		{"denied direct message", File{Allow: []string{"C1"}, Deny: []string{"D1"}}, "D1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.file).For(tt.channel).Allowed; got != tt.want {
+				t.Errorf("For(%q).Allowed = %v, want %v", tt.channel, got, tt.want)
+			}
+		})
+	}
+}
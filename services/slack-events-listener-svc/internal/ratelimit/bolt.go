@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BitwaveCorp/shared-svcs/shared/boltbucket"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltBackend stores limiter state in an embedded bbolt database so limits
+// and daily quotas survive restarts. The file can't be shared, so each
+// instance still enforces the limits on its own.
+type BoltBackend struct {
+	bucket *boltbucket.Bucket
+}
+
+// NewBoltBackend opens (or creates) the database file at path
+func NewBoltBackend(path string) (*BoltBackend, error) {
+	bucket, err := boltbucket.Open(path, "rate_limits")
+	if err != nil {
+		return nil, err
+	}
+	return &BoltBackend{bucket: bucket}, nil
+}
+
+func (b *BoltBackend) Update(keys []string, fn func(states map[string]*State) error) error {
+	err := b.bucket.Update(func(bucket *bolt.Bucket) error {
+		states := make(map[string]*State, len(keys))
+		for _, k := range keys {
+			state := &State{}
+			if data := bucket.Get([]byte(k)); data != nil {
+				if err := json.Unmarshal(data, state); err != nil {
+					return err
+				}
+			}
+			states[k] = state
+		}
+
+		if err := fn(states); err != nil {
+			return err
+		}
+
+		for k, state := range states {
+			data, err := json.Marshal(state)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(k), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update rate limit state: %w", err)
+	}
+	return nil
+}
+
+func (b *BoltBackend) DeleteExpired(cutoff time.Time) (int, error) {
+	removed, err := b.bucket.DeleteIf(func(_, data []byte) bool {
+		var state State
+		return json.Unmarshal(data, &state) != nil || state.Refilled.Before(cutoff)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired rate limit state: %w", err)
+	}
+	return removed, nil
+}
+
+func (b *BoltBackend) Close() error {
+	return b.bucket.Close()
+}
@@ -0,0 +1,283 @@
+// Package ratelimit throttles questions per user, channel and workspace with
+// token buckets, and caps how many questions and Claude tokens each may use
+// per day.
+package ratelimit
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// State is what is stored per user, channel or workspace
+type State struct {
+	// Token bucket: questions that can be asked right now, as of Refilled
+	Allowance float64   `json:"allowance"`
+	Refilled  time.Time `json:"refilled"`
+
+	// Daily counters for Day (a UTC date)
+	Day        string `json:"day"`
+	Requests   int    `json:"requests"`
+	TokensUsed int    `json:"tokens_used"`
+}
+
+// Backend stores limiter state. RedisBackend is shared between instances so
+// the limits hold across all of them; the others are per instance.
+// Implementations must be safe for concurrent use.
+type Backend interface {
+	// Update applies fn to the states of keys and saves them, atomically so
+	// two requests can't both take the last question. Keys with no stored
+	// state are passed zero states. fn may be run again, on fresh states, if
+	// another instance changed them first.
+	Update(keys []string, fn func(states map[string]*State) error) error
+	// DeleteExpired removes state last used before cutoff and returns how
+	// many entries were removed.
+	DeleteExpired(cutoff time.Time) (int, error)
+	// Close closes the state database once the limiter is done with it.
+	Close() error
+}
+
+// OpenBackend opens the limiter state store named by RATE_LIMIT_BACKEND.
+// "bolt" uses path and "redis" uses redisURL.
+func OpenBackend(kind, path, redisURL string) (Backend, error) {
+	switch kind {
+	case "", "memory":
+		return NewMemoryBackend(), nil
+	case "bolt":
+		return NewBoltBackend(path)
+	case "redis":
+		return NewRedisBackend(redisURL)
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend: %q", kind)
+	}
+}
+
+// Limits for one kind of scope. Zero disables a limit.
+type Limits struct {
+	PerMinute     float64 // sustained questions per minute
+	Burst         int     // questions that can be asked at once
+	DailyRequests int
+	DailyTokens   int // Claude input plus output tokens
+}
+
+// Scope identifies who a limit applies to, e.g. a user
+type Scope struct {
+	Kind string // "user", "channel" or "workspace"
+	ID   string
+}
+
+func (s Scope) key() string {
+	return s.Kind + ":" + s.ID
+}
+
+// Decision is the outcome of Allow. When a question is refused, Scope and
+// Reason say which limit was hit and ResetAt when it is lifted.
+type Decision struct {
+	Allowed bool
+	Scope   Scope
+	Reason  string // "rate", "requests" or "tokens"
+	ResetAt time.Time
+}
+
+// Limiter applies limits per scope kind
+type Limiter struct {
+	backend Backend
+	limits  map[string]Limits
+	logger  *slog.Logger
+	done    chan struct{}
+}
+
+// NewLimiter creates a limiter with the limits for each scope kind
+func NewLimiter(backend Backend, limits map[string]Limits, logger *slog.Logger) *Limiter {
+	limiter := &Limiter{
+		backend: backend,
+		limits:  limits,
+		logger:  logger,
+		done:    make(chan struct{}),
+	}
+
+	go limiter.cleanupRoutine()
+
+	return limiter
+}
+
+// Allow checks every scope's limits and, only if none is exceeded, counts a
+// question against all of them. Backend errors allow the question: a broken
+// store shouldn't take Wavie down.
+func (l *Limiter) Allow(scopes ...Scope) Decision {
+	scopes = l.limited(scopes)
+	if len(scopes) == 0 {
+		return Decision{Allowed: true}
+	}
+
+	now := time.Now()
+	var decision Decision
+	err := l.backend.Update(keys(scopes), func(states map[string]*State) error {
+		decision = Decision{Allowed: true}
+		for _, scope := range scopes {
+			limits := l.limits[scope.Kind]
+			state := states[scope.key()]
+			state.refill(limits, now)
+
+			switch {
+			case limits.PerMinute > 0 && state.Allowance < 1:
+				wait := time.Duration((1 - state.Allowance) / limits.PerMinute * float64(time.Minute))
+				decision = Decision{Scope: scope, Reason: "rate", ResetAt: now.Add(wait)}
+			case limits.DailyRequests > 0 && state.Requests >= limits.DailyRequests:
+				decision = Decision{Scope: scope, Reason: "requests", ResetAt: nextDay(now)}
+			case limits.DailyTokens > 0 && state.TokensUsed >= limits.DailyTokens:
+				decision = Decision{Scope: scope, Reason: "tokens", ResetAt: nextDay(now)}
+			default:
+				continue
+			}
+			return nil
+		}
+
+		for _, scope := range scopes {
+			state := states[scope.key()]
+			if l.limits[scope.Kind].PerMinute > 0 {
+				state.Allowance--
+			}
+			state.Requests++
+		}
+		return nil
+	})
+	if err != nil {
+		l.logger.Error("Failed to check rate limits", "error", err)
+		return Decision{Allowed: true}
+	}
+
+	return decision
+}
+
+// RecordTokens counts the Claude tokens an answer used against every scope's
+// daily token quota
+func (l *Limiter) RecordTokens(tokens int, scopes ...Scope) {
+	scopes = l.limited(scopes)
+	if len(scopes) == 0 || tokens <= 0 {
+		return
+	}
+
+	now := time.Now()
+	err := l.backend.Update(keys(scopes), func(states map[string]*State) error {
+		for _, scope := range scopes {
+			state := states[scope.key()]
+			state.refill(l.limits[scope.Kind], now)
+			state.TokensUsed += tokens
+		}
+		return nil
+	})
+	if err != nil {
+		l.logger.Error("Failed to record token usage", "error", err)
+	}
+}
+
+// Quota is how much of a scope's daily quotas is left. A limit of 0 means
+// that quota is off.
+type Quota struct {
+	RequestLimit int
+	RequestsLeft int
+	TokenLimit   int
+	TokensLeft   int
+	ResetAt      time.Time
+}
+
+// Remaining reports a scope's daily quotas without counting anything against
+// them
+func (l *Limiter) Remaining(scope Scope) Quota {
+	limits := l.limits[scope.Kind]
+	now := time.Now()
+	quota := Quota{
+		RequestLimit: limits.DailyRequests,
+		RequestsLeft: limits.DailyRequests,
+		TokenLimit:   limits.DailyTokens,
+		TokensLeft:   limits.DailyTokens,
+		ResetAt:      nextDay(now),
+	}
+	if len(l.limited([]Scope{scope})) == 0 {
+		return quota
+	}
+
+	err := l.backend.Update([]string{scope.key()}, func(states map[string]*State) error {
+		state := states[scope.key()]
+		state.refill(limits, now)
+		quota.RequestsLeft = max(limits.DailyRequests-state.Requests, 0)
+		quota.TokensLeft = max(limits.DailyTokens-state.TokensUsed, 0)
+		return nil
+	})
+	if err != nil {
+		l.logger.Error("Failed to read rate limit state", "error", err)
+	}
+	return quota
+}
+
+// Close stops the cleanup routine and closes the backend
+func (l *Limiter) Close() error {
+	close(l.done)
+	return l.backend.Close()
+}
+
+// limited drops scopes without an ID or without any limits
+func (l *Limiter) limited(scopes []Scope) []Scope {
+	var result []Scope
+	for _, scope := range scopes {
+		if scope.ID != "" && l.limits[scope.Kind] != (Limits{}) {
+			result = append(result, scope)
+		}
+	}
+	return result
+}
+
+// refill tops up the token bucket for the time since it was last refilled
+// and starts new daily counters at midnight UTC
+func (s *State) refill(limits Limits, now time.Time) {
+	capacity := float64(max(limits.Burst, 1))
+	if s.Refilled.IsZero() {
+		s.Allowance = capacity
+	} else {
+		s.Allowance = min(s.Allowance+now.Sub(s.Refilled).Minutes()*limits.PerMinute, capacity)
+	}
+	s.Refilled = now
+
+	if day := now.UTC().Format(time.DateOnly); s.Day != day {
+		s.Day = day
+		s.Requests = 0
+		s.TokensUsed = 0
+	}
+}
+
+// cleanupRoutine periodically removes state for scopes that have been quiet
+// for over a day; it would be back to a full bucket and fresh counters anyway
+func (l *Limiter) cleanupRoutine() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			removed, err := l.backend.DeleteExpired(time.Now().Add(-48 * time.Hour))
+			if err != nil {
+				l.logger.Error("Failed to clean up rate limit state", "error", err)
+				continue
+			}
+			if removed > 0 {
+				l.logger.Info("Cleaned up rate limit state", "removed", removed)
+			}
+		case <-l.done:
+			return
+		}
+	}
+}
+
+func keys(scopes []Scope) []string {
+	result := make([]string, len(scopes))
+	for i, scope := range scopes {
+		result[i] = scope.key()
+	}
+	return result
+}
+
+// nextDay is when the daily quotas reset: the next midnight UTC
+func nextDay(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
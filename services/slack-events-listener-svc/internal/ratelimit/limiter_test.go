@@ -0,0 +1,172 @@
+package ratelimit
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+var (
+	user    = Scope{Kind: "user", ID: "U1"}
+	channel = Scope{Kind: "channel", ID: "C1"}
+)
+
+func newTestLimiter(t *testing.T, limits map[string]Limits) (*Limiter, *MemoryBackend) {
+	t.Helper()
+	backend := NewMemoryBackend()
+	limiter := NewLimiter(backend, limits, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(func() { limiter.Close() })
+	return limiter, backend
+}
+
+// rewind moves a scope's state back in time, as if d had passed since it
+// was last used
+func rewind(t *testing.T, backend *MemoryBackend, scope Scope, d time.Duration) {
+	t.Helper()
+	err := backend.Update([]string{scope.key()}, func(states map[string]*State) error {
+		state := states[scope.key()]
+		state.Refilled = state.Refilled.Add(-d)
+		if day, err := time.Parse(time.DateOnly, state.Day); err == nil {
+			state.Day = day.Add(-d).Format(time.DateOnly)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to rewind state: %v", err)
+	}
+}
+
+func TestRateLimitWindow(t *testing.T) {
+	limiter, backend := newTestLimiter(t, map[string]Limits{"user": {PerMinute: 60, Burst: 3}})
+
+	for i := range 3 {
+		if decision := limiter.Allow(user); !decision.Allowed {
+			t.Fatalf("question %d of the burst was refused: %+v", i+1, decision)
+		}
+	}
+
+	decision := limiter.Allow(user)
+	if decision.Allowed || decision.Reason != "rate" || decision.Scope != user {
+		t.Fatalf("question over the burst = %+v, want refused by the user's rate", decision)
+	}
+	if wait := time.Until(decision.ResetAt); wait <= 0 || wait > time.Second {
+		t.Errorf("refused question can be asked again in %v, want within a second", wait)
+	}
+
+	// One question a second refills the bucket, but never past the burst
+	rewind(t, backend, user, 10*time.Second)
+	for i := range 3 {
+		if decision := limiter.Allow(user); !decision.Allowed {
+			t.Fatalf("question %d after refilling was refused: %+v", i+1, decision)
+		}
+	}
+	if limiter.Allow(user).Allowed {
+		t.Error("bucket refilled past its burst")
+	}
+}
+
+func TestDailyRequestWindow(t *testing.T) {
+	limiter, backend := newTestLimiter(t, map[string]Limits{"user": {DailyRequests: 2}})
+
+	for range 2 {
+		if !limiter.Allow(user).Allowed {
+			t.Fatal("question within the daily quota was refused")
+		}
+	}
+
+	decision := limiter.Allow(user)
+	if decision.Allowed || decision.Reason != "requests" {
+		t.Fatalf("question over the daily quota = %+v, want refused for requests", decision)
+	}
+	if want := nextDay(time.Now()); !decision.ResetAt.Equal(want) {
+		t.Errorf("quota resets at %v, want %v", decision.ResetAt, want)
+	}
+	if quota := limiter.Remaining(user); quota.RequestLimit != 2 || quota.RequestsLeft != 0 {
+		t.Errorf("Remaining = %+v, want 0 of 2 requests left", quota)
+	}
+
+	// A new UTC day starts new counters
+	rewind(t, backend, user, 24*time.Hour)
+	if !limiter.Allow(user).Allowed {
+		t.Error("question on a new day was refused")
+	}
+	if quota := limiter.Remaining(user); quota.RequestsLeft != 1 {
+		t.Errorf("Remaining on a new day = %+v, want 1 request left", quota)
+	}
+}
+
+func TestDailyTokenWindow(t *testing.T) {
+	limiter, backend := newTestLimiter(t, map[string]Limits{"user": {DailyTokens: 100}})
+
+	if !limiter.Allow(user).Allowed {
+		t.Fatal("first question was refused")
+	}
+	limiter.RecordTokens(60, user)
+	if quota := limiter.Remaining(user); quota.TokensLeft != 40 {
+		t.Errorf("Remaining = %+v, want 40 tokens left", quota)
+	}
+
+	// The quota is checked before a question, so the one that crosses it
+	// still gets its answer
+	if !limiter.Allow(user).Allowed {
+		t.Fatal("question under the token quota was refused")
+	}
+	limiter.RecordTokens(60, user)
+	if decision := limiter.Allow(user); decision.Allowed || decision.Reason != "tokens" {
+		t.Fatalf("question over the token quota = %+v, want refused for tokens", decision)
+	}
+
+	rewind(t, backend, user, 24*time.Hour)
+	if !limiter.Allow(user).Allowed {
+		t.Error("question on a new day was refused")
+	}
+}
+
+func TestRefusedQuestionsAreNotCounted(t *testing.T) {
+	limiter, _ := newTestLimiter(t, map[string]Limits{
+		"user":    {DailyRequests: 10},
+		"channel": {DailyRequests: 1},
+	})
+
+	if !limiter.Allow(user, channel).Allowed {
+		t.Fatal("first question was refused")
+	}
+	decision := limiter.Allow(user, channel)
+	if decision.Allowed || decision.Scope != channel {
+		t.Fatalf("second question = %+v, want refused by the channel", decision)
+	}
+
+	if quota := limiter.Remaining(user); quota.RequestsLeft != 9 {
+		t.Errorf("user has %d requests left, want 9: the refused question was counted", quota.RequestsLeft)
+	}
+}
+
+func TestUnlimitedScopes(t *testing.T) {
+	limiter, _ := newTestLimiter(t, map[string]Limits{"user": {DailyRequests: 1}})
+
+	for range 3 {
+		if !limiter.Allow(channel, Scope{Kind: "user"}).Allowed {
+			t.Fatal("question without a limited scope was refused")
+		}
+	}
+	if quota := limiter.Remaining(channel); quota.RequestLimit != 0 {
+		t.Errorf("Remaining for an unlimited scope = %+v, want no limit", quota)
+	}
+}
+
+func TestDeleteExpired(t *testing.T) {
+	limiter, backend := newTestLimiter(t, map[string]Limits{"user": {DailyRequests: 5}, "channel": {DailyRequests: 5}})
+
+	limiter.Allow(user)
+	limiter.Allow(channel)
+	rewind(t, backend, user, 72*time.Hour)
+
+	removed, err := backend.DeleteExpired(time.Now().Add(-48 * time.Hour))
+	if err != nil || removed != 1 {
+		t.Fatalf("DeleteExpired = %d, %v; want 1 removed", removed, err)
+	}
+	if quota := limiter.Remaining(channel); quota.RequestsLeft != 4 {
+		t.Errorf("recently used state was removed: %+v", quota)
+	}
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryBackend keeps limiter state in an in-process map. It is lost on
+// restart and is not shared between instances, so each instance enforces the
+// limits on its own.
+type MemoryBackend struct {
+	states map[string]State
+	mutex  sync.Mutex
+}
+
+// NewMemoryBackend creates an empty in-memory backend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		states: make(map[string]State),
+	}
+}
+
+func (b *MemoryBackend) Update(keys []string, fn func(states map[string]*State) error) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	states := make(map[string]*State, len(keys))
+	for _, k := range keys {
+		state := b.states[k]
+		states[k] = &state
+	}
+
+	if err := fn(states); err != nil {
+		return err
+	}
+
+	for k, state := range states {
+		b.states[k] = *state
+	}
+	return nil
+}
+
+func (b *MemoryBackend) DeleteExpired(cutoff time.Time) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	removed := 0
+	for k, state := range b.states {
+		if state.Refilled.Before(cutoff) {
+			delete(b.states, k)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (b *MemoryBackend) Close() error {
+	return nil
+}
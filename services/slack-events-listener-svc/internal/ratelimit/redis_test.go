@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newRedisLimiter returns a limiter for another instance sharing server
+func newRedisLimiter(t *testing.T, server *miniredis.Miniredis, limits map[string]Limits) *Limiter {
+	t.Helper()
+	backend, err := OpenBackend("redis", "", "redis://"+server.Addr())
+	if err != nil {
+		t.Fatalf("failed to open redis backend: %v", err)
+	}
+	limiter := NewLimiter(backend, limits, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(func() { limiter.Close() })
+	return limiter
+}
+
+func TestRedisLimitsAreShared(t *testing.T) {
+	server := miniredis.RunT(t)
+	limits := map[string]Limits{"user": {DailyRequests: 10}}
+	instances := []*Limiter{newRedisLimiter(t, server, limits), newRedisLimiter(t, server, limits)}
+
+	// Both instances race for the same user's quota
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := range 30 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if instances[i%2].Allow(user).Allowed {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != 10 {
+		t.Errorf("instances allowed %d questions between them, want the daily quota of 10", got)
+	}
+	for i, limiter := range instances {
+		if quota := limiter.Remaining(user); quota.RequestsLeft != 0 {
+			t.Errorf("instance %d sees %d questions left, want 0", i, quota.RequestsLeft)
+		}
+	}
+	if ttl := server.TTL(redisKeyPrefix + user.key()); ttl != redisStateTTL {
+		t.Errorf("state expires in %v, want %v", ttl, redisStateTTL)
+	}
+}
+
+func TestRedisUnreachable(t *testing.T) {
+	server := miniredis.RunT(t)
+	addr := server.Addr()
+	server.Close()
+
+	if _, err := OpenBackend("redis", "", "redis://"+addr); err == nil {
+		t.Error("OpenBackend succeeded without a redis server")
+	}
+	if _, err := OpenBackend("redis", "", "not a url"); err == nil {
+		t.Error("OpenBackend accepted an invalid redis URL")
+	}
+}
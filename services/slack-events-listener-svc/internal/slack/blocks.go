@@ -0,0 +1,241 @@
+package slack
+
+import "github.com/BitwaveCorp/shared-svcs/shared/mrkdwn"
+
+// Slack rejects section text over 3000 characters. Longer answers are spread
+// over several sections, and answers too long for one message over several
+// messages in the thread, which keeps each message readable and well under
+// the 50 block limit.
+const (
+	maxSectionChars = 3000
+	maxMessageChars = 12000
+)
+
+// Block Kit action and callback IDs used on Wavie's answers
+const (
+	ActionFeedbackPositive = "feedback_positive"
+	ActionFeedbackNegative = "feedback_negative"
+	ActionFeedbackText     = "feedback_text"
+
+	CallbackFeedbackModal = "wavie_feedback_modal"
+
+	// The "Ask Wavie about this message" shortcut (set this callback ID on
+	// the message shortcut in the app settings) and the modal it opens
+	CallbackAskAboutMessage = "wavie_ask_about_message"
+	CallbackAskModal        = "wavie_ask_modal"
+
+	AskQuestionBlockID     = "question"
+	AskQuestionActionID    = "question_input"
+	AskVisibilityBlockID   = "visibility"
+	AskVisibilityActionID  = "visibility_input"
+	AskVisibilityThread    = "thread"
+	AskVisibilityEphemeral = "ephemeral"
+
+	FeedbackInputBlockID  = "feedback"
+	FeedbackInputActionID = "feedback_input"
+)
+
+type MessageBlock struct {
+	Type     string         `json:"type"`
+	BlockID  string         `json:"block_id,omitempty"`
+	Text     *TextObject    `json:"text,omitempty"`
+	Elements []BlockElement `json:"elements,omitempty"`
+	Label    *TextObject    `json:"label,omitempty"`
+	Element  *BlockElement  `json:"element,omitempty"`
+	Optional bool           `json:"optional,omitempty"`
+}
+
+type TextObject struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	Emoji bool   `json:"emoji,omitempty"`
+}
+
+// BlockElement is an interactive element such as a button or text input
+type BlockElement struct {
+	Type        string      `json:"type"`
+	ActionID    string      `json:"action_id,omitempty"`
+	Text        *TextObject `json:"text,omitempty"`
+	Value       string      `json:"value,omitempty"`
+	Style       string      `json:"style,omitempty"`
+	Multiline   bool        `json:"multiline,omitempty"`
+	Placeholder *TextObject `json:"placeholder,omitempty"`
+
+	// Radio buttons and selects
+	Options       []Option `json:"options,omitempty"`
+	InitialOption *Option  `json:"initial_option,omitempty"`
+}
+
+// Option is one choice of a radio button group or select
+type Option struct {
+	Text  *TextObject `json:"text"`
+	Value string      `json:"value"`
+}
+
+// View is a modal or App Home surface
+type View struct {
+	Type            string         `json:"type"`
+	CallbackID      string         `json:"callback_id,omitempty"`
+	Title           *TextObject    `json:"title,omitempty"`
+	Submit          *TextObject    `json:"submit,omitempty"`
+	Close           *TextObject    `json:"close,omitempty"`
+	Blocks          []MessageBlock `json:"blocks"`
+	PrivateMetadata string         `json:"private_metadata,omitempty"`
+}
+
+func plainText(text string) *TextObject {
+	return &TextObject{Type: "plain_text", Text: text, Emoji: true}
+}
+
+func button(actionID, text, value, style string) BlockElement {
+	return BlockElement{
+		Type:     "button",
+		ActionID: actionID,
+		Text:     plainText(text),
+		Value:    value,
+		Style:    style,
+	}
+}
+
+// SplitAnswer breaks an answer, already rendered as mrkdwn, into the texts of
+// the messages it has to be posted as
+func SplitAnswer(text string) []string {
+	return mrkdwn.Split(text, maxMessageChars)
+}
+
+// TextBlocks lays out mrkdwn text as sections, splitting it between
+// paragraphs where it is too long for one
+func TextBlocks(text string) []MessageBlock {
+	var blocks []MessageBlock
+	for _, part := range mrkdwn.Split(text, maxSectionChars) {
+		blocks = append(blocks, MessageBlock{
+			Type: "section",
+			Text: &TextObject{Type: "mrkdwn", Text: part},
+		})
+	}
+	return blocks
+}
+
+// AnswerBlocks lays out an answer, already rendered as mrkdwn, with feedback
+// buttons. The correlation ID travels as the button value so feedback can be
+// traced to the answer.
+func AnswerBlocks(text, correlationID string) []MessageBlock {
+	return append(TextBlocks(text), MessageBlock{
+		Type:    "actions",
+		BlockID: "wavie_feedback",
+		Elements: []BlockElement{
+			button(ActionFeedbackPositive, "👍 Helpful", correlationID, ""),
+			button(ActionFeedbackNegative, "👎 Not helpful", correlationID, ""),
+			button(ActionFeedbackText, "Tell us more", correlationID, ""),
+		},
+	})
+}
+
+// FeedbackModal asks for detailed feedback; metadata is returned untouched
+// in the view_submission payload
+func FeedbackModal(metadata string) View {
+	return View{
+		Type:       "modal",
+		CallbackID: CallbackFeedbackModal,
+		Title:      plainText("Wavie feedback"),
+		Submit:     plainText("Send"),
+		Close:      plainText("Cancel"),
+		Blocks: []MessageBlock{
+			{
+				Type:    "input",
+				BlockID: FeedbackInputBlockID,
+				Label:   plainText("What could Wavie have done better?"),
+				Element: &BlockElement{
+					Type:        "plain_text_input",
+					ActionID:    FeedbackInputActionID,
+					Multiline:   true,
+					Placeholder: plainText("The answer was missing…"),
+				},
+			},
+		},
+		PrivateMetadata: metadata,
+	}
+}
+
+// AskModal asks what the user wants to know about a message and who should
+// see the answer; metadata is returned untouched in the view_submission
+// payload
+func AskModal(metadata string) View {
+	thread := Option{Text: plainText("Reply in the thread"), Value: AskVisibilityThread}
+	private := Option{Text: plainText("Only visible to me"), Value: AskVisibilityEphemeral}
+
+	return View{
+		Type:       "modal",
+		CallbackID: CallbackAskModal,
+		Title:      plainText("Ask Wavie"),
+		Submit:     plainText("Ask"),
+		Close:      plainText("Cancel"),
+		Blocks: []MessageBlock{
+			{
+				Type:     "input",
+				BlockID:  AskQuestionBlockID,
+				Label:    plainText("What would you like to know about this message?"),
+				Optional: true,
+				Element: &BlockElement{
+					Type:        "plain_text_input",
+					ActionID:    AskQuestionActionID,
+					Multiline:   true,
+					Placeholder: plainText("Leave empty to have Wavie explain it"),
+				},
+			},
+			{
+				Type:    "input",
+				BlockID: AskVisibilityBlockID,
+				Label:   plainText("Who should see the answer?"),
+				Element: &BlockElement{
+					Type:          "radio_buttons",
+					ActionID:      AskVisibilityActionID,
+					Options:       []Option{private, thread},
+					InitialOption: &private,
+				},
+			},
+		},
+		PrivateMetadata: metadata,
+	}
+}
+
+// AnswerMessage builds the message for one of Wavie's answers: the answer
+// (rendered as mrkdwn) as Block Kit with feedback buttons, plus its metadata
+func AnswerMessage(channel, text, threadTS string, answer AnswerMetadata) MessageResponse {
+	return MessageResponse{
+		Channel:  channel,
+		Text:     text,
+		ThreadTS: threadTS,
+		Blocks:   AnswerBlocks(text, answer.CorrelationID),
+		Metadata: &MessageMetadata{
+			EventType:    AnswerEventType,
+			EventPayload: answer,
+		},
+	}
+}
+
+// AnswerMessages builds the messages for an answer that may be too long for
+// one. Every part carries the answer's metadata so feedback on any of them
+// can be linked; only the last has the feedback buttons.
+func AnswerMessages(channel, text, threadTS string, answer AnswerMetadata) []MessageResponse {
+	parts := SplitAnswer(text)
+
+	messages := make([]MessageResponse, len(parts))
+	for i, part := range parts {
+		if i == len(parts)-1 {
+			messages[i] = AnswerMessage(channel, part, threadTS, answer)
+			continue
+		}
+		messages[i] = MessageResponse{
+			Channel:  channel,
+			Text:     part,
+			ThreadTS: threadTS,
+			Blocks:   TextBlocks(part),
+			Metadata: &MessageMetadata{
+				EventType:    AnswerEventType,
+				EventPayload: answer,
+			},
+		}
+	}
+	return messages
+}
@@ -4,56 +4,296 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/BitwaveCorp/shared-svcs/shared/slackapi"
 )
 
 type Client struct {
-	botToken string
-	logger   *slog.Logger
-	client   *http.Client
+	api    *slackapi.Client
+	once   *slackapi.Client // api without retries
+	logger *slog.Logger
+	client *http.Client // for file downloads and response URLs
+	names  *Names       // set for clients handed out by Clients
+
+	mutex        sync.Mutex
+	workspaceURL string // from auth.test, once looked up
 }
 
-func NewClient(botToken string, logger *slog.Logger) *Client {
+func NewClient(apiURL, botToken string, logger *slog.Logger) *Client {
+	api := slackapi.New(apiURL, botToken, logger)
 	return &Client{
-		botToken: botToken,
-		logger:   logger,
+		api:    api,
+		once:   api.WithoutRetries(),
+		logger: logger,
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 	}
 }
 
-func (c *Client) PostMessage(ctx context.Context, channel, text string, threadTS ...string) error {
+// Names returns the workspace's name cache, or nil if the client wasn't
+// created by Clients
+func (c *Client) Names() *Names {
+	return c.names
+}
+
+// PostMessage posts a plain text message and returns its timestamp
+func (c *Client) PostMessage(ctx context.Context, channel, text string, threadTS ...string) (string, error) {
 	payload := MessageResponse{
 		Channel: channel,
 		Text:    text,
 	}
-	
+
 	// Add thread_ts if provided
 	if len(threadTS) > 0 && threadTS[0] != "" {
 		payload.ThreadTS = threadTS[0]
 	}
 
-	jsonData, err := json.Marshal(payload)
+	return c.Post(ctx, payload)
+}
+
+// UpdateMessage replaces the content of the message identified by
+// payload.Channel and payload.TS
+func (c *Client) UpdateMessage(ctx context.Context, payload MessageResponse) error {
+	if _, err := c.callMessageAPI(ctx, "chat.update", payload); err != nil {
+		return fmt.Errorf("failed to update message: %w", err)
+	}
+
+	c.logger.Info("Message updated in Slack", "channel", payload.Channel, "ts", payload.TS)
+	return nil
+}
+
+// UpdateMessageOnce is UpdateMessage without retries, for progress updates
+// that the next one replaces anyway
+func (c *Client) UpdateMessageOnce(ctx context.Context, payload MessageResponse) error {
+	if err := c.once.Call(ctx, "chat.update", payload, nil); err != nil {
+		return fmt.Errorf("failed to update message: %w", err)
+	}
+	return nil
+}
+
+// DeleteMessage deletes one of the bot's messages
+func (c *Client) DeleteMessage(ctx context.Context, channel, ts string) error {
+	payload := map[string]string{"channel": channel, "ts": ts}
+	if _, err := c.callMessageAPI(ctx, "chat.delete", payload); err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+
+	c.logger.Info("Message deleted from Slack", "channel", channel, "ts", ts)
+	return nil
+}
+
+// Post calls chat.postMessage with a fully built message and returns the
+// posted message's timestamp
+func (c *Client) Post(ctx context.Context, payload MessageResponse) (string, error) {
+	postResp, err := c.callMessageAPI(ctx, "chat.postMessage", payload)
+	if err != nil {
+		return "", err
+	}
+
+	c.logger.Info("Message posted to Slack", "channel", payload.Channel, "ts", postResp.TS)
+	return postResp.TS, nil
+}
+
+// PostEphemeral posts a message only user can see and returns its
+// timestamp. Ephemeral messages can't carry metadata or be edited later.
+func (c *Client) PostEphemeral(ctx context.Context, user string, payload MessageResponse) (string, error) {
+	payload.Metadata = nil
+	postResp, err := c.callMessageAPI(ctx, "chat.postEphemeral", struct {
+		MessageResponse
+		User string `json:"user"`
+	}{payload, user})
+	if err != nil {
+		return "", err
+	}
+
+	c.logger.Info("Ephemeral message posted to Slack", "channel", payload.Channel, "user", user)
+	return postResp.MessageTS, nil
+}
+
+// RateLimitWait reports whether err is a rate limited call that outlasted the
+// client's retries, and how long Slack asked to wait
+func RateLimitWait(err error) (time.Duration, bool) {
+	if !slackapi.IsRateLimited(err) {
+		return 0, false
+	}
+	var httpErr *slackapi.HTTPError
+	errors.As(err, &httpErr)
+	return httpErr.RetryAfter, true
+}
+
+// callMessageAPI POSTs a JSON payload to one of the chat.* methods
+func (c *Client) callMessageAPI(ctx context.Context, method string, payload any) (*slackapi.PostedMessage, error) {
+	var posted slackapi.PostedMessage
+	if err := c.api.Call(ctx, method, payload, &posted); err != nil {
+		return nil, err
+	}
+	return &posted, nil
+}
+
+// GetThreadReplies returns every message in a thread, parent first, following
+// pagination cursors until the thread is exhausted.
+func (c *Client) GetThreadReplies(ctx context.Context, channel, threadTS string) ([]Message, error) {
+	var messages []Message
+	cursor := ""
+
+	for {
+		params := url.Values{}
+		params.Set("channel", channel)
+		params.Set("ts", threadTS)
+		params.Set("limit", "200")
+		params.Set("include_all_metadata", "true")
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+
+		repliesResp, err := c.conversationsReplies(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, repliesResp.Messages...)
+
+		cursor = repliesResp.ResponseMetadata.NextCursor
+		if !repliesResp.HasMore || cursor == "" {
+			break
+		}
+	}
+
+	c.logger.Info("Fetched thread replies from Slack", "channel", channel, "thread_ts", threadTS, "count", len(messages))
+	return messages, nil
+}
+
+// GetMessage fetches a single message, top-level or in a thread, including
+// its metadata. It returns nil if the message doesn't exist.
+func (c *Client) GetMessage(ctx context.Context, channel, ts string) (*Message, error) {
+	// conversations.replies accepts any message in a thread as ts and always
+	// returns the parent, so narrow the window to the message itself
+	params := url.Values{}
+	params.Set("channel", channel)
+	params.Set("ts", ts)
+	params.Set("oldest", ts)
+	params.Set("latest", ts)
+	params.Set("inclusive", "true")
+	params.Set("include_all_metadata", "true")
+
+	repliesResp, err := c.conversationsReplies(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, message := range repliesResp.Messages {
+		if message.TS == ts {
+			return &message, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetRecentMessages returns up to limit top-level messages of a channel posted
+// before the given timestamp, newest first
+func (c *Client) GetRecentMessages(ctx context.Context, channel, before string, limit int) ([]Message, error) {
+	params := url.Values{}
+	params.Set("channel", channel)
+	params.Set("latest", before)
+	params.Set("limit", strconv.Itoa(limit))
+
+	var historyResp RepliesResponse
+	if err := c.api.Get(ctx, "conversations.history", params, &historyResp); err != nil {
+		return nil, fmt.Errorf("failed to fetch channel history: %w", err)
+	}
+	return historyResp.Messages, nil
+}
+
+func (c *Client) conversationsReplies(ctx context.Context, params url.Values) (*RepliesResponse, error) {
+	var repliesResp RepliesResponse
+	if err := c.api.Get(ctx, "conversations.replies", params, &repliesResp); err != nil {
+		return nil, fmt.Errorf("failed to fetch thread replies: %w", err)
+	}
+	return &repliesResp, nil
+}
+
+// GetUser fetches a user's profile (users.info)
+func (c *Client) GetUser(ctx context.Context, userID string) (*User, error) {
+	var infoResp UserInfoResponse
+	if err := c.api.Get(ctx, "users.info", url.Values{"user": {userID}}, &infoResp); err != nil {
+		return nil, err
+	}
+	return &infoResp.User, nil
+}
+
+// GetConversation fetches a channel's details (conversations.info)
+func (c *Client) GetConversation(ctx context.Context, channelID string) (*Conversation, error) {
+	var infoResp ConversationInfoResponse
+	if err := c.api.Get(ctx, "conversations.info", url.Values{"channel": {channelID}}, &infoResp); err != nil {
+		return nil, err
+	}
+	return &infoResp.Channel, nil
+}
+
+// DownloadFile downloads a file shared in Slack. Files larger than maxBytes
+// are rejected.
+func (c *Client) DownloadFile(ctx context.Context, fileURL string, maxBytes int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.api.Token())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("file download error: %d", resp.StatusCode)
+	}
+
+	// Without the files:read scope Slack answers with its login page
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return nil, fmt.Errorf("file download returned a web page; check the files:read scope")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("file is larger than %d bytes", maxBytes)
+	}
+	return data, nil
+}
+
+// RespondToURL posts a delayed reply to the response_url of a slash command
+// or interaction
+func (c *Client) RespondToURL(ctx context.Context, responseURL string, response CommandResponse) error {
+	jsonData, err := json.Marshal(response)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return fmt.Errorf("failed to marshal command response: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://slack.com/api/chat.postMessage", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", responseURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.botToken)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+		return fmt.Errorf("failed to send response: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -62,6 +302,73 @@ func (c *Client) PostMessage(ctx context.Context, channel, text string, threadTS
 		return fmt.Errorf("slack API error: %d - %s", resp.StatusCode, string(body))
 	}
 
-	c.logger.Info("Message posted to Slack", "channel", channel)
+	c.logger.Info("Response sent to Slack response_url")
+	return nil
+}
+
+// OpenView opens a modal in response to an interaction's trigger_id
+func (c *Client) OpenView(ctx context.Context, triggerID string, view View) error {
+	payload := map[string]any{
+		"trigger_id": triggerID,
+		"view":       view,
+	}
+
+	if err := c.api.Call(ctx, "views.open", payload, nil); err != nil {
+		return fmt.Errorf("failed to open view: %w", err)
+	}
+
+	c.logger.Info("View opened in Slack", "callback_id", view.CallbackID)
 	return nil
 }
+
+// PublishView publishes a user's App Home tab (views.publish)
+func (c *Client) PublishView(ctx context.Context, userID string, view View) error {
+	payload := map[string]any{
+		"user_id": userID,
+		"view":    view,
+	}
+
+	if err := c.api.Call(ctx, "views.publish", payload, nil); err != nil {
+		return fmt.Errorf("failed to publish view: %w", err)
+	}
+
+	c.logger.Info("View published in Slack", "user", userID)
+	return nil
+}
+
+// TeamID returns the ID of the workspace the client's token belongs to
+// (auth.test)
+func (c *Client) TeamID(ctx context.Context) (string, error) {
+	var authResp AuthTestResponse
+	if err := c.api.Get(ctx, "auth.test", url.Values{}, &authResp); err != nil {
+		return "", fmt.Errorf("failed to identify workspace: %w", err)
+	}
+	return authResp.TeamID, nil
+}
+
+// WorkspaceURL returns the address of the workspace the client's token
+// belongs to, such as https://acme.slack.com/, looking it up with auth.test
+// the first time
+func (c *Client) WorkspaceURL(ctx context.Context) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.workspaceURL == "" {
+		var authResp AuthTestResponse
+		if err := c.api.Get(ctx, "auth.test", url.Values{}, &authResp); err != nil {
+			return "", fmt.Errorf("failed to identify workspace: %w", err)
+		}
+		c.workspaceURL = authResp.URL
+	}
+	return c.workspaceURL, nil
+}
+
+// OpenDirectMessage returns the ID of the DM between Wavie and a user,
+// opening it if needed (conversations.open)
+func (c *Client) OpenDirectMessage(ctx context.Context, userID string) (string, error) {
+	var openResp ConversationInfoResponse
+	if err := c.api.Call(ctx, "conversations.open", map[string]string{"users": userID}, &openResp); err != nil {
+		return "", fmt.Errorf("failed to open direct message: %w", err)
+	}
+	return openResp.Channel.ID, nil
+}
@@ -0,0 +1,84 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// TokenSource finds the bot token of the workspace an event came from. It
+// returns "" if Wavie isn't installed there.
+type TokenSource interface {
+	BotToken(ctx context.Context, enterpriseID, teamID string) (string, error)
+}
+
+// Clients hands out a Web API client for each workspace Wavie is installed
+// in, so every request is made with that workspace's own bot token
+type Clients struct {
+	apiURL       string
+	tokens       TokenSource
+	defaultToken string
+	homeTeamID   string
+	nameTTL      time.Duration
+	logger       *slog.Logger
+
+	mutex   sync.Mutex
+	clients map[string]*Client // by bot token
+}
+
+// NewClients creates a pool of clients for the Web API at apiURL. defaultToken is the token of homeTeamID,
+// the workspace Wavie was first set up in, and is used for it when tokens
+// (which may be nil) doesn't know it; it may be empty when every workspace
+// installs through OAuth. Each client caches names for nameTTL.
+func NewClients(apiURL string, tokens TokenSource, defaultToken, homeTeamID string, nameTTL time.Duration, logger *slog.Logger) *Clients {
+	return &Clients{
+		apiURL:       apiURL,
+		tokens:       tokens,
+		defaultToken: defaultToken,
+		homeTeamID:   homeTeamID,
+		nameTTL:      nameTTL,
+		logger:       logger,
+		clients:      make(map[string]*Client),
+	}
+}
+
+// HomeTeamID returns the ID of the workspace Wavie was first set up in, or ""
+// if it isn't known
+func (c *Clients) HomeTeamID() string {
+	return c.homeTeamID
+}
+
+// ForTeam returns the client for a workspace, or for an Enterprise Grid
+// organization when Wavie is installed org-wide. Workspaces without an
+// installation get an error, never another workspace's token.
+func (c *Clients) ForTeam(ctx context.Context, enterpriseID, teamID string) (*Client, error) {
+	token := ""
+	if c.tokens != nil {
+		var err error
+		token, err = c.tokens.BotToken(ctx, enterpriseID, teamID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up bot token: %w", err)
+		}
+	}
+	if token == "" && teamID != "" && teamID == c.homeTeamID {
+		token = c.defaultToken
+	}
+	if token == "" {
+		return nil, fmt.Errorf("wavie is not installed in team %s (enterprise %s)", teamID, enterpriseID)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Clients are kept per token, so a reinstall that issues a new token
+	// starts with a fresh name cache
+	client, ok := c.clients[token]
+	if !ok {
+		client = NewClient(c.apiURL, token, c.logger)
+		client.names = NewNames(client, c.nameTTL, c.logger)
+		c.clients[token] = client
+	}
+	return client, nil
+}
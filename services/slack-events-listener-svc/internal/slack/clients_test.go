@@ -0,0 +1,53 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type fakeTokens map[string]string
+
+func (f fakeTokens) BotToken(ctx context.Context, enterpriseID, teamID string) (string, error) {
+	if teamID == "T_BROKEN" {
+		return "", errors.New("store unavailable")
+	}
+	return f[teamID], nil
+}
+
+func TestForTeam(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name      string
+		tokens    TokenSource
+		teamID    string
+		wantToken string // "" means an error
+	}{
+		{"installed workspace", fakeTokens{"T_OTHER": "xoxb-other"}, "T_OTHER", "xoxb-other"},
+		{"installed home workspace", fakeTokens{"T_HOME": "xoxb-installed"}, "T_HOME", "xoxb-installed"},
+		{"home workspace without an installation", fakeTokens{}, "T_HOME", "xoxb-home"},
+		{"home workspace without a store", nil, "T_HOME", "xoxb-home"},
+		{"unknown workspace", fakeTokens{}, "T_OTHER", ""},
+		{"unknown workspace without a store", nil, "T_OTHER", ""},
+		{"event without a workspace", nil, "", ""},
+		{"store error", fakeTokens{}, "T_BROKEN", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := NewClients("", tt.tokens, "xoxb-home", "T_HOME", time.Hour, logger)
+			client, err := clients.ForTeam(context.Background(), "", tt.teamID)
+			switch {
+			case tt.wantToken == "" && err == nil:
+				t.Errorf("ForTeam(%q) used token %q, want an error", tt.teamID, client.api.Token())
+			case tt.wantToken != "" && err != nil:
+				t.Errorf("ForTeam(%q) failed: %v", tt.teamID, err)
+			case tt.wantToken != "" && client.api.Token() != tt.wantToken:
+				t.Errorf("ForTeam(%q) used token %q, want %q", tt.teamID, client.api.Token(), tt.wantToken)
+			}
+		})
+	}
+}
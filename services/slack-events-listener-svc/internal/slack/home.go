@@ -0,0 +1,114 @@
+package slack
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ActionHomePrompt prefixes the action IDs of the quick-start buttons on the
+// Home tab; Slack requires them to be unique within a block
+const ActionHomePrompt = "home_prompt"
+
+// How much of a question the Home tab shows
+const homeSnippetChars = 150
+
+// HomeEntry is a past question or piece of feedback listed on the Home tab
+type HomeEntry struct {
+	Text string // plain text, escaped and shortened for display
+	Note string // mrkdwn shown under the text, such as the feedback given
+	Link string // permalink to the message, if known
+	At   time.Time
+}
+
+// Home is the content of a user's App Home tab
+type Home struct {
+	Questions []HomeEntry
+	Feedback  []HomeEntry
+	Quota     string // mrkdwn
+	Prompts   []string
+}
+
+// HomeView lays out a user's Home tab: quick-start prompts, their remaining
+// quota, their recent questions and the feedback they gave
+func HomeView(home Home) View {
+	blocks := []MessageBlock{
+		header("Ask Wavie"),
+		section("Mention @Wavie in any channel, send it a direct message, or use `/wavie ask`. Or start with one of these:"),
+	}
+
+	if len(home.Prompts) > 0 {
+		var buttons []BlockElement
+		for i, prompt := range home.Prompts {
+			buttons = append(buttons, button(fmt.Sprintf("%s_%d", ActionHomePrompt, i), shorten(prompt, 75), prompt, ""))
+		}
+		blocks = append(blocks, MessageBlock{Type: "actions", BlockID: "wavie_home_prompts", Elements: buttons})
+	}
+
+	blocks = append(blocks,
+		MessageBlock{Type: "divider"},
+		header("Your usage today"),
+		section(home.Quota),
+		MessageBlock{Type: "divider"},
+		header("Your recent questions"))
+	blocks = append(blocks, homeEntries(home.Questions, "_You haven't asked Wavie anything recently._")...)
+
+	blocks = append(blocks,
+		MessageBlock{Type: "divider"},
+		header("Your feedback"))
+	blocks = append(blocks, homeEntries(home.Feedback, "_You haven't rated any answers recently._")...)
+
+	return View{
+		Type:   "home",
+		Blocks: blocks,
+	}
+}
+
+// homeEntries lists entries as sections, or empty when there are none
+func homeEntries(entries []HomeEntry, empty string) []MessageBlock {
+	if len(entries) == 0 {
+		return []MessageBlock{section(empty)}
+	}
+
+	var blocks []MessageBlock
+	for _, entry := range entries {
+		text := "*" + entry.Text + "*"
+		if entry.Note != "" {
+			text += "\n" + entry.Note
+		}
+		text += "\n" + fmt.Sprintf("<!date^%d^{date_short_pretty} at {time}|%s>", entry.At.Unix(), entry.At.UTC().Format("Jan 2 15:04 UTC"))
+		if entry.Link != "" {
+			text += " · <" + entry.Link + "|Open>"
+		}
+		blocks = append(blocks, section(text))
+	}
+	return blocks
+}
+
+// HomeSnippet prepares user text for the Home tab: on one line, shortened,
+// and escaped so it can't break the surrounding mrkdwn
+func HomeSnippet(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	text = shorten(text, homeSnippetChars)
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "*", "").Replace(text)
+}
+
+// shorten cuts text to at most limit characters, marking the cut
+func shorten(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit-1]) + "…"
+}
+
+func header(text string) MessageBlock {
+	return MessageBlock{Type: "header", Text: plainText(text)}
+}
+
+func section(text string) MessageBlock {
+	return MessageBlock{
+		Type: "section",
+		Text: &TextObject{Type: "mrkdwn", Text: text},
+	}
+}
@@ -0,0 +1,132 @@
+package slack
+
+import (
+	"container/list"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// maxNames bounds the cache; the least recently used names are evicted
+	maxNames = 10000
+	// failedNameTTL is how long a failed lookup is remembered, so a deleted
+	// user or a channel Wavie can't see isn't looked up for every message
+	failedNameTTL = 30 * time.Second
+)
+
+type cachedName struct {
+	key     string
+	name    string
+	expires time.Time
+}
+
+// Names resolves user and channel IDs to display names, caching them so a
+// busy thread doesn't call users.info for every message
+type Names struct {
+	client *Client
+	ttl    time.Duration
+	logger *slog.Logger
+
+	mutex sync.Mutex
+	order *list.List // of *cachedName, most recently used first
+	cache map[string]*list.Element
+}
+
+// NewNames creates a name resolver that caches names for ttl
+func NewNames(client *Client, ttl time.Duration, logger *slog.Logger) *Names {
+	return &Names{
+		client: client,
+		ttl:    ttl,
+		logger: logger,
+		order:  list.New(),
+		cache:  make(map[string]*list.Element),
+	}
+}
+
+// UserName returns a user's display name, falling back to their real name
+// and then their username. It returns "" if the user can't be looked up.
+func (n *Names) UserName(ctx context.Context, userID string) string {
+	return n.resolve("user:"+userID, func() (string, error) {
+		user, err := n.client.GetUser(ctx, userID)
+		if err != nil {
+			return "", err
+		}
+		for _, name := range []string{user.Profile.DisplayName, user.Profile.RealName, user.Name} {
+			if name != "" {
+				return name, nil
+			}
+		}
+		return "", nil
+	})
+}
+
+// ChannelName returns a channel's name, or "" if it can't be looked up
+func (n *Names) ChannelName(ctx context.Context, channelID string) string {
+	return n.resolve("channel:"+channelID, func() (string, error) {
+		channel, err := n.client.GetConversation(ctx, channelID)
+		if err != nil {
+			return "", err
+		}
+		return channel.Name, nil
+	})
+}
+
+// resolve returns the cached name for key, looking it up if it is missing or
+// expired. Failed lookups are cached as "" for failedNameTTL.
+func (n *Names) resolve(key string, lookup func() (string, error)) string {
+	if name, ok := n.get(key); ok {
+		return name
+	}
+
+	name, err := lookup()
+	ttl := n.ttl
+	if err != nil {
+		n.logger.Warn("Failed to resolve name", "error", err, "id", key)
+		ttl = failedNameTTL
+	}
+	n.put(key, name, ttl)
+
+	return name
+}
+
+// get returns an unexpired cached name and marks it as recently used
+func (n *Names) get(key string) (string, bool) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	element, ok := n.cache[key]
+	if !ok {
+		return "", false
+	}
+	cached := element.Value.(*cachedName)
+	if !time.Now().Before(cached.expires) {
+		n.order.Remove(element)
+		delete(n.cache, key)
+		return "", false
+	}
+	n.order.MoveToFront(element)
+	return cached.name, true
+}
+
+// put caches a name for ttl, evicting the least recently used name if the
+// cache is full
+func (n *Names) put(key, name string, ttl time.Duration) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	cached := &cachedName{key: key, name: name, expires: time.Now().Add(ttl)}
+	if element, ok := n.cache[key]; ok {
+		element.Value = cached
+		n.order.MoveToFront(element)
+		return
+	}
+
+	n.cache[key] = n.order.PushFront(cached)
+	if n.order.Len() > maxNames {
+		oldest := n.order.Back()
+		n.order.Remove(oldest)
+		delete(n.cache, oldest.Value.(*cachedName).key)
+	}
+}
@@ -0,0 +1,86 @@
+package slack
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func newTestNames(ttl time.Duration) *Names {
+	return NewNames(nil, ttl, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestNamesAreCachedUntilTheyExpire(t *testing.T) {
+	n := newTestNames(time.Hour)
+
+	lookups := 0
+	lookup := func() (string, error) {
+		lookups++
+		return "alice", nil
+	}
+	for range 3 {
+		if name := n.resolve("user:U1", lookup); name != "alice" {
+			t.Fatalf("resolve = %q, want alice", name)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("looked up %d times, want 1", lookups)
+	}
+
+	n.put("user:U1", "alice", -time.Second)
+	n.resolve("user:U1", lookup)
+	if lookups != 2 {
+		t.Errorf("expired name wasn't looked up again")
+	}
+}
+
+func TestFailedLookupsAreCachedBriefly(t *testing.T) {
+	n := newTestNames(time.Hour)
+
+	lookups := 0
+	lookup := func() (string, error) {
+		lookups++
+		return "", errors.New("user_not_found")
+	}
+	for range 3 {
+		if name := n.resolve("user:U1", lookup); name != "" {
+			t.Fatalf("resolve = %q, want \"\"", name)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("looked up %d times, want 1", lookups)
+	}
+
+	n.mutex.Lock()
+	expires := n.cache["user:U1"].Value.(*cachedName).expires
+	n.mutex.Unlock()
+	if time.Until(expires) > failedNameTTL {
+		t.Errorf("failed lookup cached for %v, want at most %v", time.Until(expires), failedNameTTL)
+	}
+}
+
+func TestLeastRecentlyUsedNamesAreEvicted(t *testing.T) {
+	n := newTestNames(time.Hour)
+
+	for i := range maxNames {
+		n.put(fmt.Sprintf("user:U%d", i), "name", time.Hour)
+	}
+	// Touch the oldest so the second oldest is evicted instead
+	if _, ok := n.get("user:U0"); !ok {
+		t.Fatal("user:U0 missing before the cache was full")
+	}
+	n.put("user:new", "name", time.Hour)
+
+	if n.order.Len() != maxNames || len(n.cache) != maxNames {
+		t.Errorf("cache holds %d names (%d in the map), want %d", n.order.Len(), len(n.cache), maxNames)
+	}
+	if _, ok := n.get("user:U0"); !ok {
+		t.Error("recently used name was evicted")
+	}
+	if _, ok := n.get("user:U1"); ok {
+		t.Error("least recently used name wasn't evicted")
+	}
+}
@@ -0,0 +1,89 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/BitwaveCorp/shared-svcs/shared/slackapi"
+)
+
+// OAuth is the app's OAuth v2 configuration, used to install Wavie in other
+// workspaces
+type OAuth struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       string // comma separated bot scopes
+	api          *slackapi.Client
+}
+
+// NewOAuth creates the OAuth configuration for an app, exchanging codes with
+// the Web API at apiURL
+func NewOAuth(apiURL, clientID, clientSecret, redirectURL, scopes string, logger *slog.Logger) *OAuth {
+	return &OAuth{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       scopes,
+		// oauth.v2.access authenticates with the client credentials, not a token
+		api: slackapi.New(apiURL, "", logger),
+	}
+}
+
+// OAuthAccessResponse is the response body of oauth.v2.access
+type OAuthAccessResponse struct {
+	AccessToken         string      `json:"access_token"`
+	TokenType           string      `json:"token_type"`
+	Scope               string      `json:"scope"`
+	BotUserID           string      `json:"bot_user_id"`
+	AppID               string      `json:"app_id"`
+	Team                *OAuthOwner `json:"team"`
+	Enterprise          *OAuthOwner `json:"enterprise"`
+	IsEnterpriseInstall bool        `json:"is_enterprise_install"`
+	AuthedUser          struct {
+		ID string `json:"id"`
+	} `json:"authed_user"`
+}
+
+// OAuthOwner is the workspace or organization an app was installed in
+type OAuthOwner struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// AuthorizeURL is the Slack page that asks a user to install the app. state
+// comes back unchanged on the redirect.
+func (o *OAuth) AuthorizeURL(state string) string {
+	params := url.Values{}
+	params.Set("client_id", o.ClientID)
+	params.Set("scope", o.Scopes)
+	params.Set("state", state)
+	if o.RedirectURL != "" {
+		params.Set("redirect_uri", o.RedirectURL)
+	}
+	return "https://slack.com/oauth/v2/authorize?" + params.Encode()
+}
+
+// Exchange trades the code from the OAuth redirect for the workspace's bot
+// token (oauth.v2.access)
+func (o *OAuth) Exchange(ctx context.Context, code string) (*OAuthAccessResponse, error) {
+	params := url.Values{}
+	params.Set("client_id", o.ClientID)
+	params.Set("client_secret", o.ClientSecret)
+	params.Set("code", code)
+	if o.RedirectURL != "" {
+		params.Set("redirect_uri", o.RedirectURL)
+	}
+
+	var accessResp OAuthAccessResponse
+	if err := o.api.CallForm(ctx, "oauth.v2.access", params, &accessResp); err != nil {
+		return nil, err
+	}
+	if accessResp.TokenType != "bot" || accessResp.AccessToken == "" {
+		return nil, fmt.Errorf("oauth.v2.access returned no bot token")
+	}
+
+	return &accessResp, nil
+}
@@ -0,0 +1,127 @@
+package slack
+
+import (
+	"context"
+	"strings"
+)
+
+// Slack escapes these in message text; everything between < and > is markup
+var entities = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+
+// PlainText converts Slack message markup into readable text for Claude.
+// Mentions of botUserID (Wavie itself) are removed; other user and channel
+// mentions become @name and #name, using names to look up names Slack didn't
+// include. Links keep both their label and URL, and entities are unescaped.
+// names may be nil, in which case only names included in the markup are used.
+func PlainText(ctx context.Context, text, botUserID string, names *Names) string {
+	var out strings.Builder
+	for {
+		start := strings.IndexByte(text, '<')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start:], '>')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		out.WriteString(entities.Replace(text[:start]))
+		token := text[start+1 : end]
+		text = text[end+1:]
+
+		if isMentionOf(token, botUserID) {
+			// Don't leave a double space where the mention was
+			if strings.HasSuffix(out.String(), " ") || out.Len() == 0 {
+				text = strings.TrimPrefix(text, " ")
+			}
+			continue
+		}
+		out.WriteString(renderToken(ctx, token, names))
+	}
+	out.WriteString(entities.Replace(text))
+
+	return strings.TrimSpace(out.String())
+}
+
+// Mentions reports whether text mentions userID
+func Mentions(text, userID string) bool {
+	if userID == "" {
+		return false
+	}
+	for {
+		start := strings.IndexByte(text, '<')
+		if start < 0 {
+			return false
+		}
+		end := strings.IndexByte(text[start:], '>')
+		if end < 0 {
+			return false
+		}
+		if isMentionOf(text[start+1:start+end], userID) {
+			return true
+		}
+		text = text[start+end+1:]
+	}
+}
+
+// isMentionOf reports whether a markup token such as "@U123" or
+// "@U123|wavie" mentions userID
+func isMentionOf(token, userID string) bool {
+	if userID == "" || !strings.HasPrefix(token, "@") {
+		return false
+	}
+	id, _, _ := strings.Cut(token[1:], "|")
+	return id == userID
+}
+
+// renderToken converts the markup between < and > into text
+func renderToken(ctx context.Context, token string, names *Names) string {
+	value, label, hasLabel := strings.Cut(token, "|")
+	label = entities.Replace(label)
+
+	switch {
+	case strings.HasPrefix(value, "@"):
+		// <@U123> or <@U123|name>
+		name := label
+		if name == "" && names != nil {
+			name = names.UserName(ctx, value[1:])
+		}
+		if name == "" {
+			name = value[1:]
+		}
+		return "@" + strings.TrimPrefix(name, "@")
+
+	case strings.HasPrefix(value, "#"):
+		// <#C123> or <#C123|general>
+		name := label
+		if name == "" && names != nil {
+			name = names.ChannelName(ctx, value[1:])
+		}
+		if name == "" {
+			name = value[1:]
+		}
+		return "#" + name
+
+	case strings.HasPrefix(value, "!"):
+		// <!here>, <!subteam^S123|@team>, <!date^…|fallback>
+		if hasLabel {
+			return label
+		}
+		command, _, _ := strings.Cut(value[1:], "^")
+		return "@" + command
+
+	default:
+		// <https://…>, <https://…|label>, <mailto:…|address>
+		link := entities.Replace(value)
+		switch {
+		case label == "":
+			return link
+		case label == link, strings.HasSuffix(link, ":"+label), strings.HasSuffix(link, "://"+label):
+			// Slack links bare domains and addresses itself; keep what was typed
+			return label
+		default:
+			return label + " (" + link + ")"
+		}
+	}
+}
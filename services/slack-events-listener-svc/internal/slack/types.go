@@ -1,6 +1,12 @@
 package slack
 
-import "time"
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // EventRequest represents a Slack event request
 type EventRequest struct {
@@ -8,6 +14,7 @@ type EventRequest struct {
 	Challenge   string   `json:"challenge"`
 	Type        string   `json:"type"`
 	TeamID      string   `json:"team_id"`
+	EnterpriseID string  `json:"enterprise_id,omitempty"`
 	APIAppID    string   `json:"api_app_id"`
 	Event       Event    `json:"event"`
 	EventID     string   `json:"event_id"`
@@ -17,15 +24,36 @@ type EventRequest struct {
 
 type Event struct {
 	Type     string `json:"type"`
+	Subtype  string `json:"subtype,omitempty"`
 	User     string `json:"user"`
 	Text     string `json:"text"`
 	Channel  string `json:"channel"`
+	ChannelType string `json:"channel_type,omitempty"`
 	TS       string `json:"ts"`
 	ThreadTS string `json:"thread_ts,omitempty"`
 	EventTS  string `json:"event_ts"`
 	BotID    string `json:"bot_id,omitempty"`
 	Item     Item    `json:"item,omitempty"`
-	Reaction Reaction `json:"reaction,omitempty"`
+	Reaction string `json:"reaction,omitempty"`
+	ItemUser string `json:"item_user,omitempty"` // reaction_added: author of the reacted-to message
+	Files    []File `json:"files,omitempty"`
+	Tab      string `json:"tab,omitempty"` // app_home_opened: "home" or "messages"
+	Tokens   *RevokedTokens `json:"tokens,omitempty"` // tokens_revoked
+}
+
+// RevokedTokens lists the users whose tokens were revoked, by token type
+type RevokedTokens struct {
+	OAuth []string `json:"oauth,omitempty"`
+	Bot   []string `json:"bot,omitempty"`
+}
+
+// File is a file shared with a message
+type File struct {
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	Mimetype           string `json:"mimetype"`
+	Size               int64  `json:"size"`
+	URLPrivateDownload string `json:"url_private_download"`
 }
 
 type Item struct {
@@ -34,13 +62,6 @@ type Item struct {
 	TS      string `json:"ts"`
 }
 
-type Reaction struct {
-	Type    string `json:"type"`
-	User    string `json:"user"`
-	Reaction string `json:"reaction"`
-	Item    Item    `json:"item"`
-}
-
 type Auth struct {
 	EnterpriseID        string `json:"enterprise_id"`
 	TeamID              string `json:"team_id"`
@@ -50,9 +71,250 @@ type Auth struct {
 }
 
 type MessageResponse struct {
-	Channel  string `json:"channel"`
-	Text     string `json:"text"`
-	ThreadTS string `json:"thread_ts,omitempty"`
+	Channel  string           `json:"channel"`
+	TS       string           `json:"ts,omitempty"` // set for chat.update
+	Text     string           `json:"text"`
+	ThreadTS string           `json:"thread_ts,omitempty"`
+	Blocks   []MessageBlock   `json:"blocks,omitempty"`
+	Metadata *MessageMetadata `json:"metadata,omitempty"`
+}
+
+// AnswerEventType is the metadata event type attached to Wavie's answers
+const AnswerEventType = "wavie_answer"
+
+// MessageMetadata is Slack message metadata. Wavie only attaches metadata to
+// its answers, so the payload is always an AnswerMetadata.
+type MessageMetadata struct {
+	EventType    string         `json:"event_type"`
+	EventPayload AnswerMetadata `json:"event_payload"`
+}
+
+// AnswerMetadata travels with an answer in Slack so any instance can tell
+// which request produced it
+type AnswerMetadata struct {
+	CorrelationID string `json:"correlation_id"`
+	Model         string `json:"model,omitempty"`
+	PromptVersion string `json:"prompt_version,omitempty"`
+}
+
+// Message is a message returned by the conversations.* Web API methods
+type Message struct {
+	Type     string           `json:"type"`
+	Subtype  string           `json:"subtype,omitempty"`
+	User     string           `json:"user"`
+	BotID    string           `json:"bot_id,omitempty"`
+	Text     string           `json:"text"`
+	TS       string           `json:"ts"`
+	ThreadTS string           `json:"thread_ts,omitempty"`
+	Metadata *MessageMetadata `json:"metadata,omitempty"`
+}
+
+// RepliesResponse is the response body of conversations.replies
+type RepliesResponse struct {
+	Messages         []Message        `json:"messages"`
+	HasMore          bool             `json:"has_more"`
+	ResponseMetadata ResponseMetadata `json:"response_metadata"`
+}
+
+type ResponseMetadata struct {
+	NextCursor string `json:"next_cursor"`
+}
+
+// User is the part of a users.info user that Wavie uses
+type User struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Profile struct {
+		DisplayName string `json:"display_name"`
+		RealName    string `json:"real_name"`
+	} `json:"profile"`
+}
+
+// UserInfoResponse is the response body of users.info
+type UserInfoResponse struct {
+	User User `json:"user"`
+}
+
+// Conversation is the part of a conversations.info channel that Wavie uses
+type Conversation struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	IsPrivate bool   `json:"is_private"`
+}
+
+// ConversationInfoResponse is the response body of conversations.info and
+// conversations.open
+type ConversationInfoResponse struct {
+	Channel Conversation `json:"channel"`
+}
+
+// AuthTestResponse is the response body of auth.test
+type AuthTestResponse struct {
+	URL    string `json:"url"` // the workspace, e.g. https://acme.slack.com/
+	TeamID string `json:"team_id"`
+	UserID string `json:"user_id"`
+}
+
+// ParseTimestamp converts a Slack message timestamp ("1700000000.123456") to a time
+func ParseTimestamp(ts string) time.Time {
+	seconds, err := strconv.ParseFloat(ts, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMicro(int64(seconds * 1e6))
+}
+
+// IsDirectMessage reports whether a channel ID is a direct message with
+// Wavie; Slack gives those IDs a "D" prefix
+func IsDirectMessage(channelID string) bool {
+	return strings.HasPrefix(channelID, "D")
+}
+
+// SlashCommand is the form-encoded payload Slack sends for a slash command
+type SlashCommand struct {
+	Command     string
+	Text        string
+	TeamID      string
+	EnterpriseID string
+	ChannelID   string
+	ChannelName string
+	UserID      string
+	UserName    string
+	ResponseURL string
+	TriggerID   string
+}
+
+// ParseSlashCommand reads a slash command from its form values
+func ParseSlashCommand(values url.Values) SlashCommand {
+	return SlashCommand{
+		Command:     values.Get("command"),
+		Text:        values.Get("text"),
+		TeamID:      values.Get("team_id"),
+		EnterpriseID: values.Get("enterprise_id"),
+		ChannelID:   values.Get("channel_id"),
+		ChannelName: values.Get("channel_name"),
+		UserID:      values.Get("user_id"),
+		UserName:    values.Get("user_name"),
+		ResponseURL: values.Get("response_url"),
+		TriggerID:   values.Get("trigger_id"),
+	}
+}
+
+// CommandResponse is a message sent in reply to a slash command, either as
+// the HTTP response or later through the command's response_url
+type CommandResponse struct {
+	ResponseType    string         `json:"response_type,omitempty"` // "ephemeral" or "in_channel"
+	Text            string         `json:"text"`
+	Blocks          []MessageBlock `json:"blocks,omitempty"`
+	ReplaceOriginal bool           `json:"replace_original,omitempty"`
+}
+
+// InteractionPayload is the JSON sent in the "payload" form field of
+// interactivity requests (button clicks, modal submissions, shortcuts)
+type InteractionPayload struct {
+	Type        string               `json:"type"` // "block_actions", "view_submission", "message_action" or "shortcut"
+	CallbackID  string               `json:"callback_id,omitempty"` // shortcuts
+	TriggerID   string               `json:"trigger_id"`
+	ResponseURL string               `json:"response_url,omitempty"`
+	User        InteractionUser      `json:"user"`
+	Team        InteractionTeam      `json:"team"`
+	Enterprise  *InteractionTeam     `json:"enterprise,omitempty"`
+	Channel     InteractionChannel   `json:"channel"`
+	Container   InteractionContainer `json:"container"`
+	Message     *Message             `json:"message,omitempty"`
+	MessageTS   string               `json:"message_ts,omitempty"` // message shortcuts
+	Actions     []Action             `json:"actions,omitempty"`
+	View        *ViewPayload         `json:"view,omitempty"`
+}
+
+type InteractionUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	TeamID   string `json:"team_id"`
+}
+
+type InteractionTeam struct {
+	ID     string `json:"id"`
+	Domain string `json:"domain"`
+}
+
+type InteractionChannel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type InteractionContainer struct {
+	Type        string `json:"type"`
+	MessageTS   string `json:"message_ts"`
+	ChannelID   string `json:"channel_id"`
+	ThreadTS    string `json:"thread_ts,omitempty"`
+	IsEphemeral bool   `json:"is_ephemeral"`
+}
+
+type Action struct {
+	ActionID string `json:"action_id"`
+	BlockID  string `json:"block_id"`
+	Type     string `json:"type"`
+	Value    string `json:"value"`
+}
+
+// ViewPayload is the submitted state of a modal
+type ViewPayload struct {
+	ID              string    `json:"id"`
+	CallbackID      string    `json:"callback_id"`
+	PrivateMetadata string    `json:"private_metadata"`
+	State           ViewState `json:"state"`
+}
+
+type ViewState struct {
+	Values map[string]map[string]ViewStateValue `json:"values"`
+}
+
+type ViewStateValue struct {
+	Type           string  `json:"type"`
+	Value          string  `json:"value"`
+	SelectedOption *Option `json:"selected_option,omitempty"`
+}
+
+// MessageLink builds the permalink of a message in the workspace at
+// workspaceURL, the way chat.getPermalink does. Replies in a thread need the
+// thread's timestamp to open in it.
+func MessageLink(workspaceURL, channel, ts, threadTS string) string {
+	if workspaceURL == "" || channel == "" || ts == "" {
+		return ""
+	}
+	link := strings.TrimSuffix(workspaceURL, "/") + "/archives/" + channel + "/p" + strings.Replace(ts, ".", "", 1)
+	if threadTS != "" && threadTS != ts {
+		link += "?" + url.Values{"thread_ts": {threadTS}, "cid": {channel}}.Encode()
+	}
+	return link
+}
+
+// ParsePermalink extracts the channel and thread timestamp from a message
+// permalink such as https://acme.slack.com/archives/C123/p1700000000123456.
+// Links to thread replies resolve to the parent's timestamp.
+func ParsePermalink(link string) (channel, threadTS string, err error) {
+	link = strings.Trim(link, "<>")
+	if i := strings.Index(link, "|"); i >= 0 {
+		link = link[:i]
+	}
+
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid permalink: %w", err)
+	}
+
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "archives" || len(parts[2]) < 8 || parts[2][0] != 'p' {
+		return "", "", fmt.Errorf("not a Slack message permalink: %s", link)
+	}
+
+	if threadTS = parsed.Query().Get("thread_ts"); threadTS != "" {
+		return parts[1], threadTS, nil
+	}
+
+	digits := parts[2][1:]
+	return parts[1], digits[:len(digits)-6] + "." + digits[len(digits)-6:], nil
 }
 
 // Message represents a single message in a conversation for the Claude API
@@ -70,15 +332,92 @@ type ClaudeRequest struct {
 	ThreadTS           string               `json:"thread_ts,omitempty"`
 	ConversationHistory []ConversationMessage `json:"conversation_history,omitempty"`
 	CorrelationID      string               `json:"correlation_id"`
+	Attachments        []Attachment         `json:"attachments,omitempty"`
+
+	// From the channel policy: the proxy persona (system prompt) and model
+	// to answer with; empty means the proxy's defaults
+	Persona string `json:"persona,omitempty"`
+	Model   string `json:"model,omitempty"`
+}
+
+// Attachment is a file sent to Claude with a question; Data is base64 in JSON
+type Attachment struct {
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type"`
+	Data     []byte `json:"data"`
 }
 
 type ClaudeResponse struct {
 	Response      string `json:"response"`
 	CorrelationID string `json:"correlation_id"`
+	Model         string `json:"model,omitempty"`
+	PromptVersion string `json:"prompt_version,omitempty"`
+	InputTokens   int    `json:"input_tokens,omitempty"`
+	OutputTokens  int    `json:"output_tokens,omitempty"`
 	Error         string `json:"error,omitempty"`
 }
 
+// ClaudeStreamEvent is one line of the proxy's /api/chat/stream response:
+// "delta" events carry the next piece of the answer, and the stream ends with
+// a "done" event holding the whole answer or an "error" event
+type ClaudeStreamEvent struct {
+	Type          string `json:"type"`
+	Text          string `json:"text,omitempty"`
+	Response      string `json:"response,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	Model         string `json:"model,omitempty"`
+	PromptVersion string `json:"prompt_version,omitempty"`
+	InputTokens   int    `json:"input_tokens,omitempty"`
+	OutputTokens  int    `json:"output_tokens,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// ThreadMessage is one message of a thread sent to the proxy to summarize,
+// with its author and mentions resolved to names
+type ThreadMessage struct {
+	Author    string    `json:"author"`
+	Text      string    `json:"text"`
+	Timestamp time.Time `json:"timestamp,omitempty"`
+}
+
+// SummarizeRequest is the body of the proxy's /api/summarize
+type SummarizeRequest struct {
+	Messages      []ThreadMessage `json:"messages"`
+	UserID        string          `json:"user_id"`
+	ChannelID     string          `json:"channel_id"`
+	ThreadTS      string          `json:"thread_ts"`
+	CorrelationID string          `json:"correlation_id"`
+	Model         string          `json:"model,omitempty"`
+}
+
+// Summary is a thread's structured summary
+type Summary struct {
+	Overview      string       `json:"overview"`
+	Decisions     []string     `json:"decisions"`
+	OpenQuestions []string     `json:"open_questions"`
+	ActionItems   []ActionItem `json:"action_items"`
+}
+
+// ActionItem is a task someone took on, or was asked to, in a thread. Owner
+// is empty when nobody did.
+type ActionItem struct {
+	Owner string `json:"owner"`
+	Task  string `json:"task"`
+}
+
+type SummarizeResponse struct {
+	Summary       Summary `json:"summary"`
+	Chunks        int     `json:"chunks,omitempty"`
+	CorrelationID string  `json:"correlation_id"`
+	Model         string  `json:"model,omitempty"`
+	PromptVersion string  `json:"prompt_version,omitempty"`
+	InputTokens   int     `json:"input_tokens,omitempty"`
+	OutputTokens  int     `json:"output_tokens,omitempty"`
+	Error         string  `json:"error,omitempty"`
+}
+
 type BroadcastRequest struct {
+	TeamID        string    `json:"team_id"`
 	UserID        string    `json:"user_id"`
 	ChannelID     string    `json:"channel_id"`
 	ThreadID      string    `json:"thread_id,omitempty"`
@@ -86,10 +425,14 @@ type BroadcastRequest struct {
 	Response      string    `json:"response"`
 	Timestamp     time.Time `json:"timestamp"`
 	CorrelationID string    `json:"correlation_id"`
+
+	// Overrides the broadcast service's channel, from the channel policy
+	BroadcastChannelID string `json:"broadcast_channel_id,omitempty"`
 }
 
 // FeedbackRequest represents a request to broadcast user feedback
 type FeedbackRequest struct {
+	TeamID        string    `json:"team_id"`
 	UserID        string    `json:"user_id"`
 	ChannelID     string    `json:"channel_id"`
 	MessageTS     string    `json:"message_ts"`
@@ -100,4 +443,13 @@ type FeedbackRequest struct {
 	FeedbackText  string    `json:"feedback_text,omitempty"`
 	Timestamp     time.Time `json:"timestamp"`
 	CorrelationID string    `json:"correlation_id"`
+
+	// Correlation ID of the rated answer, matching its broadcast, and what
+	// produced it
+	AnswerCorrelationID string `json:"answer_correlation_id,omitempty"`
+	Model               string `json:"model,omitempty"`
+	PromptVersion       string `json:"prompt_version,omitempty"`
+
+	// Overrides the broadcast service's channel, from the channel policy
+	BroadcastChannelID string `json:"broadcast_channel_id,omitempty"`
 }
@@ -0,0 +1,57 @@
+package slack
+
+import "testing"
+
+func TestMessageLink(t *testing.T) {
+	tests := []struct {
+		name      string
+		workspace string
+		ts        string
+		threadTS  string
+		want      string
+	}{
+		{
+			name:      "top-level message",
+			workspace: "https://acme.slack.com/",
+			ts:        "1700000000.123456",
+			want:      "https://acme.slack.com/archives/C123/p1700000000123456",
+		},
+		{
+			name:      "thread reply",
+			workspace: "https://acme.slack.com/",
+			ts:        "1700000001.000100",
+			threadTS:  "1700000000.123456",
+			want:      "https://acme.slack.com/archives/C123/p1700000001000100?cid=C123&thread_ts=1700000000.123456",
+		},
+		{
+			name:      "thread parent",
+			workspace: "https://acme.slack.com",
+			ts:        "1700000000.123456",
+			threadTS:  "1700000000.123456",
+			want:      "https://acme.slack.com/archives/C123/p1700000000123456",
+		},
+		{name: "unknown workspace", ts: "1700000000.123456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MessageLink(tt.workspace, "C123", tt.ts, tt.threadTS)
+			if got != tt.want {
+				t.Fatalf("MessageLink = %q, want %q", got, tt.want)
+			}
+			if got == "" {
+				return
+			}
+
+			// Links Wavie builds must read back as the thread they are in
+			channel, threadTS, err := ParsePermalink(got)
+			wantThread := tt.threadTS
+			if wantThread == "" {
+				wantThread = tt.ts
+			}
+			if err != nil || channel != "C123" || threadTS != wantThread {
+				t.Errorf("ParsePermalink = %q, %q, %v; want C123, %q", channel, threadTS, err, wantThread)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package socketmode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/BitwaveCorp/shared-svcs/shared/dedup"
+	"github.com/BitwaveCorp/shared-svcs/shared/slackapi"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Slack pings roughly every 30 seconds; a silent connection is dead
+	readTimeout  = 90 * time.Second
+	writeTimeout = 10 * time.Second
+
+	minBackoff = 1 * time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// Dispatcher receives the payloads delivered over the socket. It is
+// implemented by api.Handler so both transports share one dispatch path.
+type Dispatcher interface {
+	DispatchEvent(eventReq slack.EventRequest, retry dedup.Retry) error
+	RunSlashCommand(ctx context.Context, cmd slack.SlashCommand) slack.CommandResponse
+	DispatchInteraction(ctx context.Context, payload slack.InteractionPayload)
+}
+
+// envelope wraps every message Slack sends over a Socket Mode connection
+type envelope struct {
+	EnvelopeID             string          `json:"envelope_id"`
+	Type                   string          `json:"type"`
+	Payload                json.RawMessage `json:"payload"`
+	AcceptsResponsePayload bool            `json:"accepts_response_payload"`
+	RetryAttempt           int             `json:"retry_attempt"`
+	RetryReason            string          `json:"retry_reason"`
+	Reason                 string          `json:"reason,omitempty"`
+}
+
+// ack acknowledges an envelope, optionally carrying a response payload
+type ack struct {
+	EnvelopeID string `json:"envelope_id"`
+	Payload    any    `json:"payload,omitempty"`
+}
+
+type connectionsOpenResponse struct {
+	URL string `json:"url"`
+}
+
+// Client receives Slack events over a Socket Mode websocket instead of a
+// public HTTP endpoint, reconnecting whenever the connection drops
+type Client struct {
+	api        *slackapi.Client // authenticated with the app token
+	dispatcher Dispatcher
+	logger     *slog.Logger
+	dialer     *websocket.Dialer
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	// Slash commands and interactions being handled off the read loop
+	handlers sync.WaitGroup
+}
+
+// NewClient creates a Socket Mode client. apiURL is the Web API base URL
+// (https://slack.com/api), overridable to point at a local stand-in.
+func NewClient(appToken, apiURL string, dispatcher Dispatcher, logger *slog.Logger) *Client {
+	return &Client{
+		api:        slackapi.New(apiURL, appToken, logger),
+		dispatcher: dispatcher,
+		logger:     logger,
+		dialer:     websocket.DefaultDialer,
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+	}
+}
+
+// Run keeps a connection open until ctx is cancelled, then waits for the
+// slash commands and interactions it already took to be handled
+func (c *Client) Run(ctx context.Context) {
+	defer c.handlers.Wait()
+	backoff := c.minBackoff
+
+	for {
+		connected, err := c.connectAndServe(ctx)
+		if ctx.Err() != nil {
+			c.logger.Info("Socket Mode client stopped")
+			return
+		}
+
+		if connected {
+			backoff = c.minBackoff
+		}
+		if err != nil {
+			c.logger.Error("Socket Mode connection failed", "error", err, "retry_in", backoff)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			c.logger.Info("Socket Mode client stopped")
+			return
+		}
+
+		if !connected {
+			backoff = min(backoff*2, c.maxBackoff)
+		}
+	}
+}
+
+// connectAndServe opens one connection and reads from it until it closes.
+// It reports whether Slack greeted the connection with a hello.
+func (c *Client) connectAndServe(ctx context.Context) (bool, error) {
+	wsURL, err := c.openConnection(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	conn, _, err := c.dialer.DialContext(ctx, wsURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to dial websocket: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock the read loop when we are asked to stop
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var writeMutex sync.Mutex
+	write := func(v any) error {
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		return conn.WriteJSON(v)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(writeTimeout))
+	})
+
+	connected := false
+	for {
+		var env envelope
+		if err := conn.ReadJSON(&env); err != nil {
+			return connected, fmt.Errorf("failed to read from websocket: %w", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+		switch env.Type {
+		case "hello":
+			connected = true
+			c.logger.Info("Socket Mode connected")
+		case "disconnect":
+			// Slack asks us to reconnect, e.g. before rotating the socket
+			c.logger.Info("Socket Mode disconnect requested", "reason", env.Reason)
+			return connected, nil
+		default:
+			if err := c.handleEnvelope(ctx, env, write); err != nil {
+				return connected, err
+			}
+		}
+	}
+}
+
+// handleEnvelope dispatches one payload and acknowledges it. Slack expects
+// the ack within three seconds and envelopes are read one at a time, so
+// anything that may be slow is handled off the read loop: events only queue
+// work, interactions are acknowledged first, and slash commands, whose ack
+// carries their response, are answered from a goroutine of their own.
+func (c *Client) handleEnvelope(ctx context.Context, env envelope, write func(any) error) error {
+	switch env.Type {
+	case "events_api":
+		var eventReq slack.EventRequest
+		if err := json.Unmarshal(env.Payload, &eventReq); err != nil {
+			c.logger.Error("Failed to parse event payload", "error", err, "envelope_id", env.EnvelopeID)
+			break
+		}
+		if err := c.dispatcher.DispatchEvent(eventReq, dedup.Retry{Num: env.RetryAttempt, Reason: env.RetryReason}); err != nil {
+			// Leave the envelope unacknowledged so Slack redelivers it
+			c.logger.Warn("Not acknowledging event", "error", err, "envelope_id", env.EnvelopeID)
+			return nil
+		}
+
+	case "slash_commands":
+		var fields map[string]string
+		if err := json.Unmarshal(env.Payload, &fields); err != nil {
+			c.logger.Error("Failed to parse slash command payload", "error", err, "envelope_id", env.EnvelopeID)
+			break
+		}
+		values := url.Values{}
+		for key, value := range fields {
+			values.Set(key, value)
+		}
+		cmd := slack.ParseSlashCommand(values)
+
+		c.handle(func() {
+			response := ack{EnvelopeID: env.EnvelopeID, Payload: c.dispatcher.RunSlashCommand(ctx, cmd)}
+			if err := acknowledge(response, write); err != nil {
+				// The read loop notices the broken connection and reconnects
+				c.logger.Error("Failed to answer slash command", "error", err, "envelope_id", env.EnvelopeID)
+			}
+		})
+		return nil
+
+	case "interactive":
+		var payload slack.InteractionPayload
+		if err := json.Unmarshal(env.Payload, &payload); err != nil {
+			c.logger.Error("Failed to parse interaction payload", "error", err, "envelope_id", env.EnvelopeID)
+			break
+		}
+		if err := acknowledge(ack{EnvelopeID: env.EnvelopeID}, write); err != nil {
+			return err
+		}
+
+		// Acknowledged, so it is handled even if the client is stopping
+		c.handle(func() { c.dispatcher.DispatchInteraction(context.WithoutCancel(ctx), payload) })
+		return nil
+
+	default:
+		c.logger.Info("Ignoring unsupported Socket Mode envelope", "type", env.Type)
+	}
+
+	return acknowledge(ack{EnvelopeID: env.EnvelopeID}, write)
+}
+
+// handle runs fn off the read loop; Run waits for it before returning
+func (c *Client) handle(fn func()) {
+	c.handlers.Add(1)
+	go func() {
+		defer c.handlers.Done()
+		fn()
+	}()
+}
+
+// acknowledge sends an envelope's ack, unless Slack sent it without an ID
+func acknowledge(response ack, write func(any) error) error {
+	if response.EnvelopeID == "" {
+		return nil
+	}
+	if err := write(response); err != nil {
+		return fmt.Errorf("failed to acknowledge envelope: %w", err)
+	}
+	return nil
+}
+
+// openConnection asks Slack for a fresh websocket URL
+func (c *Client) openConnection(ctx context.Context) (string, error) {
+	var openResp connectionsOpenResponse
+	if err := c.api.CallForm(ctx, "apps.connections.open", url.Values{}, &openResp); err != nil {
+		return "", fmt.Errorf("failed to open connection: %w", err)
+	}
+	return openResp.URL, nil
+}
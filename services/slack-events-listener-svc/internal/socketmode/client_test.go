@@ -0,0 +1,316 @@
+package socketmode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BitwaveCorp/shared-svcs/shared/dedup"
+	"github.com/BitwaveCorp/slack-wavie-bot-system-upgraded/services/slack-events-listener-svc/internal/slack"
+	"github.com/gorilla/websocket"
+)
+
+// fakeDispatcher records what the client dispatches. Events with the ID
+// "reject" fail, as a duplicate still being processed would. Interactions
+// wait for hold, if set, to be closed.
+type fakeDispatcher struct {
+	mutex        sync.Mutex
+	events       []slack.EventRequest
+	retries      []dedup.Retry
+	commands     []slack.SlashCommand
+	interactions []slack.InteractionPayload
+	hold         chan struct{}
+}
+
+func (d *fakeDispatcher) DispatchEvent(eventReq slack.EventRequest, retry dedup.Retry) error {
+	if eventReq.EventID == "reject" {
+		return errors.New("still processing")
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.events = append(d.events, eventReq)
+	d.retries = append(d.retries, retry)
+	return nil
+}
+
+func (d *fakeDispatcher) RunSlashCommand(ctx context.Context, cmd slack.SlashCommand) slack.CommandResponse {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.commands = append(d.commands, cmd)
+	return slack.CommandResponse{ResponseType: "ephemeral", Text: "ran " + cmd.Text}
+}
+
+func (d *fakeDispatcher) DispatchInteraction(ctx context.Context, payload slack.InteractionPayload) {
+	if d.hold != nil {
+		<-d.hold
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.interactions = append(d.interactions, payload)
+}
+
+// fakeSlack stands in for apps.connections.open and the websocket it hands
+// out. Each connection is passed to serve.
+type fakeSlack struct {
+	server   *httptest.Server
+	opens    chan time.Time
+	failOpen bool
+	serve    func(conn *websocket.Conn)
+}
+
+func newFakeSlack(t *testing.T, serve func(conn *websocket.Conn)) *fakeSlack {
+	t.Helper()
+	f := &fakeSlack{opens: make(chan time.Time, 100), serve: serve}
+	upgrader := websocket.Upgrader{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		f.opens <- time.Now()
+		if r.Header.Get("Authorization") != "Bearer xapp-test" {
+			t.Errorf("Authorization = %q, want the app token", r.Header.Get("Authorization"))
+		}
+		if f.failOpen {
+			fmt.Fprint(w, `{"ok": false, "error": "internal_error"}`)
+			return
+		}
+		wsURL := "ws" + strings.TrimPrefix(f.server.URL, "http") + "/socket"
+		fmt.Fprintf(w, `{"ok": true, "url": %q}`, wsURL)
+	})
+	mux.HandleFunc("/socket", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("failed to upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		f.serve(conn)
+	})
+
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func newTestClient(f *fakeSlack, dispatcher Dispatcher) *Client {
+	c := NewClient("xapp-test", f.server.URL, dispatcher, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	c.minBackoff = 10 * time.Millisecond
+	c.maxBackoff = 40 * time.Millisecond
+	return c
+}
+
+// runClient runs c until the test ends
+func runClient(t *testing.T, c *Client) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Error("Run didn't return after cancel")
+		}
+	})
+}
+
+// readAck reads the client's next ack. It runs on the server's goroutine, so
+// it reports failures with Errorf.
+func readAck(t *testing.T, conn *websocket.Conn) map[string]json.RawMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var got map[string]json.RawMessage
+	if err := conn.ReadJSON(&got); err != nil {
+		t.Errorf("failed to read ack: %v", err)
+	}
+	return got
+}
+
+func TestEnvelopesAreDispatchedAndAcknowledged(t *testing.T) {
+	dispatcher := &fakeDispatcher{}
+	acks := make(chan map[string]json.RawMessage, 10)
+
+	f := newFakeSlack(t, func(conn *websocket.Conn) {
+		conn.WriteJSON(map[string]any{"type": "hello"})
+		conn.WriteJSON(map[string]any{
+			"envelope_id":   "env-event",
+			"type":          "events_api",
+			"retry_attempt": 1,
+			"retry_reason":  "timeout",
+			"payload": map[string]any{
+				"event_id": "Ev1",
+				"event":    map[string]any{"type": "app_mention", "text": "hi"},
+			},
+		})
+		acks <- readAck(t, conn)
+
+		// A rejected event isn't acknowledged, so the next ack is the command's
+		conn.WriteJSON(map[string]any{
+			"envelope_id": "env-rejected",
+			"type":        "events_api",
+			"payload":     map[string]any{"event_id": "reject"},
+		})
+		conn.WriteJSON(map[string]any{
+			"envelope_id": "env-command",
+			"type":        "slash_commands",
+			"payload":     map[string]string{"command": "/wavie", "text": "status", "user_id": "U1"},
+		})
+		acks <- readAck(t, conn)
+
+		// Hold the connection open until the client goes away
+		conn.ReadMessage()
+	})
+	runClient(t, newTestClient(f, dispatcher))
+
+	eventAck := <-acks
+	if got := string(eventAck["envelope_id"]); got != `"env-event"` {
+		t.Errorf("first ack envelope_id = %s, want env-event", got)
+	}
+	if _, ok := eventAck["payload"]; ok {
+		t.Errorf("event ack carries a payload: %s", eventAck["payload"])
+	}
+
+	commandAck := <-acks
+	if got := string(commandAck["envelope_id"]); got != `"env-command"` {
+		t.Errorf("second ack envelope_id = %s, want env-command", got)
+	}
+	var response slack.CommandResponse
+	if err := json.Unmarshal(commandAck["payload"], &response); err != nil {
+		t.Fatalf("failed to decode command response: %v", err)
+	}
+	if response.Text != "ran status" {
+		t.Errorf("command response text = %q, want %q", response.Text, "ran status")
+	}
+
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+	if len(dispatcher.events) != 1 || dispatcher.events[0].EventID != "Ev1" || dispatcher.events[0].Event.Type != "app_mention" {
+		t.Errorf("dispatched events = %+v, want the app_mention Ev1", dispatcher.events)
+	}
+	if len(dispatcher.retries) == 1 && dispatcher.retries[0] != (dedup.Retry{Num: 1, Reason: "timeout"}) {
+		t.Errorf("retry = %+v, want attempt 1 for timeout", dispatcher.retries[0])
+	}
+	if len(dispatcher.commands) != 1 || dispatcher.commands[0].UserID != "U1" {
+		t.Errorf("dispatched commands = %+v, want one from U1", dispatcher.commands)
+	}
+}
+
+func TestSlowInteractionsDontStallTheSocket(t *testing.T) {
+	dispatcher := &fakeDispatcher{hold: make(chan struct{})}
+	acks := make(chan map[string]json.RawMessage, 10)
+
+	f := newFakeSlack(t, func(conn *websocket.Conn) {
+		conn.WriteJSON(map[string]any{"type": "hello"})
+		conn.WriteJSON(map[string]any{
+			"envelope_id": "env-interaction",
+			"type":        "interactive",
+			"payload":     map[string]any{"type": "block_actions"},
+		})
+		conn.WriteJSON(map[string]any{
+			"envelope_id": "env-event",
+			"type":        "events_api",
+			"payload":     map[string]any{"event_id": "Ev1"},
+		})
+		acks <- readAck(t, conn)
+		acks <- readAck(t, conn)
+		conn.ReadMessage()
+	})
+	runClient(t, newTestClient(f, dispatcher))
+
+	// Both are acknowledged while the interaction is still being handled
+	for _, want := range []string{`"env-interaction"`, `"env-event"`} {
+		select {
+		case got := <-acks:
+			if string(got["envelope_id"]) != want {
+				t.Errorf("ack envelope_id = %s, want %s", got["envelope_id"], want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no ack for %s while an interaction was being handled", want)
+		}
+	}
+
+	close(dispatcher.hold)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		dispatcher.mutex.Lock()
+		handled := len(dispatcher.interactions)
+		dispatcher.mutex.Unlock()
+		if handled == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("interaction wasn't handled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDisconnectReconnects(t *testing.T) {
+	var mutex sync.Mutex
+	connections := 0
+
+	f := newFakeSlack(t, func(conn *websocket.Conn) {
+		mutex.Lock()
+		connections++
+		first := connections == 1
+		mutex.Unlock()
+
+		conn.WriteJSON(map[string]any{"type": "hello"})
+		if first {
+			conn.WriteJSON(map[string]any{"type": "disconnect", "reason": "refresh_requested"})
+		}
+		conn.ReadMessage()
+	})
+	runClient(t, newTestClient(f, &fakeDispatcher{}))
+
+	for i := range 2 {
+		select {
+		case <-f.opens:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("connection %d wasn't opened", i+1)
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if connections > 2 {
+		t.Errorf("opened %d connections, want 2", connections)
+	}
+}
+
+func TestFailedConnectionsBackOff(t *testing.T) {
+	f := newFakeSlack(t, func(conn *websocket.Conn) {})
+	f.failOpen = true
+	runClient(t, newTestClient(f, &fakeDispatcher{}))
+
+	var opens []time.Time
+	for len(opens) < 5 {
+		select {
+		case opened := <-f.opens:
+			opens = append(opens, opened)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d connection attempts", len(opens))
+		}
+	}
+
+	// Waits double from minBackoff and stop at maxBackoff
+	want := []time.Duration{10, 20, 40, 40}
+	for i, minimum := range want {
+		minimum *= time.Millisecond
+		if gap := opens[i+1].Sub(opens[i]); gap < minimum {
+			t.Errorf("wait before attempt %d = %v, want at least %v", i+2, gap, minimum)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrQueueFull is returned by Submit when the queue limit is reached
+	ErrQueueFull = errors.New("worker pool queue is full")
+	// ErrClosed is returned by Submit once Shutdown has been called
+	ErrClosed = errors.New("worker pool is shut down")
+)
+
+type task struct {
+	run     func()
+	abandon func(ctx context.Context)
+}
+
+// keyQueue holds the tasks submitted for one key, oldest first. While the
+// first one runs, running is set and the rest wait.
+type keyQueue struct {
+	tasks   []task
+	running bool
+}
+
+// Stats is a snapshot of the pool's queue and throughput counters
+type Stats struct {
+	Workers       int   `json:"workers"`
+	QueueCapacity int   `json:"queue_capacity"`
+	QueueDepth    int64 `json:"queue_depth"`
+	Active        int64 `json:"active"`
+	Processed     int64 `json:"processed"`
+	Rejected      int64 `json:"rejected"`
+	Abandoned     int64 `json:"abandoned"`
+}
+
+// Pool runs tasks on a fixed number of workers with a bounded queue. Tasks
+// with the same key run one after another, in the order they were submitted,
+// so work for a thread never races. Any free worker picks up the next key
+// that has work, so a slow task only holds up tasks with its own key.
+type Pool struct {
+	workers     int
+	queueSize   int
+	ready       chan string // keys with a waiting task and none running
+	mutex       sync.Mutex
+	keys        map[string]*keyQueue
+	closed      bool
+	readyClosed bool
+	wg          sync.WaitGroup
+	logger      *slog.Logger
+
+	queued    atomic.Int64
+	active    atomic.Int64
+	processed atomic.Int64
+	rejected  atomic.Int64
+	abandoned atomic.Int64
+}
+
+// How long abandoned tasks get, together, to tell their users
+const abandonTimeout = 5 * time.Second
+
+// New starts a pool with the given number of workers that holds at most
+// queueSize waiting tasks across all workers
+func New(workers, queueSize int, logger *slog.Logger) *Pool {
+	p := &Pool{
+		workers:   workers,
+		queueSize: queueSize,
+		// A key is ready at most once and has at least one waiting task, so
+		// sends never block
+		ready:  make(chan string, queueSize),
+		keys:   make(map[string]*keyQueue),
+		logger: logger,
+	}
+
+	for range workers {
+		p.wg.Add(1)
+		go p.worker()
+	}
+
+	return p
+}
+
+// Submit queues run behind the other tasks for key. If the pool is shut down
+// before the task starts, abandon (which may be nil) is called instead, with
+// a context that bounds how long it may take.
+func (p *Pool) Submit(key string, run func(), abandon func(ctx context.Context)) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		p.rejected.Add(1)
+		return ErrClosed
+	}
+	if p.queued.Load() >= int64(p.queueSize) {
+		p.rejected.Add(1)
+		return ErrQueueFull
+	}
+
+	p.queued.Add(1)
+	queue, busy := p.keys[key]
+	if !busy {
+		queue = &keyQueue{}
+		p.keys[key] = queue
+	}
+	queue.tasks = append(queue.tasks, task{run: run, abandon: abandon})
+	if !busy {
+		p.ready <- key
+	}
+	return nil
+}
+
+// Stats returns the current counters
+func (p *Pool) Stats() Stats {
+	return Stats{
+		Workers:       p.workers,
+		QueueCapacity: p.queueSize,
+		QueueDepth:    p.queued.Load(),
+		Active:        p.active.Load(),
+		Processed:     p.processed.Load(),
+		Rejected:      p.rejected.Load(),
+		Abandoned:     p.abandoned.Load(),
+	}
+}
+
+// Shutdown stops accepting tasks and waits for queued tasks to finish. If
+// ctx expires first, tasks that have not started are abandoned and an error
+// reports what was left behind.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.closeReadyIfIdle()
+	p.mutex.Unlock()
+
+	p.logger.Info("Draining worker pool", "queue_depth", p.queued.Load(), "active", p.active.Load())
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.logger.Info("Worker pool drained")
+		return nil
+	case <-ctx.Done():
+	}
+
+	// Out of time: take every task that hasn't started off its queue,
+	// leaving running tasks to finish on their own
+	p.mutex.Lock()
+	var abandoned []task
+	for key, queue := range p.keys {
+		if queue.running {
+			abandoned = append(abandoned, queue.tasks[1:]...)
+			queue.tasks = queue.tasks[:1]
+			continue
+		}
+		abandoned = append(abandoned, queue.tasks...)
+		delete(p.keys, key)
+	}
+	p.closeReadyIfIdle()
+	p.mutex.Unlock()
+
+	p.abandonTasks(abandoned)
+
+	return fmt.Errorf("worker pool did not drain in time: %d tasks still running, %d abandoned",
+		p.active.Load(), p.abandoned.Load())
+}
+
+func (p *Pool) worker() {
+	defer p.wg.Done()
+
+	for key := range p.ready {
+		p.mutex.Lock()
+		queue, ok := p.keys[key]
+		if !ok {
+			// Abandoned during shutdown
+			p.mutex.Unlock()
+			continue
+		}
+		queue.running = true
+		t := queue.tasks[0]
+		p.mutex.Unlock()
+
+		p.queued.Add(-1)
+		p.active.Add(1)
+		p.runTask(t)
+		p.active.Add(-1)
+		p.processed.Add(1)
+
+		// Hand the key's next task to whichever worker is free, so keys
+		// take turns
+		p.mutex.Lock()
+		queue.running = false
+		queue.tasks = queue.tasks[1:]
+		if len(queue.tasks) > 0 {
+			p.ready <- key
+		} else {
+			delete(p.keys, key)
+			p.closeReadyIfIdle()
+		}
+		p.mutex.Unlock()
+	}
+}
+
+// closeReadyIfIdle stops the workers once the pool is shut down and no key
+// has work left. The caller holds the mutex.
+func (p *Pool) closeReadyIfIdle() {
+	if p.closed && !p.readyClosed && len(p.keys) == 0 {
+		p.readyClosed = true
+		close(p.ready)
+	}
+}
+
+// runTask runs a task, keeping a panic from taking the worker down with it
+func (p *Pool) runTask(t task) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.logger.Error("Worker task panicked", "panic", r)
+		}
+	}()
+	t.run()
+}
+
+// abandonTasks calls the abandon functions of tasks that won't run, all at
+// once, so that each user is told within abandonTimeout
+func (p *Pool) abandonTasks(tasks []task) {
+	ctx, cancel := context.WithTimeout(context.Background(), abandonTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, t := range tasks {
+		p.queued.Add(-1)
+		p.abandoned.Add(1)
+		if t.abandon == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					p.logger.Error("Abandoning task panicked", "panic", r)
+				}
+			}()
+			t.abandon(ctx)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,189 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestPool(workers, queueSize int) *Pool {
+	return New(workers, queueSize, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestTasksWithTheSameKeyRunInOrder(t *testing.T) {
+	p := newTestPool(4, 1000)
+
+	var mutex sync.Mutex
+	order := make(map[string][]int)
+	running := make(map[string]bool)
+
+	for i := range 50 {
+		for _, key := range []string{"a", "b", "c"} {
+			err := p.Submit(key, func() {
+				mutex.Lock()
+				if running[key] {
+					t.Errorf("two tasks for %q ran at once", key)
+				}
+				running[key] = true
+				mutex.Unlock()
+
+				time.Sleep(100 * time.Microsecond)
+
+				mutex.Lock()
+				running[key] = false
+				order[key] = append(order[key], i)
+				mutex.Unlock()
+			}, nil)
+			if err != nil {
+				t.Fatalf("Submit failed: %v", err)
+			}
+		}
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	for key, got := range order {
+		if len(got) != 50 {
+			t.Errorf("%q ran %d tasks, want 50", key, len(got))
+		}
+		for i, n := range got {
+			if n != i {
+				t.Errorf("%q ran task %d in position %d", key, n, i)
+				break
+			}
+		}
+	}
+}
+
+func TestSlowTaskOnlyHoldsUpItsKey(t *testing.T) {
+	// With keys sharded over two workers, about half of them would wait
+	// behind the slow task
+	p := newTestPool(2, 100)
+	defer p.Shutdown(context.Background())
+
+	release := make(chan struct{})
+	slowDone := make(chan struct{})
+	if err := p.Submit("slow", func() { <-release }, nil); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if err := p.Submit("slow", func() { close(slowDone) }, nil); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	done := make(chan string, 20)
+	for i := range 20 {
+		key := fmt.Sprintf("key-%d", i)
+		if err := p.Submit(key, func() { done <- key }, nil); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+
+	for range 20 {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("tasks for other keys waited for the slow task")
+		}
+	}
+
+	select {
+	case <-slowDone:
+		t.Fatal("the slow key's second task ran before its first finished")
+	default:
+	}
+	close(release)
+	select {
+	case <-slowDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the slow key's second task never ran")
+	}
+}
+
+func TestSubmitLimits(t *testing.T) {
+	p := newTestPool(1, 2)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	p.Submit("a", func() { close(started); <-release }, nil)
+	<-started
+
+	// The running task no longer counts against the queue
+	for range 2 {
+		if err := p.Submit("b", func() {}, nil); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+	if err := p.Submit("c", func() {}, nil); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Submit over the limit = %v, want ErrQueueFull", err)
+	}
+
+	close(release)
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if err := p.Submit("d", func() {}, nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Submit after Shutdown = %v, want ErrClosed", err)
+	}
+
+	stats := p.Stats()
+	if stats.Processed != 3 || stats.Rejected != 2 || stats.QueueDepth != 0 {
+		t.Errorf("stats = %+v, want 3 processed, 2 rejected and an empty queue", stats)
+	}
+}
+
+func TestShutdownAbandonsTasksThatDidNotStart(t *testing.T) {
+	p := newTestPool(1, 100)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	p.Submit("a", func() { close(started); <-release }, nil)
+	<-started
+
+	// Each abandon blocks until all of them have been called, so they only
+	// finish if they run concurrently
+	const queued = 5
+	var calls sync.WaitGroup
+	calls.Add(queued)
+	var mutex sync.Mutex
+	var abandoned []string
+	for i := range queued {
+		key := "a"
+		if i%2 == 1 {
+			key = fmt.Sprintf("other-%d", i)
+		}
+		err := p.Submit(key, func() { t.Errorf("task %d ran after the grace period", i) }, func(ctx context.Context) {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("abandon got a context without a deadline")
+			}
+			calls.Done()
+			calls.Wait()
+			mutex.Lock()
+			abandoned = append(abandoned, key)
+			mutex.Unlock()
+		})
+		if err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); err == nil {
+		t.Error("Shutdown succeeded with a task still running")
+	}
+	close(release)
+
+	if len(abandoned) != queued {
+		t.Errorf("abandoned %d tasks, want %d", len(abandoned), queued)
+	}
+	if stats := p.Stats(); stats.Abandoned != queued || stats.QueueDepth != 0 {
+		t.Errorf("stats = %+v, want %d abandoned and an empty queue", stats, queued)
+	}
+}
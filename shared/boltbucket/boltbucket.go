@@ -0,0 +1,88 @@
+// Package boltbucket keeps one bbolt bucket in a database file of its own. It
+// is the storage behind the services' "bolt" store backends.
+//
+// bbolt locks the file while it is open, so a database belongs to a single
+// process: state kept in it survives restarts but is per instance.
+package boltbucket
+
+import (
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bucket is an open database holding one bucket
+type Bucket struct {
+	db   *bolt.DB
+	name []byte
+}
+
+// Open opens (or creates) the database file at path and the bucket called
+// name in it. It gives up if another process holds the file for 5 seconds.
+func Open(path, name string) (*Bucket, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", name, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(name))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create %s bucket: %w", name, err)
+	}
+
+	return &Bucket{db: db, name: []byte(name)}, nil
+}
+
+// View calls fn with the bucket in a read-only transaction. Values are only
+// valid until fn returns.
+func (b *Bucket) View(fn func(bucket *bolt.Bucket) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return fn(tx.Bucket(b.name))
+	})
+}
+
+// Update calls fn with the bucket in a read-write transaction, which is
+// rolled back if fn returns an error
+func (b *Bucket) Update(fn func(bucket *bolt.Bucket) error) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return fn(tx.Bucket(b.name))
+	})
+}
+
+// DeleteIf deletes every entry for which expired returns true and returns how
+// many were deleted
+func (b *Bucket) DeleteIf(expired func(key, value []byte) bool) (int, error) {
+	removed := 0
+	err := b.Update(func(bucket *bolt.Bucket) error {
+		// Deleting through a cursor while iterating skips entries
+		var keys [][]byte
+		err := bucket.ForEach(func(key, value []byte) error {
+			if expired(key, value) {
+				keys = append(keys, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		removed = len(keys)
+		return nil
+	})
+	return removed, err
+}
+
+// Close closes the database, releasing its file lock
+func (b *Bucket) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,57 @@
+package boltbucket
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestDeleteIf(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	b, err := Open(path, "test")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	err = b.Update(func(bucket *bolt.Bucket) error {
+		for _, key := range []string{"old-1", "new-1", "old-2", "old-3", "new-2"} {
+			if err := bucket.Put([]byte(key), []byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	removed, err := b.DeleteIf(func(key, value []byte) bool {
+		return strings.HasPrefix(string(value), "old")
+	})
+	if err != nil {
+		t.Fatalf("DeleteIf failed: %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("DeleteIf removed %d entries, want 3", removed)
+	}
+
+	// The bucket and what's left in it survive reopening
+	b.Close()
+	if b, err = Open(path, "test"); err != nil {
+		t.Fatalf("reopening failed: %v", err)
+	}
+	defer b.Close()
+
+	var left []string
+	b.View(func(bucket *bolt.Bucket) error {
+		return bucket.ForEach(func(key, _ []byte) error {
+			left = append(left, string(key))
+			return nil
+		})
+	})
+	if strings.Join(left, ",") != "new-1,new-2" {
+		t.Errorf("left %v, want [new-1 new-2]", left)
+	}
+}
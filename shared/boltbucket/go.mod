@@ -0,0 +1,7 @@
+module github.com/BitwaveCorp/shared-svcs/shared/boltbucket
+
+go 1.24
+
+require go.etcd.io/bbolt v1.3.11
+
+require golang.org/x/sys v0.4.0 // indirect
@@ -0,0 +1,77 @@
+package dedup
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/BitwaveCorp/shared-svcs/shared/boltbucket"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltBackend stores claims in an embedded bbolt database so they survive
+// restarts
+type BoltBackend struct {
+	bucket *boltbucket.Bucket
+}
+
+// NewBoltBackend opens (or creates) the database file at path
+func NewBoltBackend(path string) (*BoltBackend, error) {
+	bucket, err := boltbucket.Open(path, "claims")
+	if err != nil {
+		return nil, err
+	}
+	return &BoltBackend{bucket: bucket}, nil
+}
+
+func (b *BoltBackend) Claim(key string, expiresAt time.Time) (bool, error) {
+	claimed := false
+	err := b.bucket.Update(func(bucket *bolt.Bucket) error {
+		if data := bucket.Get([]byte(key)); data != nil && decodeTime(data).After(time.Now()) {
+			return nil
+		}
+		claimed = true
+		return bucket.Put([]byte(key), encodeTime(expiresAt))
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to claim key: %w", err)
+	}
+	return claimed, nil
+}
+
+func (b *BoltBackend) Release(key string) error {
+	err := b.bucket.Update(func(bucket *bolt.Bucket) error {
+		return bucket.Delete([]byte(key))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release key: %w", err)
+	}
+	return nil
+}
+
+func (b *BoltBackend) DeleteExpired(now time.Time) (int, error) {
+	removed, err := b.bucket.DeleteIf(func(_, data []byte) bool {
+		return !decodeTime(data).After(now)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired keys: %w", err)
+	}
+	return removed, nil
+}
+
+func (b *BoltBackend) Close() error {
+	return b.bucket.Close()
+}
+
+func encodeTime(t time.Time) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(t.UnixNano()))
+	return data
+}
+
+func decodeTime(data []byte) time.Time {
+	if len(data) != 8 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(data)))
+}
@@ -0,0 +1,119 @@
+// Package dedup drops repeated deliveries of the same event or request. A
+// key is claimed before work starts, so a retry that arrives while the first
+// delivery is still being processed is recognised as a duplicate. Claims
+// expire after a TTL so the set of seen keys stays bounded.
+package dedup
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Backend stores claimed keys. Implementations must make Claim atomic.
+type Backend interface {
+	// Claim records key until expiresAt unless an unexpired claim already
+	// exists, and reports whether this call took the claim.
+	Claim(key string, expiresAt time.Time) (bool, error)
+	// Release drops a claim so the key can be processed again.
+	Release(key string) error
+	// DeleteExpired removes claims that expired before now and returns how
+	// many were removed.
+	DeleteExpired(now time.Time) (int, error)
+	// Close closes the claims database, if there is one.
+	Close() error
+}
+
+// OpenBackend returns the dedup backend for kind: "memory" (the default), or
+// "bolt" to keep claims in the database file at path.
+func OpenBackend(kind, path string) (Backend, error) {
+	switch kind {
+	case "", "memory":
+		return NewMemoryBackend(), nil
+	case "bolt":
+		return NewBoltBackend(path)
+	default:
+		return nil, fmt.Errorf("unknown dedup backend: %q", kind)
+	}
+}
+
+// Deduplicator claims keys for a fixed TTL
+type Deduplicator struct {
+	backend Backend
+	ttl     time.Duration
+	logger  *slog.Logger
+	done    chan struct{}
+}
+
+// New creates a Deduplicator and starts its cleanup routine
+func New(backend Backend, ttl time.Duration, logger *slog.Logger) *Deduplicator {
+	d := &Deduplicator{
+		backend: backend,
+		ttl:     ttl,
+		logger:  logger,
+		done:    make(chan struct{}),
+	}
+
+	go d.cleanupRoutine()
+
+	return d
+}
+
+// Claim reports whether the caller should process key. It returns false if
+// the key was claimed within the TTL. Backend failures are logged and the
+// key is processed, since dropping a first delivery is worse than a duplicate.
+func (d *Deduplicator) Claim(key string) bool {
+	claimed, err := d.backend.Claim(key, time.Now().Add(d.ttl))
+	if err != nil {
+		d.logger.Error("Failed to claim dedup key, processing anyway", "error", err, "key", key)
+		return true
+	}
+	return claimed
+}
+
+// Release gives up a claim, typically because processing failed and a retry
+// should be allowed through
+func (d *Deduplicator) Release(key string) {
+	if err := d.backend.Release(key); err != nil {
+		d.logger.Error("Failed to release dedup key", "error", err, "key", key)
+	}
+}
+
+// Close stops the cleanup routine and closes the backend
+func (d *Deduplicator) Close() error {
+	close(d.done)
+	return d.backend.Close()
+}
+
+func (d *Deduplicator) cleanupRoutine() {
+	ticker := time.NewTicker(min(d.ttl, 5*time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := d.backend.DeleteExpired(time.Now()); err != nil {
+				d.logger.Error("Failed to delete expired dedup keys", "error", err)
+			}
+		case <-d.done:
+			return
+		}
+	}
+}
+
+// Retry describes a redelivery by Slack. Num is zero for a first delivery.
+type Retry struct {
+	Num    int
+	Reason string // e.g. "http_timeout", "http_error"
+}
+
+// SlackRetry reads the X-Slack-Retry-Num and X-Slack-Retry-Reason headers
+func SlackRetry(r *http.Request) Retry {
+	num, _ := strconv.Atoi(r.Header.Get("X-Slack-Retry-Num"))
+	return Retry{
+		Num:    num,
+		Reason: r.Header.Get("X-Slack-Retry-Reason"),
+	}
+}
@@ -0,0 +1,98 @@
+package dedup
+
+import (
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// backends returns a fresh backend of every kind
+func backends(t *testing.T) map[string]Backend {
+	t.Helper()
+	bolt, err := NewBoltBackend(filepath.Join(t.TempDir(), "dedup.db"))
+	if err != nil {
+		t.Fatalf("failed to open bolt backend: %v", err)
+	}
+	t.Cleanup(func() { bolt.Close() })
+
+	return map[string]Backend{
+		"memory": NewMemoryBackend(),
+		"bolt":   bolt,
+	}
+}
+
+func TestClaimRelease(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			d := New(backend, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			defer d.Close()
+
+			if !d.Claim("Ev1") {
+				t.Fatal("first claim was refused")
+			}
+			if d.Claim("Ev1") {
+				t.Error("second claim of a held key succeeded")
+			}
+			if !d.Claim("Ev2") {
+				t.Error("claim of another key was refused")
+			}
+
+			d.Release("Ev1")
+			if !d.Claim("Ev1") {
+				t.Error("claim after release was refused")
+			}
+		})
+	}
+}
+
+func TestExpiredClaims(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			for key, expiresAt := range map[string]time.Time{
+				"expired": now.Add(-time.Second),
+				"live":    now.Add(time.Hour),
+			} {
+				if claimed, err := backend.Claim(key, expiresAt); err != nil || !claimed {
+					t.Fatalf("Claim(%q) = %v, %v; want true", key, claimed, err)
+				}
+			}
+
+			// An expired claim no longer blocks the key, even before cleanup
+			if claimed, _ := backend.Claim("expired", now.Add(-time.Second)); !claimed {
+				t.Error("expired claim blocked a new one")
+			}
+			if claimed, _ := backend.Claim("live", now.Add(time.Hour)); claimed {
+				t.Error("live claim was taken again")
+			}
+
+			removed, err := backend.DeleteExpired(now)
+			if err != nil {
+				t.Fatalf("DeleteExpired failed: %v", err)
+			}
+			if removed != 1 {
+				t.Errorf("DeleteExpired removed %d claims, want 1", removed)
+			}
+			if claimed, _ := backend.Claim("live", now.Add(time.Hour)); claimed {
+				t.Error("cleanup removed a live claim")
+			}
+		})
+	}
+}
+
+func TestOpenBackend(t *testing.T) {
+	for _, kind := range []string{"", "memory", "bolt"} {
+		backend, err := OpenBackend(kind, filepath.Join(t.TempDir(), "dedup.db"))
+		if err != nil {
+			t.Errorf("OpenBackend(%q) failed: %v", kind, err)
+			continue
+		}
+		backend.Close()
+	}
+
+	if _, err := OpenBackend("redis", ""); err == nil {
+		t.Error("OpenBackend accepted an unknown kind")
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/BitwaveCorp/shared-svcs/shared/dedup
+
+go 1.24
+
+require (
+	github.com/BitwaveCorp/shared-svcs/shared/boltbucket v0.0.0
+	go.etcd.io/bbolt v1.3.11
+)
+
+require golang.org/x/sys v0.4.0 // indirect
+
+replace github.com/BitwaveCorp/shared-svcs/shared/boltbucket => ../boltbucket
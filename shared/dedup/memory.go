@@ -0,0 +1,57 @@
+package dedup
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryBackend keeps claims in an in-process map. Claims are lost on restart
+// and are not shared between instances.
+type MemoryBackend struct {
+	claims map[string]time.Time
+	mutex  sync.Mutex
+}
+
+// NewMemoryBackend creates an empty in-memory backend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		claims: make(map[string]time.Time),
+	}
+}
+
+func (b *MemoryBackend) Claim(key string, expiresAt time.Time) (bool, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if existing, exists := b.claims[key]; exists && existing.After(time.Now()) {
+		return false, nil
+	}
+	b.claims[key] = expiresAt
+	return true, nil
+}
+
+func (b *MemoryBackend) Release(key string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.claims, key)
+	return nil
+}
+
+func (b *MemoryBackend) DeleteExpired(now time.Time) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	removed := 0
+	for key, expiresAt := range b.claims {
+		if !expiresAt.After(now) {
+			delete(b.claims, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (b *MemoryBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,3 @@
+module github.com/BitwaveCorp/shared-svcs/shared/mrkdwn
+
+go 1.24
@@ -0,0 +1,277 @@
+// Package mrkdwn converts the CommonMark that Claude writes into Slack's
+// mrkdwn, which differs in almost every construct: *bold* instead of
+// **bold**, <url|text> links, no headings or tables, and &, < and > must be
+// escaped.
+package mrkdwn
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	fenceLine     = regexp.MustCompile("^ {0,3}(```+|~~~+)(.*)$")
+	languageTag   = regexp.MustCompile(`^[\w+#.-]*$`)
+	headingLine   = regexp.MustCompile(`^ {0,3}#{1,6}\s+(.*?)(?:\s+#+)?\s*$`)
+	ruleLine      = regexp.MustCompile(`^ {0,3}([-*_])(\s*([-*_])){2,}\s*$`)
+	bulletLine    = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedLine   = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	quoteLine     = regexp.MustCompile(`^ {0,3}>\s?(.*)$`)
+	taskItem      = regexp.MustCompile(`^\[([ xX])\]\s+(.*)$`)
+	tableSepLine  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	linkPattern   = regexp.MustCompile(`!?\[([^\]]*)\]\(\s*<?((?:[^()\s<>]|\([^()\s<>]*\))+)>?(?:\s+"[^"]*")?\s*\)`)
+	autolink      = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	boldPattern   = regexp.MustCompile(`\*\*([^*\n]+?)\*\*|__([^_\n]+?)__`)
+	italicPattern = regexp.MustCompile(`\*([^\s*](?:[^*\n]*[^\s*])?)\*`)
+	strikePattern = regexp.MustCompile(`~~([^~\n]+?)~~`)
+)
+
+// Render converts Markdown to mrkdwn
+func Render(markdown string) string {
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		// Fenced code: copied verbatim apart from escaping, language tag dropped
+		if match := fenceLine.FindStringSubmatch(line); match != nil {
+			fence, rest := match[1], strings.TrimSpace(match[2])
+
+			// A whole block on one line
+			if body, ok := strings.CutSuffix(rest, fence); ok && strings.TrimSpace(body) != "" {
+				out = append(out, "```"+escape(strings.TrimSpace(body))+"```")
+				continue
+			}
+
+			// Anything after the fence but a language tag is code
+			var code []string
+			if !languageTag.MatchString(rest) {
+				code = append(code, escape(rest))
+			}
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+					break
+				}
+				code = append(code, escape(lines[i]))
+			}
+			out = append(out, "```\n"+strings.Join(code, "\n")+"\n```")
+			continue
+		}
+
+		// Tables have no mrkdwn equivalent; lay them out as preformatted text
+		if i+1 < len(lines) && strings.Contains(line, "|") && tableSepLine.MatchString(lines[i+1]) {
+			rows := [][]string{tableCells(line)}
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|"); i++ {
+				rows = append(rows, tableCells(lines[i]))
+			}
+			i--
+			out = append(out, renderTable(rows))
+			continue
+		}
+
+		out = append(out, renderLine(line))
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// renderLine converts one line outside code blocks and tables
+func renderLine(line string) string {
+	if match := headingLine.FindStringSubmatch(line); match != nil {
+		// Already bold, so emphasis inside would only add stray markers
+		text := strings.NewReplacer("**", "", "__", "").Replace(match[1])
+		return "*" + inline(text) + "*"
+	}
+
+	if ruleLine.MatchString(line) {
+		return "──────────"
+	}
+
+	if match := quoteLine.FindStringSubmatch(line); match != nil {
+		return "> " + renderLine(match[1])
+	}
+
+	if match := bulletLine.FindStringSubmatch(line); match != nil {
+		marker := "•"
+		text := match[2]
+		if task := taskItem.FindStringSubmatch(text); task != nil {
+			marker = "☐"
+			if task[1] != " " {
+				marker = "☑"
+			}
+			text = task[2]
+		}
+		return indent(match[1]) + marker + " " + inline(text)
+	}
+
+	if match := orderedLine.FindStringSubmatch(line); match != nil {
+		return indent(match[1]) + match[2] + ". " + inline(match[3])
+	}
+
+	return inline(line)
+}
+
+// indent maps Markdown list nesting to a visible indent; Slack keeps leading
+// whitespace but not its width, so each level gets a fixed indent
+func indent(whitespace string) string {
+	width := len(strings.ReplaceAll(whitespace, "\t", "    "))
+	return strings.Repeat("    ", width/2)
+}
+
+// inline converts emphasis, code spans and links within a line
+func inline(text string) string {
+	var out strings.Builder
+
+	// Code spans are literal, so only the text between them is converted
+	for {
+		start := strings.Index(text, "`")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(text[start+1:], "`")
+		if end < 0 {
+			break
+		}
+		end += start + 1
+
+		out.WriteString(inlineText(text[:start]))
+		out.WriteString("`" + escape(text[start+1:end]) + "`")
+		text = text[end+1:]
+	}
+	out.WriteString(inlineText(text))
+
+	return out.String()
+}
+
+// inlineText converts text that contains no code spans
+func inlineText(text string) string {
+	// Links are swapped for placeholders so emphasis rules can't touch their
+	// URLs, e.g. underscores in paths
+	var links []string
+	protect := func(link string) string {
+		links = append(links, link)
+		return fmt.Sprintf("\x00%d\x00", len(links)-1)
+	}
+
+	text = linkPattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := linkPattern.FindStringSubmatch(match)
+		label, url := strings.TrimSpace(parts[1]), parts[2]
+		if label == "" || label == url {
+			return protect("<" + escape(url) + ">")
+		}
+		return protect("<" + escape(url) + "|" + escape(stripEmphasis(label)) + ">")
+	})
+	text = autolink.ReplaceAllStringFunc(text, func(match string) string {
+		return protect("<" + escape(autolink.FindStringSubmatch(match)[1]) + ">")
+	})
+
+	text = escape(text)
+
+	// Bold goes through a marker so the italic rule doesn't rewrite it
+	text = boldPattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := boldPattern.FindStringSubmatch(match)
+		return "\x01" + parts[1] + parts[2] + "\x01"
+	})
+	text = replaceItalics(text, func(inner string) string { return "_" + inner + "_" })
+	text = strings.ReplaceAll(text, "\x01", "*")
+	text = strikePattern.ReplaceAllString(text, "~${1}~")
+
+	for i, link := range links {
+		text = strings.Replace(text, fmt.Sprintf("\x00%d\x00", i), link, 1)
+	}
+
+	return text
+}
+
+// stripEmphasis removes Markdown emphasis markers, for places where mrkdwn
+// can't format text such as link labels and preformatted tables
+func stripEmphasis(text string) string {
+	text = boldPattern.ReplaceAllString(text, "${1}${2}")
+	text = replaceItalics(text, func(inner string) string { return inner })
+	text = strikePattern.ReplaceAllString(text, "${1}")
+	return strings.ReplaceAll(text, "`", "")
+}
+
+// replaceItalics replaces *italic* spans with replace(inner). A * inside a
+// word, as in 2*3*4, is left alone: mrkdwn's _ only works at word
+// boundaries, and there it is more likely arithmetic than emphasis.
+func replaceItalics(text string, replace func(inner string) string) string {
+	var out strings.Builder
+	last := 0
+	for _, match := range italicPattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[0], match[1]
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if isWordRune(before) || isWordRune(after) {
+			continue
+		}
+		out.WriteString(text[last:start])
+		out.WriteString(replace(text[match[2]:match[3]]))
+		last = end
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// escape escapes the characters Slack treats as control sequences
+func escape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// tableCells splits a table row into trimmed cells
+func tableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+
+	cells := strings.Split(line, "|")
+	for i, cell := range cells {
+		cells[i] = stripEmphasis(strings.TrimSpace(cell))
+	}
+	return cells
+}
+
+// renderTable lays out rows as an aligned, preformatted table with a rule
+// under the header row
+func renderTable(rows [][]string) string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	var lines []string
+	for r, row := range rows {
+		cells := make([]string, len(widths))
+		for i := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			cells[i] = cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+		}
+		lines = append(lines, escape(strings.TrimRight(strings.Join(cells, " | "), " ")))
+
+		if r == 0 {
+			rule := make([]string, len(widths))
+			for i, width := range widths {
+				rule[i] = strings.Repeat("-", width)
+			}
+			lines = append(lines, strings.Join(rule, "-+-"))
+		}
+	}
+
+	return "```\n" + strings.Join(lines, "\n") + "\n```"
+}
@@ -0,0 +1,48 @@
+package mrkdwn
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{"bold", "a **bold** word", "a *bold* word"},
+		{"italic", "an *italic* word", "an _italic_ word"},
+		{"bold and italic", "**b** and *i*", "*b* and _i_"},
+		{"strikethrough", "~~gone~~", "~gone~"},
+		{"arithmetic", "2*3*4 is 24", "2*3*4 is 24"},
+		{"asterisks inside words", "file*name*s", "file*name*s"},
+		{"escaping", "a < b && c > d", "a &lt; b &amp;&amp; c &gt; d"},
+		{"code span", "run `a < b` **now**", "run `a &lt; b` *now*"},
+		{"heading", "## Setup", "*Setup*"},
+		{"heading with closing hashes", "## Setup ##", "*Setup*"},
+		{"heading ending in a hash", "## Using C#", "*Using C#*"},
+		{"heading with emphasis", "# The **real** fix", "*The real fix*"},
+		{"link", "see [the docs](https://example.com/a_b_c)", "see <https://example.com/a_b_c|the docs>"},
+		{"link with parentheses", "[Go](https://en.wikipedia.org/wiki/Go_(programming_language))", "<https://en.wikipedia.org/wiki/Go_(programming_language)|Go>"},
+		{"link followed by parenthesis", "([docs](https://example.com))", "(<https://example.com|docs>)"},
+		{"bare link", "[https://example.com](https://example.com)", "<https://example.com>"},
+		{"autolink", "<https://example.com>", "<https://example.com>"},
+		{"bullets", "- one\n  - two", "• one\n    • two"},
+		{"tasks", "- [ ] todo\n- [x] done", "☐ todo\n☑ done"},
+		{"ordered", "1) first", "1. first"},
+		{"quote", "> **note**", "> *note*"},
+		{"rule", "---", "──────────"},
+		{"code block", "```go\nif a < b {\n```", "```\nif a &lt; b {\n```"},
+		{"code block without a language", "```\n**x**\n```\nafter", "```\n**x**\n```\nafter"},
+		{"code after the fence", "```SELECT * FROM t\nWHERE a > 1\n```", "```\nSELECT * FROM t\nWHERE a &gt; 1\n```"},
+		{"code block on one line", "```echo hi```", "```echo hi```"},
+		{"unclosed code block", "```\ncode", "```\ncode\n```"},
+		{"table", "| a | **b** |\n|---|:-:|\n| 1 | 22 |", "```\na | b\n--+---\n1 | 22\n```"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.markdown); got != tt.want {
+				t.Errorf("Render(%q)\n got %q\nwant %q", tt.markdown, got, tt.want)
+			}
+		})
+	}
+}
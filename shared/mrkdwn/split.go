@@ -0,0 +1,202 @@
+package mrkdwn
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// fenceOverhead is the length of the ``` lines wrapped around each piece of
+// a code block that has to be split
+const fenceOverhead = len("```\n") + len("\n```")
+
+// piece is a part of the text together with the separator that joins it to
+// the piece before it
+type piece struct {
+	text string
+	sep  string
+}
+
+// Split breaks mrkdwn text into chunks of at most limit characters. It breaks
+// between paragraphs where it can, then between lines, then between words,
+// and never mid-word unless a single word is longer than limit. Code blocks
+// are kept whole when they fit; longer ones are split between lines and each
+// chunk gets its own fences, so every chunk renders on its own.
+func Split(text string, limit int) []string {
+	text = strings.TrimSpace(text)
+	if length(text) <= limit {
+		return []string{text}
+	}
+
+	var chunks []string
+	current := ""
+	for _, p := range pieces(text, limit) {
+		switch {
+		case current == "":
+			current = p.text
+		case length(current)+length(p.sep)+length(p.text) <= limit:
+			current += p.sep + p.text
+		default:
+			chunks = append(chunks, strings.TrimRight(current, " \n"))
+			current = p.text
+		}
+	}
+	if current != "" {
+		chunks = append(chunks, strings.TrimRight(current, " \n"))
+	}
+
+	return chunks
+}
+
+// pieces cuts text into pieces that each fit within limit
+func pieces(text string, limit int) []piece {
+	var result []piece
+	for _, block := range blocks(text) {
+		sep := "\n\n"
+		if length(block) <= limit {
+			result = append(result, piece{block, sep})
+			continue
+		}
+
+		var parts []piece
+		if strings.HasPrefix(block, "```") {
+			parts = splitCode(block, limit)
+		} else {
+			parts = splitParagraph(block, limit)
+		}
+		if len(parts) == 0 {
+			// Nothing to split between, e.g. a one-line ```...``` block
+			for _, part := range hardSplit(block, limit) {
+				parts = append(parts, piece{part, ""})
+			}
+		}
+		parts[0].sep = sep
+		result = append(result, parts...)
+	}
+	return result
+}
+
+// blocks cuts text into paragraphs and code blocks. Blank lines inside a
+// code block don't end it.
+func blocks(text string) []string {
+	var result []string
+	var current []string
+	inCode := false
+
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		isFence := strings.HasPrefix(strings.TrimSpace(line), "```")
+
+		switch {
+		case inCode:
+			current = append(current, line)
+			if isFence {
+				inCode = false
+				flush()
+			}
+		case isFence:
+			flush()
+			current = append(current, line)
+			inCode = true
+		case strings.TrimSpace(line) == "":
+			flush()
+		default:
+			current = append(current, line)
+		}
+	}
+	flush()
+
+	return result
+}
+
+// splitCode splits an oversized code block between lines, fencing each part
+func splitCode(block string, limit int) []piece {
+	lines := strings.Split(block, "\n")
+	body := lines[1:]
+	if len(body) > 0 && strings.HasPrefix(strings.TrimSpace(body[len(body)-1]), "```") {
+		body = body[:len(body)-1]
+	}
+
+	available := limit - fenceOverhead
+	var result []piece
+	var current []string
+	size := 0
+
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, piece{"```\n" + strings.Join(current, "\n") + "\n```", "\n"})
+			current = nil
+			size = 0
+		}
+	}
+
+	for _, line := range body {
+		// A line too long for any chunk has to be cut; it stays in a code block
+		for _, part := range hardSplit(line, available) {
+			extra := length(part)
+			if len(current) > 0 {
+				extra++
+			}
+			if size+extra > available {
+				flush()
+				extra = length(part)
+			}
+			current = append(current, part)
+			size += extra
+		}
+	}
+	flush()
+
+	return result
+}
+
+// splitParagraph splits an oversized paragraph between lines, and lines that
+// are still too long between words
+func splitParagraph(block string, limit int) []piece {
+	var result []piece
+	for _, line := range strings.Split(block, "\n") {
+		if length(line) <= limit {
+			result = append(result, piece{line, "\n"})
+			continue
+		}
+
+		sep := "\n"
+		for _, word := range strings.Split(line, " ") {
+			if word == "" {
+				continue
+			}
+			for i, part := range hardSplit(word, limit) {
+				if i > 0 {
+					sep = ""
+				}
+				result = append(result, piece{part, sep})
+				sep = " "
+			}
+		}
+	}
+	return result
+}
+
+// hardSplit cuts text into parts of at most limit characters, as a last resort
+func hardSplit(text string, limit int) []string {
+	if length(text) <= limit || limit <= 0 {
+		return []string{text}
+	}
+
+	var parts []string
+	runes := []rune(text)
+	for len(runes) > limit {
+		parts = append(parts, string(runes[:limit]))
+		runes = runes[limit:]
+	}
+	return append(parts, string(runes))
+}
+
+func length(text string) int {
+	return utf8.RuneCountInString(text)
+}
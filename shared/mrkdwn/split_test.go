@@ -0,0 +1,42 @@
+package mrkdwn
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{"fits", "  short  ", 10, []string{"short"}},
+		{"between paragraphs", "one two\n\nthree four", 12, []string{"one two", "three four"}},
+		{"joins small paragraphs", "a\n\nb\n\ncdefgh", 6, []string{"a\n\nb", "cdefgh"}},
+		{"between lines", "one two\nthree four", 12, []string{"one two", "three four"}},
+		{"between words", "one two three four", 9, []string{"one two", "three", "four"}},
+		{"long word", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"multibyte", "ééééé", 2, []string{"éé", "éé", "é"}},
+		{"code block kept whole", "intro\n\n```\na\nb\n```", 15, []string{"intro", "```\na\nb\n```"}},
+		{"code block split between lines", "```go\naaaa\nbbbb\ncccc\n```", 17, []string{"```\naaaa\nbbbb\n```", "```\ncccc\n```"}},
+		{"code block line too long", "```\nabcdefghijkl\n```", 14, []string{"```\nabcdef\n```", "```\nghijkl\n```"}},
+		{"one-line code block", "```" + strings.Repeat("x", 20) + "```", 10, []string{"```xxxxxxx", "xxxxxxxxxx", "xxx```"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Split(tt.text, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split(%q, %d)\n got %q\nwant %q", tt.text, tt.limit, got, tt.want)
+			}
+			for _, chunk := range got {
+				if length(chunk) > tt.limit {
+					t.Errorf("chunk %q is longer than %d", chunk, tt.limit)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package slackapi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Error is a Web API call that Slack answered with "ok": false. Code is
+// Slack's error string, such as "not_in_channel" or "channel_not_found".
+type Error struct {
+	Method   string
+	Code     string
+	Messages []string // details from response_metadata, if any
+}
+
+func (e *Error) Error() string {
+	if len(e.Messages) > 0 {
+		return fmt.Sprintf("slack API error: %s: %s (%s)", e.Method, e.Code, strings.Join(e.Messages, "; "))
+	}
+	return fmt.Sprintf("slack API error: %s: %s", e.Method, e.Code)
+}
+
+// transientCodes are ok:false errors that may go away if the call is repeated
+var transientCodes = map[string]bool{
+	"internal_error":      true,
+	"fatal_error":         true,
+	"service_unavailable": true,
+	"request_timeout":     true,
+	"ratelimited":         true,
+}
+
+// Temporary reports whether repeating the call later may succeed. Most
+// errors, such as not_in_channel or invalid_auth, need someone to fix
+// something first.
+func (e *Error) Temporary() bool {
+	return transientCodes[e.Code]
+}
+
+// HTTPError is a Web API call that failed with a non-200 status, after any
+// retries. RetryAfter is set for 429 responses.
+type HTTPError struct {
+	Method     string
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	if e.StatusCode == 429 {
+		return fmt.Sprintf("slack API error: %s: rate limited, retry after %s", e.Method, e.RetryAfter)
+	}
+	return fmt.Sprintf("slack API error: %s: %d - %s", e.Method, e.StatusCode, e.Body)
+}
+
+// ErrorCode returns Slack's error string if err is an ok:false response, or ""
+func ErrorCode(err error) string {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
+
+// IsRateLimited reports whether err is a 429 that outlasted the retries
+func IsRateLimited(err error) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == 429
+}
@@ -0,0 +1,3 @@
+module github.com/BitwaveCorp/shared-svcs/shared/slackapi
+
+go 1.24
@@ -0,0 +1,272 @@
+// Package slackapi calls Slack Web API methods. Slack answers most failures
+// with a 200 and "ok": false, so every response is checked for that and
+// turned into an *Error carrying Slack's error code. Rate limited (429) calls
+// are retried after the Retry-After Slack asks for. Server errors (5xx) and
+// dropped connections are retried with exponential backoff, except for
+// methods that must not run twice, such as chat.postMessage: those are only
+// retried if Slack can't have seen the request.
+package slackapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is where the Web API is served
+	DefaultBaseURL = "https://slack.com/api"
+
+	// Attempts per call, including the first
+	maxAttempts = 4
+
+	// Backoff between attempts after a server or connection error
+	defaultMinBackoff = 1 * time.Second
+	defaultMaxBackoff = 8 * time.Second
+
+	// A 429 asking to wait longer than this is returned to the caller rather
+	// than slept through
+	maxRetryAfter = 30 * time.Second
+)
+
+// nonIdempotent are the methods that mustn't be repeated once Slack may have
+// acted on them: a repeat posts a second message, or fails on a trigger ID or
+// OAuth code that was already used
+var nonIdempotent = map[string]bool{
+	"chat.postMessage":     true,
+	"chat.postEphemeral":   true,
+	"chat.scheduleMessage": true,
+	"views.open":           true,
+	"views.push":           true,
+	"oauth.v2.access":      true,
+}
+
+// Client calls Web API methods with one token. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	token      string
+	logger     *slog.Logger
+	client     *http.Client
+	attempts   int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// New creates a client for the Web API at baseURL (DefaultBaseURL if empty)
+// that authenticates with token: a bot, user or app token, or none for
+// methods such as oauth.v2.access that take credentials in the request
+func New(baseURL, token string, logger *slog.Logger) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/") + "/",
+		token:   token,
+		logger:  logger,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		attempts:   maxAttempts,
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+}
+
+// WithoutRetries returns a client with the same token that makes every call
+// once, for updates that are soon superseded and not worth waiting on. Its
+// errors are the same, so a rate limited call still reports Retry-After.
+func (c *Client) WithoutRetries() *Client {
+	once := *c
+	once.attempts = 1
+	return &once
+}
+
+// Token returns the token calls are made with, for requests that aren't Web
+// API methods, such as downloading files
+func (c *Client) Token() string {
+	return c.token
+}
+
+// PostedMessage identifies a message posted by chat.postMessage,
+// chat.postEphemeral or chat.update
+type PostedMessage struct {
+	Channel   string `json:"channel"`
+	TS        string `json:"ts"`
+	MessageTS string `json:"message_ts,omitempty"` // chat.postEphemeral
+}
+
+// PostMessage calls chat.postMessage with a JSON message and returns where it
+// was posted
+func (c *Client) PostMessage(ctx context.Context, message any) (*PostedMessage, error) {
+	var posted PostedMessage
+	if err := c.Call(ctx, "chat.postMessage", message, &posted); err != nil {
+		return nil, err
+	}
+	return &posted, nil
+}
+
+// Call POSTs a JSON payload to a Web API method and decodes the response into
+// result, which may be nil
+func (c *Client) Call(ctx context.Context, method string, payload any, result any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", method, err)
+	}
+
+	return c.do(ctx, method, result, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+method, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		return req, nil
+	})
+}
+
+// CallForm POSTs form parameters to a Web API method, for the methods that
+// don't accept JSON
+func (c *Client) CallForm(ctx context.Context, method string, form url.Values, result any) error {
+	body := form.Encode()
+
+	return c.do(ctx, method, result, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+method, strings.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	})
+}
+
+// Get calls a read-only Web API method with query parameters
+func (c *Client) Get(ctx context.Context, method string, params url.Values, result any) error {
+	return c.do(ctx, method, result, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, "GET", c.baseURL+method+"?"+params.Encode(), nil)
+	})
+}
+
+// envelope is the part every Web API response has
+type envelope struct {
+	OK               bool   `json:"ok"`
+	Error            string `json:"error,omitempty"`
+	Warning          string `json:"warning,omitempty"`
+	ResponseMetadata struct {
+		Messages []string `json:"messages,omitempty"`
+		Warnings []string `json:"warnings,omitempty"`
+	} `json:"response_metadata"`
+}
+
+// do sends the request newRequest builds, retrying what is safe to retry,
+// and decodes a successful response into result. Requests are built afresh
+// for every attempt so their bodies can be read again.
+func (c *Client) do(ctx context.Context, method string, result any, newRequest func() (*http.Request, error)) error {
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return fmt.Errorf("failed to create %s request: %w", method, err)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		// Whether the whole request went out, and so may have been acted on
+		var sent atomic.Bool
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+			WroteRequest: func(info httptrace.WroteRequestInfo) {
+				sent.Store(info.Err == nil)
+			},
+		}))
+
+		backoff := min(c.minBackoff<<(attempt-1), c.maxBackoff)
+
+		var failure error
+		var wait time.Duration
+		resp, err := c.client.Do(req)
+		if err != nil {
+			failure = fmt.Errorf("failed to call %s: %w", method, err)
+			if sent.Load() && nonIdempotent[method] {
+				return failure
+			}
+			wait = backoff
+		} else {
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return fmt.Errorf("failed to read %s response: %w", method, err)
+			}
+
+			switch {
+			case resp.StatusCode == http.StatusTooManyRequests:
+				// Rate limited calls were turned away before doing anything
+				wait = retryAfter(resp.Header.Get("Retry-After"))
+			case resp.StatusCode >= 500 && !nonIdempotent[method]:
+				wait = backoff
+			case resp.StatusCode != http.StatusOK:
+				return &HTTPError{Method: method, StatusCode: resp.StatusCode, Body: string(body)}
+			default:
+				return c.decode(method, body, result)
+			}
+			failure = &HTTPError{Method: method, StatusCode: resp.StatusCode, Body: string(body), RetryAfter: wait}
+		}
+
+		if attempt >= c.attempts || wait > maxRetryAfter {
+			return failure
+		}
+
+		c.logger.Warn("Slack API call failed, retrying",
+			"method", method,
+			"error", failure,
+			"attempt", attempt,
+			"retry_in", wait)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return failure
+		}
+	}
+}
+
+// decode checks a response's ok flag and decodes it into result
+func (c *Client) decode(method string, body []byte, result any) error {
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", method, err)
+	}
+
+	if env.Warning != "" {
+		c.logger.Warn("Slack API warning", "method", method, "warning", env.Warning, "details", env.ResponseMetadata.Warnings)
+	}
+
+	if !env.OK {
+		return &Error{Method: method, Code: env.Error, Messages: env.ResponseMetadata.Messages}
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", method, err)
+	}
+	return nil
+}
+
+// retryAfter parses a Retry-After header in seconds. Slack always sends one
+// with a 429; if it is missing, wait a second.
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 1 {
+		return time.Second
+	}
+	return time.Duration(seconds) * time.Second
+}
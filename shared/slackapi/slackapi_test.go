@@ -0,0 +1,142 @@
+package slackapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client for server that doesn't wait between attempts
+func newTestClient(server *httptest.Server) *Client {
+	c := New(server.URL+"/api", "xoxb-test", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	c.minBackoff = time.Millisecond
+	c.maxBackoff = time.Millisecond
+	return c
+}
+
+// failFirst fails the first n requests before they are written, like a
+// refused connection
+type failFirst struct {
+	n     int32
+	calls atomic.Int32
+}
+
+func (f *failFirst) RoundTrip(req *http.Request) (*http.Response, error) {
+	if f.calls.Add(1) <= f.n {
+		return nil, errors.New("connection refused")
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestBaseURL(t *testing.T) {
+	var path, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	if err := newTestClient(server).Get(context.Background(), "auth.test", url.Values{}, nil); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if path != "/api/auth.test" || auth != "Bearer xoxb-test" {
+		t.Errorf("request went to %q with %q, want /api/auth.test with the bot token", path, auth)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		status   int  // of every failing response
+		hangUp   bool // close the connection after reading the request instead
+		failures int32
+		want     int32 // attempts
+		wantErr  bool
+	}{
+		{name: "server error on a read", method: "conversations.info", status: 500, failures: 2, want: 3},
+		{name: "server error on a post", method: "chat.postMessage", status: 503, failures: 1, want: 1, wantErr: true},
+		{name: "rate limited post", method: "chat.postMessage", status: 429, failures: 1, want: 2},
+		{name: "dropped connection on a read", method: "conversations.info", hangUp: true, failures: 1, want: 2},
+		{name: "dropped connection on a post", method: "chat.postMessage", hangUp: true, failures: 1, want: 1, wantErr: true},
+		{name: "client error", method: "conversations.info", status: 400, failures: 1, want: 1, wantErr: true},
+		{name: "server error every time", method: "conversations.info", status: 500, failures: maxAttempts, want: maxAttempts, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if attempts.Add(1) > tt.failures {
+					w.Write([]byte(`{"ok":true}`))
+					return
+				}
+				if tt.hangUp {
+					conn, _, _ := w.(http.Hijacker).Hijack()
+					conn.Close()
+					return
+				}
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := newTestClient(server).Call(context.Background(), tt.method, map[string]string{"channel": "C1"}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Call error = %v, want error: %v", err, tt.wantErr)
+			}
+			if got := attempts.Load(); got != tt.want {
+				t.Errorf("server saw %d attempts, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryUnsentRequest(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.Write([]byte(`{"ok":true,"channel":"C1","ts":"1.2"}`))
+	}))
+	defer server.Close()
+
+	// A post that never reached Slack can't have been posted, so it is retried
+	c := newTestClient(server)
+	transport := &failFirst{n: 2}
+	c.client.Transport = transport
+
+	posted, err := c.PostMessage(context.Background(), map[string]string{"channel": "C1", "text": "hi"})
+	if err != nil {
+		t.Fatalf("PostMessage failed: %v", err)
+	}
+	if posted.TS != "1.2" || attempts.Load() != 1 || transport.calls.Load() != 3 {
+		t.Errorf("posted %+v after %d tries and %d deliveries, want 1.2 after 3 tries and 1 delivery",
+			posted, transport.calls.Load(), attempts.Load())
+	}
+}
+
+func TestWithoutRetries(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	err := newTestClient(server).WithoutRetries().Call(context.Background(), "chat.update", map[string]string{"channel": "C1"}, nil)
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("server saw %d attempts, want 1", got)
+	}
+	var httpErr *HTTPError
+	if !IsRateLimited(err) || !errors.As(err, &httpErr) || httpErr.RetryAfter != 3*time.Second {
+		t.Errorf("Call error = %v, want rate limited with a 3s Retry-After", err)
+	}
+}